@@ -10,6 +10,7 @@ require (
 	github.com/quic-go/quic-go v0.59.0
 	github.com/spf13/cobra v1.3.0
 	github.com/vfaronov/httpheader v0.1.0
+	golang.org/x/sys v0.38.0
 	modernc.org/sqlite v1.46.0
 )
 
@@ -24,7 +25,6 @@ require (
 	golang.org/x/crypto v0.44.0 // indirect
 	golang.org/x/exp v0.0.0-20251113190631-e25ba8c21ef6 // indirect
 	golang.org/x/net v0.47.0 // indirect
-	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
 	modernc.org/libc v1.67.6 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
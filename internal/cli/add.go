@@ -2,8 +2,16 @@ package cli
 
 import (
 	"concurrent_downloader/internal/clipboard"
+	"concurrent_downloader/internal/config"
+	"concurrent_downloader/internal/download"
+	"concurrent_downloader/internal/download/ratelimit"
+	"concurrent_downloader/internal/download/types"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -21,8 +29,7 @@ var addCmd = &cobra.Command{
 		output, _ := cmd.Flags().GetString("output")
 		clipboardFlag, _ := cmd.Flags().GetBool("clipboard")
 		filename, _ := cmd.Flags().GetString("filename")
-		forceSingle, _ := cmd.Flags().GetBool("force-single")
-		chunkCount, _ := cmd.Flags().GetInt("chunks")
+		metalinks, _ := cmd.Flags().GetStringArray("metalink")
 
 		// Collect URLs from multiple sources to keep CLI UX simple.
 		var urls []string
@@ -42,7 +49,12 @@ var addCmd = &cobra.Command{
 				os.Exit(1)
 			}
 			urls = append(urls, url)
-			fmt.Printf("📋 URL from clipboard: %s\n", url)
+			// Keep stdout for the file when streaming it there.
+			if output == "-" {
+				fmt.Fprintf(os.Stderr, "📋 URL from clipboard: %s\n", url)
+			} else {
+				fmt.Printf("📋 URL from clipboard: %s\n", url)
+			}
 		}
 
 		// 3. URLs from batch file.
@@ -55,7 +67,7 @@ var addCmd = &cobra.Command{
 			urls = append(urls, fileUrls...)
 		}
 
-		if len(urls) == 0 {
+		if len(urls) == 0 && len(metalinks) == 0 {
 			_ = cmd.Help()
 			return
 		}
@@ -65,15 +77,30 @@ var addCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		if forceSingle && chunkCount > 0 {
-			fmt.Fprintln(os.Stderr, "Error: --chunks cannot be used with --force-single")
+		opts, err := addOptionsFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		if chunkCount < 0 {
-			fmt.Fprintln(os.Stderr, "Error: --chunks must be a positive number")
+		if opts != nil && opts.Checksum != nil && len(urls) > 1 {
+			fmt.Fprintln(os.Stderr, "Error: --checksum can only be used with a single URL")
 			os.Exit(1)
 		}
 
+		// Streaming to stdout runs here rather than in the server, which
+		// could not hand it the bytes.
+		if output == "-" {
+			if len(urls) != 1 || len(metalinks) > 0 {
+				fmt.Fprintln(os.Stderr, "Error: -o - streams a single URL")
+				os.Exit(1)
+			}
+			if err := streamToStdout(urls[0], opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
 		// Check if GoFetch is running to decide local vs remote add.
 		port := readActivePort()
 		if port == 0 {
@@ -83,20 +110,62 @@ var addCmd = &cobra.Command{
 		}
 
 		// Send downloads to server
-		count := processDownloads(urls, output, filename, forceSingle, chunkCount, port)
+		count := processDownloads(urls, output, filename, opts, port)
+		count += processMetalinks(metalinks, output, opts, port)
 
-		if count > 0 {
+		if count > 0 && opts != nil && opts.StartAt.After(time.Now()) {
+			fmt.Printf("Scheduled %d downloads for %s.\n", count, opts.StartAt.Format("2006-01-02 15:04"))
+		} else if count > 0 {
 			fmt.Printf("Successfully added %d downloads.\n", count)
 		}
 	},
 }
 
+// streamToStdout downloads url over several connections and writes it to
+// stdout in order, so it can be piped without being saved first.
+func streamToStdout(rawurl string, opts *types.AddOptions) error {
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+
+	url, mirrors := ParseURLArg(rawurl)
+	cfg := &types.DownloadConfig{
+		URL:     url,
+		Mirrors: mirrors,
+		Runtime: types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+	}
+	if opts != nil {
+		cfg.Runtime.ForceSingle = opts.ForceSingle
+		if opts.ChunkCount > 0 {
+			cfg.Runtime.RequestedConnections = opts.ChunkCount
+		}
+		cfg.Checksum = opts.Checksum
+		if opts.RateLimit > 0 {
+			cfg.Bandwidth = ratelimit.New(opts.RateLimit)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return download.StreamDownload(ctx, cfg, os.Stdout)
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
 	addCmd.Flags().StringP("batch", "b", "", "File containing URLs to download (one per line)")
-	addCmd.Flags().StringP("output", "o", "", "Output directory")
+	addCmd.Flags().StringP("output", "o", "", "Output directory, or - to stream the file to stdout")
 	addCmd.Flags().StringP("filename", "n", "", "Override output filename (single URL only)")
 	addCmd.Flags().Bool("clipboard", false, "Read URL from clipboard")
 	addCmd.Flags().Bool("force-single", false, "Force single-connection downloader")
 	addCmd.Flags().Int("chunks", 0, "Override number of chunks/connections for this download")
+	addCmd.Flags().String("limit-rate", "", "Cap this download's speed, e.g. 500K or 5M (bytes/sec)")
+	addCmd.Flags().String("priority", "", "Queue priority: high, normal, low or a number (higher starts first)")
+	addCmd.Flags().String("temp-dir", "", "Write the partial file here and move it to the output directory when done")
+	addCmd.Flags().String("at", "", "Keep the download scheduled until a time, e.g. 02:00 or \"2026-01-31 02:00\"")
+	addCmd.Flags().Duration("after", 0, "Keep the download scheduled for a while, e.g. 2h")
+	addCmd.Flags().String("pause-at", "", "Pause the download at a time, e.g. 07:00")
+	addCmd.Flags().Duration("pause-after", 0, "Pause the download after a while, e.g. 6h")
+	addCmd.Flags().String("checksum", "", "Expected digest verified on completion, e.g. sha256:<hex> (single URL only)")
+	addCmd.Flags().StringArray("metalink", nil, "Metalink (.meta4) file or URL to download (repeatable)")
 }
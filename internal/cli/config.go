@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"concurrent_downloader/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or change limits of the running server",
+	Long: `Show the running server's limits, or change them without a restart.
+--max-downloads sets how many downloads run at once; lowering it lets running
+downloads finish first. --reload re-reads settings.json.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		maxDownloads, _ := cmd.Flags().GetInt("max-downloads")
+		reload, _ := cmd.Flags().GetBool("reload")
+		if cmd.Flags().Changed("max-downloads") && reload {
+			fmt.Fprintln(os.Stderr, "Error: --max-downloads cannot be used with --reload")
+			os.Exit(1)
+		}
+		if cmd.Flags().Changed("max-downloads") && maxDownloads < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --max-downloads must be at least 1")
+			os.Exit(1)
+		}
+
+		port := readActivePort()
+		if port == 0 {
+			fmt.Fprintln(os.Stderr, "Error: GoFetch is not running. Edit settings.json to change the defaults.")
+			os.Exit(1)
+		}
+
+		method := http.MethodGet
+		var query url.Values
+		switch {
+		case cmd.Flags().Changed("max-downloads"):
+			method = http.MethodPost
+			query = url.Values{"max_concurrent_downloads": {strconv.Itoa(maxDownloads)}}
+		case reload:
+			method = http.MethodPost
+		}
+
+		resp, err := serverRequest(method, port, "/config", query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				utils.Debug("Error closing response body: %v", err)
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error: server returned %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+			os.Exit(1)
+		}
+
+		var cfg ConfigResponse
+		if err := json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid response from server: %v\n", err)
+			os.Exit(1)
+		}
+
+		if reload {
+			fmt.Println("Reloaded settings")
+		}
+		fmt.Printf("Max concurrent downloads: %d\n", cfg.MaxConcurrentDownloads)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.Flags().Int("max-downloads", 0, "Number of downloads to run at once")
+	configCmd.Flags().Bool("reload", false, "Re-read settings.json and apply it")
+}
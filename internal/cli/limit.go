@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"concurrent_downloader/internal/download/ratelimit"
+	"concurrent_downloader/internal/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var limitCmd = &cobra.Command{
+	Use:   "limit [ID] <RATE>",
+	Short: "Change the speed limit of a running download",
+	Long: `Cap the speed of a queued or running download, e.g. "limit 1a2b3c 2M".
+Use --global to cap all downloads together. RATE is in bytes/sec with an
+optional K, M or G suffix; 0 removes the limit.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		global, _ := cmd.Flags().GetBool("global")
+		if global != (len(args) == 1) {
+			fmt.Fprintln(os.Stderr, "Error: provide a download ID and a rate, or --global and a rate")
+			os.Exit(1)
+		}
+
+		rate, err := ratelimit.ParseRate(args[len(args)-1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var id string
+		if !global {
+			// Resolve partial ID to full ID
+			if id, err = resolveDownloadID(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		port := readActivePort()
+		if port == 0 {
+			fmt.Fprintln(os.Stderr, "Error: GoFetch is not running. Limits apply to running downloads only.")
+			os.Exit(1)
+		}
+
+		query := url.Values{"rate": {args[len(args)-1]}}
+		if id != "" {
+			query.Set("id", id)
+		}
+		resp, err := serverRequest(http.MethodPost, port, "/limit", query)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				utils.Debug("Error closing response body: %v", err)
+			}
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			fmt.Fprintf(os.Stderr, "Error: server returned %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+			os.Exit(1)
+		}
+
+		target := "all downloads"
+		if id != "" {
+			target = "download " + id[:8]
+		}
+		if rate == 0 {
+			fmt.Printf("Removed speed limit for %s\n", target)
+		} else {
+			fmt.Printf("Limited %s to %s/s\n", target, utils.ConvertBytesToHumanReadable(rate))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(limitCmd)
+	limitCmd.Flags().Bool("global", false, "Change the limit shared by all downloads")
+}
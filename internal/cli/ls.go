@@ -7,7 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"text/tabwriter"
 	"time"
 
@@ -55,6 +57,15 @@ type downloadInfo struct {
 	TotalSize  int64   `json:"total_size"`
 	Downloaded int64   `json:"downloaded"`
 	Speed      float64 `json:"speed,omitempty"`
+	QueuePos   int     `json:"queue_position,omitempty"`
+	Backoff    int64   `json:"backoff_until,omitempty"`
+
+	Error       string          `json:"error,omitempty"`
+	ErrorKind   types.ErrorKind `json:"error_kind,omitempty"`
+	ErrorStatus int             `json:"error_status,omitempty"`
+	Retryable   bool            `json:"retryable,omitempty"`
+	RetryCount  int             `json:"retry_count,omitempty"`
+	NextRetryAt int64           `json:"next_retry_at,omitempty"`
 }
 
 func printDownloads(jsonOutput bool) {
@@ -78,6 +89,15 @@ func printDownloads(jsonOutput bool) {
 					TotalSize:  s.TotalSize,
 					Downloaded: s.Downloaded,
 					Speed:      speed,
+					QueuePos:   s.QueuePos,
+					Backoff:    s.BackoffUntil,
+
+					Error:       s.Error,
+					ErrorKind:   s.ErrorKind,
+					ErrorStatus: s.ErrorStatus,
+					Retryable:   s.Retryable,
+					RetryCount:  s.RetryCount,
+					NextRetryAt: s.NextRetryAt,
 				})
 			}
 		}
@@ -105,6 +125,14 @@ func printDownloads(jsonOutput bool) {
 				TotalSize:  d.TotalSize,
 				Downloaded: d.Downloaded,
 				Speed:      speed,
+				QueuePos:   d.QueuePos,
+
+				Error:       d.Error,
+				ErrorKind:   d.ErrorKind,
+				ErrorStatus: d.ErrorStatus,
+				Retryable:   d.ErrorKind.Retryable(d.ErrorStatus),
+				RetryCount:  d.RetryCount,
+				NextRetryAt: d.NextRetryAt,
 			})
 		}
 	}
@@ -153,7 +181,18 @@ func printDownloads(jsonOutput bool) {
 			filename = filename[:22] + "..."
 		}
 
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, d.Status, progress, speed, size)
+		status := d.Status
+		if d.QueuePos > 0 {
+			status = fmt.Sprintf("%s (#%d)", status, d.QueuePos)
+		}
+		if d.Backoff > 0 {
+			status += " (backoff)"
+		}
+		if d.NextRetryAt > 0 {
+			status = fmt.Sprintf("%s #%d at %s", status, d.RetryCount, time.Unix(d.NextRetryAt, 0).Format("15:04"))
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, filename, status, progress, speed, size)
 	}
 	_ = w.Flush()
 }
@@ -169,7 +208,7 @@ func showDownloadDetails(partialID string, jsonOutput bool) {
 	// Try to get from running server first
 	port := readActivePort()
 	if port > 0 {
-		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/download?id=%s", port, fullID))
+		resp, err := serverRequest(http.MethodGet, port, "/download", url.Values{"id": {fullID}})
 		if err == nil {
 			defer func() {
 				if err := resp.Body.Close(); err != nil {
@@ -219,7 +258,12 @@ func showDownloadDetails(partialID string, jsonOutput bool) {
 		TotalSize:  found.TotalSize,
 		Downloaded: found.Downloaded,
 		Progress:   progress,
+		StartAt:    found.StartAt,
+		PauseAt:    found.PauseAt,
+		Priority:   found.Priority,
+		QueuePos:   found.QueuePos,
 	}
+	status.SetEntryError(*found)
 	printDownloadDetail(status, jsonOutput)
 }
 
@@ -249,8 +293,57 @@ func printDownloadDetail(d types.DownloadStatus, jsonOutput bool) {
 	if d.Speed > 0 {
 		fmt.Printf("Speed:      %.1f MB/s\n", d.Speed)
 	}
+	if d.RateLimit > 0 {
+		fmt.Printf("Limit:      %s/s\n", utils.ConvertBytesToHumanReadable(d.RateLimit))
+	}
+	if d.QueuePos > 0 {
+		fmt.Printf("Queue:      #%d\n", d.QueuePos)
+	}
+	if d.Priority != 0 {
+		fmt.Printf("Priority:   %d\n", d.Priority)
+	}
+	if d.StartAt > 0 {
+		fmt.Printf("Starts at:  %s\n", time.Unix(d.StartAt, 0).Format("2006-01-02 15:04"))
+	}
+	if d.PauseAt > 0 {
+		fmt.Printf("Pauses at:  %s\n", time.Unix(d.PauseAt, 0).Format("2006-01-02 15:04"))
+	}
+	if d.NextRetryAt > 0 {
+		fmt.Printf("Retry:      attempt %d at %s\n", d.RetryCount, time.Unix(d.NextRetryAt, 0).Format(time.TimeOnly))
+	} else if d.RetryCount > 0 {
+		fmt.Printf("Retries:    %d\n", d.RetryCount)
+	}
+	if d.BackoffUntil > 0 {
+		fmt.Printf("Backoff:    host throttled, waiting until %s\n", time.Unix(d.BackoffUntil, 0).Format(time.TimeOnly))
+	}
+	if w := d.Writes; w != nil && w.Writes > 0 {
+		fmt.Printf("Writes:     %d to disk from %d by workers (%s written)\n", w.DiskWrites, w.Writes, utils.ConvertBytesToHumanReadable(w.DiskBytes))
+	}
+	if len(d.HostConnections) > 0 {
+		origins := make([]string, 0, len(d.HostConnections))
+		for origin := range d.HostConnections {
+			origins = append(origins, origin)
+		}
+		sort.Strings(origins)
+		for _, origin := range origins {
+			fmt.Printf("Host:       %s (%d connections)\n", origin, d.HostConnections[origin])
+		}
+	}
 	if d.Error != "" {
 		fmt.Printf("Error:      %s\n", d.Error)
+		if d.ErrorKind != "" {
+			retry := "permanent"
+			if d.Retryable {
+				retry = "retryable"
+			}
+			fmt.Printf("Cause:      %s (%s)\n", d.ErrorKind, retry)
+		}
+	}
+	if d.Status == "needs_url" {
+		fmt.Printf("Next step:  GoFetch refresh-url %s <url>\n", shortID(d.ID))
+	}
+	if d.Status == "disk_full" {
+		fmt.Println("Next step:  free up disk space; the download continues by itself")
 	}
 }
 
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Show or reorder queued downloads",
+	Long: `List downloads waiting for a free slot, next to start first.
+Use "queue move" and "queue swap" to change the order.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		port := requireRunningServer()
+
+		resp := queueRequest(http.MethodGet, port, nil)
+		defer func() {
+			if err := resp.Body.Close(); err != nil {
+				utils.Debug("Error closing response body: %v", err)
+			}
+		}()
+
+		var queue []types.DownloadStatus
+		if err := json.NewDecoder(resp.Body).Decode(&queue); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid response from server: %v\n", err)
+			os.Exit(1)
+		}
+
+		if jsonOutput {
+			data, _ := json.MarshalIndent(queue, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		if len(queue) == 0 {
+			fmt.Println("Queue is empty.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "#\tID\tFILENAME\tPRIORITY")
+		_, _ = fmt.Fprintln(w, "-\t--\t--------\t--------")
+		for _, d := range queue {
+			id := d.ID
+			if len(id) > 8 {
+				id = id[:8]
+			}
+			filename := d.Filename
+			if len(filename) > 25 {
+				filename = filename[:22] + "..."
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", d.QueuePos, id, filename, d.Priority)
+		}
+		_ = w.Flush()
+	},
+}
+
+var queueMoveCmd = &cobra.Command{
+	Use:   "move <ID> <POSITION>",
+	Short: "Move a queued download to another position",
+	Long:  `Move a queued download to POSITION (1 is next to start), or to "top" or "bottom".`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		var position int
+		switch strings.ToLower(args[1]) {
+		case "top":
+			position = 1
+		case "bottom":
+			position = 0
+		default:
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Error: invalid position %q (use a number from 1, top or bottom)\n", args[1])
+				os.Exit(1)
+			}
+			position = n
+		}
+
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		port := requireRunningServer()
+		resp := queueRequest(http.MethodPost, port, url.Values{"id": {id}, "position": {strconv.Itoa(position)}})
+		_ = resp.Body.Close()
+
+		if position == 0 {
+			fmt.Printf("Moved download %s to the bottom of the queue\n", id[:8])
+		} else {
+			fmt.Printf("Moved download %s to position %d\n", id[:8], position)
+		}
+	},
+}
+
+var queueSwapCmd = &cobra.Command{
+	Use:   "swap <ID> <ID>",
+	Short: "Swap the positions of two queued downloads",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		a, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		b, err := resolveDownloadID(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		port := requireRunningServer()
+		resp := queueRequest(http.MethodPost, port, url.Values{"id": {a}, "swap": {b}})
+		_ = resp.Body.Close()
+
+		fmt.Printf("Swapped downloads %s and %s\n", a[:8], b[:8])
+	},
+}
+
+// requireRunningServer returns the server port or exits, since the queue
+// only exists inside a running instance.
+func requireRunningServer() int {
+	port := readActivePort()
+	if port == 0 {
+		fmt.Fprintln(os.Stderr, "Error: GoFetch is not running. The queue lives in the running server.")
+		os.Exit(1)
+	}
+	return port
+}
+
+// queueRequest calls the /queue endpoint and exits on any failure.
+func queueRequest(method string, port int, query url.Values) *http.Response {
+	resp, err := serverRequest(method, port, "/queue", query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+		os.Exit(1)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		fmt.Fprintf(os.Stderr, "Error: server returned %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		os.Exit(1)
+	}
+	return resp
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueMoveCmd, queueSwapCmd)
+	queueCmd.Flags().Bool("json", false, "Output in JSON format")
+}
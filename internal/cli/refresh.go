@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"concurrent_downloader/internal/download"
+	"concurrent_downloader/internal/state"
+	"concurrent_downloader/internal/utils"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var refreshURLCmd = &cobra.Command{
+	Use:   "refresh-url <ID> <URL>",
+	Short: "Give a download a new URL for the same file",
+	Long: `Replace the URL of a download that is not running, e.g. a presigned
+link that expired. The new URL must serve the same file (size, ETag and
+Last-Modified), so the download keeps its progress. A download waiting for a
+new URL resumes with it.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		initializeGlobalState()
+
+		// Resolve partial ID to full ID
+		id, err := resolveDownloadID(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		newURL := args[1]
+
+		port := readActivePort()
+		if port > 0 {
+			// Send to running server
+			resp, err := serverRequest(http.MethodPost, port, "/refresh", url.Values{"id": {id}, "url": {newURL}})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+				os.Exit(1)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					utils.Debug("Error closing response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				fmt.Fprintf(os.Stderr, "Error: server returned %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+				os.Exit(1)
+			}
+			fmt.Printf("Refreshed URL of download %s\n", id[:8])
+			return
+		}
+
+		// Offline mode: check and store the URL so the next start continues with it.
+		entry, err := state.GetDownload(id)
+		if err != nil || entry == nil {
+			fmt.Fprintln(os.Stderr, "Error: download not found")
+			os.Exit(1)
+		}
+		if entry.Status == "completed" {
+			fmt.Fprintln(os.Stderr, "Error: download already completed")
+			os.Exit(1)
+		}
+		// Headers are only kept by a running daemon.
+		if err := download.VerifyReplacementURL(context.Background(), entry, newURL, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var mirrors []string
+		for _, m := range entry.Mirrors {
+			if m != entry.URL && m != newURL {
+				mirrors = append(mirrors, m)
+			}
+		}
+		if err := state.UpdateURL(id, newURL, mirrors); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating download: %v\n", err)
+			os.Exit(1)
+		}
+		if entry.Status == "needs_url" {
+			if err := state.UpdateStatus(id, "queued"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error updating download: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Refreshed URL of download %s (offline mode). Start GoFetch to continue downloading.\n", id[:8])
+			return
+		}
+		fmt.Printf("Refreshed URL of download %s (offline mode)\n", id[:8])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refreshURLCmd)
+}
@@ -5,19 +5,28 @@ import (
 	"concurrent_downloader/internal/config"
 	"concurrent_downloader/internal/core"
 	"concurrent_downloader/internal/download"
+	"concurrent_downloader/internal/download/ratelimit"
 	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/events"
+	"concurrent_downloader/internal/metalink"
 	"concurrent_downloader/internal/state"
 	"concurrent_downloader/internal/utils"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"syscall"
@@ -64,7 +73,7 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			settings = config.DefaultSettings()
 		}
-		GlobalPool = download.NewWorkerPool(GlobalProgressCh, settings.Network.MaxConcurrentDownloads)
+		GlobalPool = download.NewWorkerPool(GlobalProgressCh, settings.MaxConcurrentDownloads())
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		initializeGlobalState()
@@ -118,10 +127,9 @@ var rootCmd = &cobra.Command{
 		batchFile, _ := cmd.Flags().GetString("batch")
 		outputDir, _ := cmd.Flags().GetString("output")
 		filename, _ := cmd.Flags().GetString("filename")
-		forceSingle, _ := cmd.Flags().GetBool("force-single")
-		chunkCount, _ := cmd.Flags().GetInt("chunks")
 		noResume, _ := cmd.Flags().GetBool("no-resume")
 		exitWhenDone, _ := cmd.Flags().GetBool("exit-when-done")
+		metalinks, _ := cmd.Flags().GetStringArray("metalink")
 
 		port, listener, err := bindServerListener(portFlag)
 		if err != nil {
@@ -150,20 +158,22 @@ var rootCmd = &cobra.Command{
 				}
 			}
 
-			if len(urls) > 0 {
+			if len(urls) > 0 || len(metalinks) > 0 {
 				if filename != "" && len(urls) > 1 {
 					fmt.Fprintln(os.Stderr, "Error: --filename can only be used with a single URL")
 					return
 				}
-				if forceSingle && chunkCount > 0 {
-					fmt.Fprintln(os.Stderr, "Error: --chunks cannot be used with --force-single")
+				opts, err := addOptionsFromFlags(cmd)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 					return
 				}
-				if chunkCount < 0 {
-					fmt.Fprintln(os.Stderr, "Error: --chunks must be a positive number")
+				if opts != nil && opts.Checksum != nil && len(urls) > 1 {
+					fmt.Fprintln(os.Stderr, "Error: --checksum can only be used with a single URL")
 					return
 				}
-				processDownloads(urls, outputDir, filename, forceSingle, chunkCount, 0) // 0 port = internal direct add
+				processDownloads(urls, outputDir, filename, opts, 0) // 0 port = internal direct add
+				processMetalinks(metalinks, outputDir, opts, 0)
 			}
 		}()
 
@@ -262,6 +272,32 @@ func StartHeadlessConsumer() {
 				}
 				delete(progressState, m.DownloadID)
 				fmt.Printf("Error: %s [%s]: %v\n", m.Filename, id, m.Err)
+			case events.DownloadChecksumMismatchMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Checksum mismatch: %s [%s] expected %s:%s, got %s:%s\n", m.Filename, shortID(m.DownloadID), m.Algorithm, m.Expected, m.Algorithm, m.Actual)
+			case events.DownloadRetryMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Reconnecting (%d/%d): %s [%s]: %s\n", m.Attempt, m.MaxAttempts, m.Filename, shortID(m.DownloadID), m.Reason)
+			case events.DownloadRetryScheduledMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Retrying (%d/%d) at %s: %s [%s]: %s\n", m.Attempt, m.MaxAttempts, m.RetryAt.Format(time.TimeOnly), m.Filename, shortID(m.DownloadID), m.Reason)
+			case events.DownloadBackoffMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Backing off: %s [%s]: %s returned %d, waiting until %s\n", m.Filename, shortID(m.DownloadID), m.Host, m.StatusCode, m.Until.Format(time.TimeOnly))
+			case events.DownloadNeedsURLMsg:
+				finalizeInline(&lastInlineID)
+				atomic.AddInt32(&activeDownloads, -1)
+				delete(progressState, m.DownloadID)
+				fmt.Printf("Needs new URL: %s [%s]: %s (use 'GoFetch refresh-url %s <url>')\n", m.Filename, shortID(m.DownloadID), m.Reason, shortID(m.DownloadID))
+			case events.DownloadURLRefreshedMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("URL refreshed: %s [%s]\n", m.Filename, shortID(m.DownloadID))
+			case events.DownloadDiskFullMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Disk full, waiting for space: %s [%s]: %s\n", m.Filename, shortID(m.DownloadID), m.Reason)
+			case events.DownloadMovingMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Moving to destination: %s [%s]: %s of %s\n", m.Filename, shortID(m.DownloadID), utils.ConvertBytesToHumanReadable(m.Moved), utils.ConvertBytesToHumanReadable(m.Total))
 			case events.DownloadQueuedMsg:
 				finalizeInline(&lastInlineID)
 				id := m.DownloadID
@@ -273,6 +309,13 @@ func StartHeadlessConsumer() {
 					state.filename = m.Filename
 				}
 				fmt.Printf("Queued: %s [%s]\n", m.Filename, id)
+			case events.DownloadScheduledMsg:
+				finalizeInline(&lastInlineID)
+				name := m.Filename
+				if name == "" {
+					name = "download"
+				}
+				fmt.Printf("Scheduled: %s [%s] starts at %s\n", name, shortID(m.DownloadID), m.StartAt.Format("2006-01-02 15:04"))
 			case events.DownloadPausedMsg:
 				finalizeInline(&lastInlineID)
 				id := m.DownloadID
@@ -295,6 +338,9 @@ func StartHeadlessConsumer() {
 				}
 				delete(progressState, m.DownloadID)
 				fmt.Printf("Removed: %s [%s]\n", m.Filename, id)
+			case events.ScheduleProfileChangedMsg:
+				finalizeInline(&lastInlineID)
+				fmt.Printf("Bandwidth profile: %s\n", describeSchedule(m.Profile, m.RateLimit, m.MaxConcurrentDownloads))
 			case events.ProgressMsg:
 				renderProgressLine(m, progressState, &lastInlineID)
 			case events.BatchProgressMsg:
@@ -560,6 +606,22 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 					eventType = "complete"
 				case events.DownloadErrorMsg:
 					eventType = "error"
+				case events.DownloadChecksumMismatchMsg:
+					eventType = "checksum_mismatch"
+				case events.DownloadRetryMsg:
+					eventType = "retry"
+				case events.DownloadRetryScheduledMsg:
+					eventType = "retry_scheduled"
+				case events.DownloadBackoffMsg:
+					eventType = "backoff"
+				case events.DownloadNeedsURLMsg:
+					eventType = "needs_url"
+				case events.DownloadURLRefreshedMsg:
+					eventType = "url_refreshed"
+				case events.DownloadDiskFullMsg:
+					eventType = "disk_full"
+				case events.DownloadMovingMsg:
+					eventType = "moving"
 				case events.ProgressMsg:
 					eventType = "progress"
 				case events.DownloadPausedMsg:
@@ -568,10 +630,14 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 					eventType = "resumed"
 				case events.DownloadQueuedMsg:
 					eventType = "queued"
+				case events.DownloadScheduledMsg:
+					eventType = "scheduled"
 				case events.DownloadRemovedMsg:
 					eventType = "removed"
 				case events.DownloadRequestMsg:
 					eventType = "request"
+				case events.ScheduleProfileChangedMsg:
+					eventType = "schedule"
 				case events.BatchProgressMsg:
 					// Unroll batch and send individual progress events
 					for _, p := range msg {
@@ -645,6 +711,154 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 		}
 	})
 
+	// Refresh endpoint (Protected). Replaces an expired URL: ?id=&url=.
+	mux.HandleFunc("/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := r.URL.Query().Get("id")
+		newURL := r.URL.Query().Get("url")
+		if id == "" || newURL == "" {
+			http.Error(w, "Missing id or url parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := service.RefreshURL(id, newURL); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{"status": "refreshed", "id": id}); err != nil {
+			utils.Debug("Failed to encode response: %v", err)
+		}
+	})
+
+	// Limit endpoint (Protected). Without an id the global limit changes.
+	mux.HandleFunc("/limit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := r.URL.Query().Get("id")
+		rate, err := ratelimit.ParseRate(r.URL.Query().Get("rate"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := service.SetRateLimit(id, rate); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"status": "limited", "id": id, "rate_limit": rate}); err != nil {
+			utils.Debug("Failed to encode response: %v", err)
+		}
+	})
+
+	// Queue endpoint (Protected). GET lists queued downloads in start order;
+	// POST moves one (?id=&position=N, 0 = bottom) or swaps two (?id=&swap=).
+	mux.HandleFunc("/queue", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			statuses, err := service.List()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			queue := []types.DownloadStatus{}
+			for _, st := range statuses {
+				if st.QueuePos > 0 {
+					queue = append(queue, st)
+				}
+			}
+			sort.Slice(queue, func(i, j int) bool { return queue[i].QueuePos < queue[j].QueuePos })
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(queue); err != nil {
+				utils.Debug("Failed to encode response: %v", err)
+			}
+		case http.MethodPost:
+			q := r.URL.Query()
+			id := q.Get("id")
+			if id == "" {
+				http.Error(w, "Missing id parameter", http.StatusBadRequest)
+				return
+			}
+
+			var err error
+			if other := q.Get("swap"); other != "" {
+				err = service.SwapInQueue(id, other)
+			} else {
+				position, convErr := strconv.Atoi(q.Get("position"))
+				if convErr != nil {
+					http.Error(w, "Invalid position parameter", http.StatusBadRequest)
+					return
+				}
+				err = service.MoveInQueue(id, position)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(map[string]string{"status": "moved", "id": id}); err != nil {
+				utils.Debug("Failed to encode response: %v", err)
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Config endpoint (Protected). GET reports the runtime limits; POST sets
+	// ?max_concurrent_downloads=N, or reloads the settings file when empty.
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if v := r.URL.Query().Get("max_concurrent_downloads"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					http.Error(w, "Invalid max_concurrent_downloads parameter", http.StatusBadRequest)
+					return
+				}
+				if err := service.SetMaxConcurrentDownloads(n); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			} else if err := service.ReloadSettings(); err != nil {
+				http.Error(w, "Failed to reload settings: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		resp := ConfigResponse{MaxConcurrentDownloads: service.Schedule().MaxConcurrentDownloads}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			utils.Debug("Failed to encode response: %v", err)
+		}
+	})
+
+	// Schedule endpoint (Protected). Reports the active bandwidth profile.
+	mux.HandleFunc("/schedule", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(service.Schedule()); err != nil {
+			utils.Debug("Failed to encode response: %v", err)
+		}
+	})
+
 	// Delete endpoint (Protected).
 	mux.HandleFunc("/delete", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodDelete && r.Method != http.MethodPost {
@@ -706,6 +920,21 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 		}
 	})
 
+	// File endpoint (Protected): serves a download's file, ranges included,
+	// while it is still downloading.
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/files/")
+		if id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		serveDownloadFile(w, r, service, id)
+	})
+
 	// Wrap mux with Auth and CORS (CORS outermost to ensure 401/403 include headers).
 	handler := corsMiddleware(authMiddleware(authToken, mux))
 
@@ -715,6 +944,42 @@ func startHTTPServer(ln net.Listener, port int, defaultOutputDir string, service
 	}
 }
 
+// serveDownloadFile serves download id's file with Range support. Reads of
+// bytes not downloaded yet wait for them, and the download fetches them
+// next, so a player can start, and seek, before the file is complete. While
+// the size is unknown, the whole file is streamed without Range support.
+func serveDownloadFile(w http.ResponseWriter, r *http.Request, service core.DownloadService, id string) {
+	status, err := service.GetStatus(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	f, err := service.Open(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// A reader waiting for data only notices a client that left once closed.
+	stop := context.AfterFunc(r.Context(), func() { _ = f.Close() })
+	defer stop()
+	defer func() { _ = f.Close() }()
+
+	// ServeContent seeks to the end to learn the size, which would wait
+	// until a queued download, or one without a Content-Length, has one.
+	if status.TotalSize <= 0 {
+		if ctype := mime.TypeByExtension(filepath.Ext(status.Filename)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("Accept-Ranges", "none")
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			_, _ = io.Copy(w, f)
+		}
+		return
+	}
+	http.ServeContent(w, r, status.Filename, time.Time{}, f)
+}
+
 // corsMiddleware keeps extension and local tools unblocked across origins.
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -749,6 +1014,17 @@ func authMiddleware(token string, next http.Handler) http.Handler {
 			return
 		}
 
+		// Media players cannot set headers, so a file also takes a token
+		// as a query parameter. Query strings end up in logs and player
+		// history, so it is one that only opens that file.
+		if id, ok := strings.CutPrefix(r.URL.Path, "/files/"); ok {
+			expected := fileToken(token, id)
+			if provided := r.URL.Query().Get("token"); provided != "" && len(provided) == len(expected) && subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
 		// Check for Authorization header.
 		authHeader := r.Header.Get("Authorization")
 		if authHeader != "" {
@@ -765,6 +1041,15 @@ func authMiddleware(token string, next http.Handler) http.Handler {
 	})
 }
 
+// fileToken returns the token that opens /files/<id> and nothing else. It is
+// derived from the daemon token, so it needs no storage and stops working
+// when that token changes.
+func fileToken(token, id string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("files/" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
 // ensureAuthToken loads or generates the daemon auth token.
 func ensureAuthToken() string {
 	tokenFile := filepath.Join(config.GetStateDir(), "token")
@@ -787,15 +1072,29 @@ func ensureAuthToken() string {
 }
 
 type DownloadRequest struct {
-	URL                  string            `json:"url"`
-	Filename             string            `json:"filename,omitempty"`
-	Path                 string            `json:"path,omitempty"`
-	RelativeToDefaultDir bool              `json:"relative_to_default_dir,omitempty"`
-	Mirrors              []string          `json:"mirrors,omitempty"`
-	SkipApproval         bool              `json:"skip_approval,omitempty"` // Extension validated request, skip TUI prompt
-	Headers              map[string]string `json:"headers,omitempty"`       // Custom HTTP headers from browser (cookies, auth, etc.)
-	ForceSingle          bool              `json:"force_single,omitempty"`
-	ChunkCount           int               `json:"chunk_count,omitempty"`
+	URL                  string             `json:"url"`
+	Filename             string             `json:"filename,omitempty"`
+	Path                 string             `json:"path,omitempty"`
+	RelativeToDefaultDir bool               `json:"relative_to_default_dir,omitempty"`
+	Mirrors              []string           `json:"mirrors,omitempty"`
+	SkipApproval         bool               `json:"skip_approval,omitempty"` // Extension validated request, skip TUI prompt
+	Headers              map[string]string  `json:"headers,omitempty"`       // Custom HTTP headers from browser (cookies, auth, etc.)
+	ForceSingle          bool               `json:"force_single,omitempty"`
+	ChunkCount           int                `json:"chunk_count,omitempty"`
+	Checksum             string             `json:"checksum,omitempty"` // Expected digest, e.g. "sha256:<hex>"
+	Size                 int64              `json:"size,omitempty"`     // Expected size in bytes
+	Pieces               *types.PieceHashes `json:"pieces,omitempty"`
+	Metalink             string             `json:"metalink,omitempty"`   // Metalink 4.0 document; queues every file it describes
+	RateLimit            int64              `json:"rate_limit,omitempty"` // Speed cap in bytes/sec
+	Priority             int                `json:"priority,omitempty"`   // Higher starts first
+	StartAt              time.Time          `json:"start_at,omitzero"`    // Hold the download as "scheduled" until then
+	PauseAt              time.Time          `json:"pause_at,omitzero"`    // Pause the download at this time
+	TempDir              string             `json:"temp_dir,omitempty"`   // Write the partial here instead of the temp_dir setting
+}
+
+// ConfigResponse reports the limits a running server can change in place.
+type ConfigResponse struct {
+	MaxConcurrentDownloads int `json:"max_concurrent_downloads"`
 }
 
 // handleDownload implements both GET status lookup and POST enqueue.
@@ -850,7 +1149,7 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		}
 	}()
 
-	if req.URL == "" {
+	if req.URL == "" && req.Metalink == "" {
 		http.Error(w, "URL is required", http.StatusBadRequest)
 		return
 	}
@@ -863,6 +1162,29 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 		http.Error(w, "chunk_count cannot be used with force_single", http.StatusBadRequest)
 		return
 	}
+	checksum, err := types.ParseChecksum(req.Checksum)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Size < 0 {
+		http.Error(w, "size must be a positive number", http.StatusBadRequest)
+		return
+	}
+	if req.RateLimit < 0 {
+		http.Error(w, "rate_limit must be a positive number", http.StatusBadRequest)
+		return
+	}
+	if err := types.ValidateSchedule(req.StartAt, req.PauseAt, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Pieces != nil {
+		if req.Pieces, err = types.NewPieceHashes(req.Pieces.Algorithm, req.Pieces.Length, req.Pieces.Hashes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Prevent directory traversal through API payloads.
 	if strings.Contains(req.Path, "..") || strings.Contains(req.Filename, "..") {
@@ -921,6 +1243,26 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	// Enforce absolute path to ensure resume works even if CWD changes.
 	outPath = utils.EnsureAbsPath(outPath)
 
+	opts := &types.AddOptions{
+		ForceSingle: req.ForceSingle,
+		ChunkCount:  req.ChunkCount,
+		Checksum:    checksum,
+		Size:        req.Size,
+		Pieces:      req.Pieces,
+		RateLimit:   req.RateLimit,
+		Priority:    req.Priority,
+		StartAt:     req.StartAt,
+		PauseAt:     req.PauseAt,
+	}
+	if req.TempDir != "" {
+		opts.TempDir = utils.EnsureAbsPath(req.TempDir)
+	}
+
+	if req.Metalink != "" {
+		handleMetalinkRequest(w, req, outPath, opts, settings, service)
+		return
+	}
+
 	// Check settings for extension prompt and duplicates.
 	// Distinguish ACTIVE (corruption risk) and COMPLETED (overwrite safe).
 	isDuplicate := false
@@ -970,10 +1312,6 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	}
 
 	// Add via service.
-	var opts *types.AddOptions
-	if req.ForceSingle || req.ChunkCount > 0 {
-		opts = &types.AddOptions{ForceSingle: req.ForceSingle, ChunkCount: req.ChunkCount}
-	}
 	newID, err := service.Add(urlForAdd, outPath, req.Filename, mirrorsForAdd, req.Headers, opts)
 	if err != nil {
 		http.Error(w, "Failed to add download: "+err.Error(), http.StatusInternalServerError)
@@ -983,96 +1321,171 @@ func handleDownload(w http.ResponseWriter, r *http.Request, defaultOutputDir str
 	// Increment active downloads counter.
 	atomic.AddInt32(&activeDownloads, 1)
 
+	status, message := "queued", "Download queued successfully"
+	if req.StartAt.After(time.Now()) {
+		status, message = "scheduled", "Download scheduled for "+req.StartAt.Local().Format("2006-01-02 15:04")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status":  "queued",
-		"message": "Download queued successfully",
+		"status":  status,
+		"message": message,
 		"id":      newID,
 	}); err != nil {
 		utils.Debug("Failed to encode response: %v", err)
 	}
 }
 
-// processDownloads handles the logic of adding downloads either to local pool or remote server.
-// Returns the number of successfully added downloads.
-func processDownloads(urls []string, outputDir string, filename string, forceSingle bool, chunkCount int, port int) int {
-	successCount := 0
+// handleMetalinkRequest queues every file described by req.Metalink.
+func handleMetalinkRequest(w http.ResponseWriter, req DownloadRequest, outPath string, opts *types.AddOptions, settings *config.Settings, service core.DownloadService) {
+	files, err := metalink.Parse(strings.NewReader(req.Metalink))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locations := metalink.ParseLocations(settings.Connections.MirrorLocations)
 
-	// If port > 0, send to a remote server.
-	if port > 0 {
-		for _, arg := range urls {
-			url, mirrors := ParseURLArg(arg)
-			if url == "" {
-				continue
-			}
-			err := sendToServer(url, mirrors, outputDir, filename, forceSingle, chunkCount, port)
-			if err != nil {
-				fmt.Printf("Error adding %s: %v\n", url, err)
-			} else {
-				successCount++
-			}
+	var ids []string
+	for _, f := range files {
+		mirrors := f.Mirrors(locations)
+		if len(mirrors) == 0 {
+			utils.Debug("Metalink file %s has no HTTP(S) mirrors, skipping", f.Name)
+			continue
 		}
-		return successCount
+		id, err := service.Add(mirrors[0], outPath, f.Name, mirrors, req.Headers, f.AddOptions(opts))
+		if err != nil {
+			http.Error(w, "Failed to add download: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt32(&activeDownloads, 1)
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		http.Error(w, "Metalink lists no HTTP(S) mirrors", http.StatusBadRequest)
+		return
 	}
 
-	// Internal add (TUI or headless mode).
-	if GlobalService == nil {
-		fmt.Fprintln(os.Stderr, "Error: GlobalService not initialized")
-		return 0
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"status":  "queued",
+		"message": fmt.Sprintf("%d download(s) queued from metalink", len(ids)),
+		"id":      ids[0],
+		"ids":     ids,
+	}); err != nil {
+		utils.Debug("Failed to encode response: %v", err)
 	}
+}
 
-	settings, err := config.LoadSettings()
-	if err != nil {
-		settings = config.DefaultSettings()
+// processDownloads handles the logic of adding downloads either to local pool or remote server.
+// Local .meta4/.metalink files among urls are expanded into their files.
+// Returns the number of successfully added downloads.
+func processDownloads(urls []string, outputDir string, filename string, opts *types.AddOptions, port int) int {
+	// Internal add (TUI or headless mode) needs the local service.
+	if port <= 0 && GlobalService == nil {
+		fmt.Fprintln(os.Stderr, "Error: GlobalService not initialized")
+		return 0
 	}
 
+	successCount := 0
 	for _, arg := range urls {
 		// Validation
 		if arg == "" {
 			continue
 		}
 
+		if metalink.IsMetalinkFile(arg) {
+			successCount += processMetalinks([]string{arg}, outputDir, opts, port)
+			continue
+		}
+
 		url, mirrors := ParseURLArg(arg)
 		if url == "" {
 			continue
 		}
 
-		// Prepare output path.
-		outPath := outputDir
-		if outPath == "" {
-			if settings.General.DefaultDownloadDir != "" {
-				outPath = settings.General.DefaultDownloadDir
-				_ = os.MkdirAll(outPath, 0o755)
-			} else {
-				outPath = "."
-			}
+		if err := addDownload(url, mirrors, outputDir, filename, opts, port); err != nil {
+			fmt.Printf("Error adding %s: %v\n", url, err)
+			continue
 		}
-		outPath = utils.EnsureAbsPath(outPath)
+		successCount++
+	}
+	return successCount
+}
 
-		// Check for duplicates/extensions if we are in TUI mode (serverProgram != nil)
-		// For headless/root direct add, we might skip prompt or auto-approve?
-		// For now, let's just add directly if headless, or prompt if TUI is up.
+// processMetalinks expands metalink documents (paths or URLs) into one download
+// per described file, carrying its mirrors, expected size and hashes.
+// Returns the number of successfully added downloads.
+func processMetalinks(sources []string, outputDir string, opts *types.AddOptions, port int) int {
+	if len(sources) == 0 {
+		return 0
+	}
+	if port <= 0 && GlobalService == nil {
+		fmt.Fprintln(os.Stderr, "Error: GlobalService not initialized")
+		return 0
+	}
 
-		// If TUI is up (serverProgram != nil), we might want to send a request msg?
-		// But processDownloads is called from QUEUE init routine, primarily for CLI args.
-		// If CLI args provided, user probably wants them added immediately.
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+	locations := metalink.ParseLocations(settings.Connections.MirrorLocations)
 
-		var opts *types.AddOptions
-		if forceSingle || chunkCount > 0 {
-			opts = &types.AddOptions{ForceSingle: forceSingle, ChunkCount: chunkCount}
-		}
-		_, err := GlobalService.Add(url, outPath, filename, mirrors, nil, opts)
+	successCount := 0
+	for _, src := range sources {
+		files, err := metalink.Load(src)
 		if err != nil {
-			fmt.Printf("Error adding %s: %v\n", url, err)
+			fmt.Fprintf(os.Stderr, "Error reading metalink %s: %v\n", src, err)
 			continue
 		}
-		atomic.AddInt32(&activeDownloads, 1)
-		successCount++
+
+		for _, f := range files {
+			mirrors := f.Mirrors(locations)
+			if len(mirrors) == 0 {
+				fmt.Printf("Skipping %s: metalink lists no HTTP(S) mirrors\n", f.Name)
+				continue
+			}
+			if err := addDownload(mirrors[0], mirrors, outputDir, f.Name, f.AddOptions(opts), port); err != nil {
+				fmt.Printf("Error adding %s: %v\n", f.Name, err)
+				continue
+			}
+			successCount++
+		}
 	}
 	return successCount
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately.
+// addDownload sends one download to a remote server (port > 0) or adds it to the local service.
+func addDownload(url string, mirrors []string, outputDir string, filename string, opts *types.AddOptions, port int) error {
+	if port > 0 {
+		return sendToServer(url, mirrors, outputDir, filename, opts, port)
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		settings = config.DefaultSettings()
+	}
+
+	// Prepare output path.
+	outPath := outputDir
+	if outPath == "" {
+		if settings.General.DefaultDownloadDir != "" {
+			outPath = settings.General.DefaultDownloadDir
+			_ = os.MkdirAll(outPath, 0o755)
+		} else {
+			outPath = "."
+		}
+	}
+	outPath = utils.EnsureAbsPath(outPath)
+
+	// processDownloads is called from the queue init routine, primarily for CLI args.
+	// If CLI args provided, user probably wants them added immediately.
+	if _, err := GlobalService.Add(url, outPath, filename, mirrors, nil, opts); err != nil {
+		return err
+	}
+	atomic.AddInt32(&activeDownloads, 1)
+	return nil
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -1088,6 +1501,15 @@ func init() {
 	rootCmd.Flags().Bool("clipboard", false, "Read URL from clipboard")
 	rootCmd.Flags().Bool("force-single", false, "Force single-connection downloader")
 	rootCmd.Flags().Int("chunks", 0, "Override number of chunks/connections for this download")
+	rootCmd.Flags().String("limit-rate", "", "Cap this download's speed, e.g. 500K or 5M (bytes/sec)")
+	rootCmd.Flags().String("priority", "", "Queue priority: high, normal, low or a number (higher starts first)")
+	rootCmd.Flags().String("temp-dir", "", "Write the partial file here and move it to the output directory when done")
+	rootCmd.Flags().String("at", "", "Keep the download scheduled until a time, e.g. 02:00 or \"2026-01-31 02:00\"")
+	rootCmd.Flags().Duration("after", 0, "Keep the download scheduled for a while, e.g. 2h")
+	rootCmd.Flags().String("pause-at", "", "Pause the download at a time, e.g. 07:00")
+	rootCmd.Flags().Duration("pause-after", 0, "Pause the download after a while, e.g. 6h")
+	rootCmd.Flags().String("checksum", "", "Expected digest verified on completion, e.g. sha256:<hex> (single URL only)")
+	rootCmd.Flags().StringArray("metalink", nil, "Metalink (.meta4) file or URL to download (repeatable)")
 	rootCmd.Flags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
 	rootCmd.Flags().Bool("exit-when-done", false, "Exit when all downloads complete")
 	rootCmd.SetVersionTemplate("GoFetch v{{.Version}}\n")
@@ -3,6 +3,7 @@ package cli
 import (
 	"concurrent_downloader/internal/config"
 	"concurrent_downloader/internal/core"
+	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/utils"
 	"fmt"
 
@@ -52,17 +53,22 @@ var serverStartCmd = &cobra.Command{
 		batchFile, _ := cmd.Flags().GetString("batch")
 		outputDir, _ := cmd.Flags().GetString("output")
 		filename, _ := cmd.Flags().GetString("filename")
-		forceSingle, _ := cmd.Flags().GetBool("force-single")
-		chunkCount, _ := cmd.Flags().GetInt("chunks")
 		exitWhenDone, _ := cmd.Flags().GetBool("exit-when-done")
 		noResume, _ := cmd.Flags().GetBool("no-resume")
+		metalinks, _ := cmd.Flags().GetStringArray("metalink")
+
+		opts, err := addOptionsFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		// Save current PID to file for status/stop commands.
 		savePID()
 		defer removePID()
 
 		// Hand off to shared server start logic.
-		startServerLogic(cmd, args, portFlag, batchFile, outputDir, filename, forceSingle, chunkCount, exitWhenDone, noResume)
+		startServerLogic(cmd, args, metalinks, portFlag, batchFile, outputDir, filename, opts, exitWhenDone, noResume)
 	},
 }
 
@@ -136,6 +142,20 @@ var serverStatusCmd = &cobra.Command{
 
 		port := readActivePort()
 		fmt.Printf("GoFetch server is running (PID: %d, Port: %d).\n", pid, port)
+
+		if port == 0 {
+			return
+		}
+		schedule, err := GetRemoteSchedule(port)
+		if err != nil {
+			utils.Debug("Failed to fetch schedule: %v", err)
+			return
+		}
+		note := ""
+		if !schedule.Enabled {
+			note = " [schedule off]"
+		}
+		fmt.Printf("Bandwidth profile: %s%s\n", describeSchedule(schedule.Profile, schedule.RateLimit, schedule.MaxConcurrentDownloads), note)
 	},
 }
 
@@ -151,6 +171,15 @@ func init() {
 	serverStartCmd.Flags().StringP("filename", "n", "", "Override output filename (single URL only)")
 	serverStartCmd.Flags().Bool("force-single", false, "Force single-connection downloader")
 	serverStartCmd.Flags().Int("chunks", 0, "Override number of chunks/connections for this download")
+	serverStartCmd.Flags().String("limit-rate", "", "Cap this download's speed, e.g. 500K or 5M (bytes/sec)")
+	serverStartCmd.Flags().String("priority", "", "Queue priority: high, normal, low or a number (higher starts first)")
+	serverStartCmd.Flags().String("temp-dir", "", "Write the partial file here and move it to the output directory when done")
+	serverStartCmd.Flags().String("at", "", "Keep the download scheduled until a time, e.g. 02:00 or \"2026-01-31 02:00\"")
+	serverStartCmd.Flags().Duration("after", 0, "Keep the download scheduled for a while, e.g. 2h")
+	serverStartCmd.Flags().String("pause-at", "", "Pause the download at a time, e.g. 07:00")
+	serverStartCmd.Flags().Duration("pause-after", 0, "Pause the download after a while, e.g. 6h")
+	serverStartCmd.Flags().String("checksum", "", "Expected digest verified on completion, e.g. sha256:<hex> (single URL only)")
+	serverStartCmd.Flags().StringArray("metalink", nil, "Metalink (.meta4) file or URL to download (repeatable)")
 	serverStartCmd.Flags().Bool("exit-when-done", false, "Exit when all downloads complete")
 	serverStartCmd.Flags().Bool("no-resume", false, "Do not auto-resume paused downloads on startup")
 }
@@ -191,7 +220,7 @@ func cleanupRuntimeFiles() {
 	}
 }
 
-func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile string, outputDir string, filename string, forceSingle bool, chunkCount int, exitWhenDone bool, noResume bool) {
+func startServerLogic(cmd *cobra.Command, args []string, metalinks []string, portFlag int, batchFile string, outputDir string, filename string, opts *types.AddOptions, exitWhenDone bool, noResume bool) {
 	port, listener, err := bindServerListener(portFlag)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -225,8 +254,13 @@ func startServerLogic(cmd *cobra.Command, args []string, portFlag int, batchFile
 				fmt.Fprintln(os.Stderr, "Error: --filename can only be used with a single URL")
 				return
 			}
-			processDownloads(urls, outputDir, filename, forceSingle, chunkCount, 0)
+			if opts != nil && opts.Checksum != nil && len(urls) > 1 {
+				fmt.Fprintln(os.Stderr, "Error: --checksum can only be used with a single URL")
+				return
+			}
+			processDownloads(urls, outputDir, filename, opts, 0)
 		}
+		processMetalinks(metalinks, outputDir, opts, 0)
 	}()
 
 	fmt.Printf("GoFetch %s running in server mode.\n", Version)
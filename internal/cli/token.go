@@ -9,12 +9,20 @@ import (
 var tokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "Print the auth token used by the GoFetch daemon",
+	Long: `Print the auth token used by the GoFetch daemon.
+
+With --file, print a token that only opens /files/<id> of one download, for
+players that take it as ?token= in the URL.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		token := ensureAuthToken()
+		if id, _ := cmd.Flags().GetString("file"); id != "" {
+			token = fileToken(token, id)
+		}
 		fmt.Println(token)
 	},
 }
 
 func init() {
+	tokenCmd.Flags().String("file", "", "Print a token that only opens the file of this download ID")
 	rootCmd.AddCommand(tokenCmd)
 }
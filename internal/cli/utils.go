@@ -7,13 +7,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"concurrent_downloader/internal/config"
+	"concurrent_downloader/internal/download/ratelimit"
 	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/state"
+	"concurrent_downloader/internal/utils"
+
+	"github.com/spf13/cobra"
 )
 
 // readActivePort reads the port from the port file written by the daemon.
@@ -63,15 +69,115 @@ func ParseURLArg(arg string) (string, []string) {
 	return urls[0], urls
 }
 
-func sendToServer(url string, mirrors []string, outPath string, filename string, forceSingle bool, chunkCount int, port int) error {
-	// Keep payload minimal; server applies defaults and validation.
-	reqBody := DownloadRequest{
-		URL:         url,
-		Filename:    filename,
-		Mirrors:     mirrors,
-		Path:        outPath,
+// addOptionsFromFlags collects the per-download overrides shared by the add,
+// root and server start commands. Returns nil when no override was given.
+func addOptionsFromFlags(cmd *cobra.Command) (*types.AddOptions, error) {
+	forceSingle, _ := cmd.Flags().GetBool("force-single")
+	chunkCount, _ := cmd.Flags().GetInt("chunks")
+	checksumFlag, _ := cmd.Flags().GetString("checksum")
+	limitRate, _ := cmd.Flags().GetString("limit-rate")
+	priorityFlag, _ := cmd.Flags().GetString("priority")
+	at, _ := cmd.Flags().GetString("at")
+	after, _ := cmd.Flags().GetDuration("after")
+	pauseAtFlag, _ := cmd.Flags().GetString("pause-at")
+	pauseAfter, _ := cmd.Flags().GetDuration("pause-after")
+	tempDir, _ := cmd.Flags().GetString("temp-dir")
+
+	if forceSingle && chunkCount > 0 {
+		return nil, fmt.Errorf("--chunks cannot be used with --force-single")
+	}
+	if chunkCount < 0 {
+		return nil, fmt.Errorf("--chunks must be a positive number")
+	}
+
+	checksum, err := types.ParseChecksum(checksumFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	var rateLimit int64
+	if limitRate != "" {
+		if rateLimit, err = ratelimit.ParseRate(limitRate); err != nil {
+			return nil, fmt.Errorf("--limit-rate: %w", err)
+		}
+	}
+
+	priority, err := types.ParsePriority(priorityFlag)
+	if err != nil {
+		return nil, fmt.Errorf("--priority: %w", err)
+	}
+
+	now := time.Now()
+	startAt, err := scheduleTimeFromFlags("at", at, "after", after, now)
+	if err != nil {
+		return nil, err
+	}
+	pauseAt, err := scheduleTimeFromFlags("pause-at", pauseAtFlag, "pause-after", pauseAfter, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := types.ValidateSchedule(startAt, pauseAt, now); err != nil {
+		return nil, err
+	}
+
+	if tempDir != "" {
+		tempDir = utils.EnsureAbsPath(tempDir)
+	}
+
+	if !forceSingle && chunkCount == 0 && checksum == nil && rateLimit == 0 && priority == 0 && startAt.IsZero() && pauseAt.IsZero() && tempDir == "" {
+		return nil, nil
+	}
+	return &types.AddOptions{
 		ForceSingle: forceSingle,
 		ChunkCount:  chunkCount,
+		Checksum:    checksum,
+		RateLimit:   rateLimit,
+		Priority:    priority,
+		StartAt:     startAt,
+		PauseAt:     pauseAt,
+		TempDir:     tempDir,
+	}, nil
+}
+
+// scheduleTimeFromFlags resolves a "--x-at <time>" / "--x-after <duration>"
+// flag pair to an absolute time (zero when neither is set).
+func scheduleTimeFromFlags(atName, at, afterName string, after time.Duration, now time.Time) (time.Time, error) {
+	switch {
+	case at != "" && after != 0:
+		return time.Time{}, fmt.Errorf("--%s cannot be used with --%s", atName, afterName)
+	case after < 0:
+		return time.Time{}, fmt.Errorf("--%s must not be negative", afterName)
+	case after > 0:
+		return now.Add(after), nil
+	case at != "":
+		t, err := types.ParseScheduleTime(at, now)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("--%s: %w", atName, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+func sendToServer(url string, mirrors []string, outPath string, filename string, opts *types.AddOptions, port int) error {
+	// Keep payload minimal; server applies defaults and validation.
+	reqBody := DownloadRequest{
+		URL:      url,
+		Filename: filename,
+		Mirrors:  mirrors,
+		Path:     outPath,
+	}
+	if opts != nil {
+		reqBody.ForceSingle = opts.ForceSingle
+		reqBody.ChunkCount = opts.ChunkCount
+		reqBody.Checksum = opts.Checksum.String()
+		reqBody.Size = opts.Size
+		reqBody.Pieces = opts.Pieces
+		reqBody.RateLimit = opts.RateLimit
+		reqBody.Priority = opts.Priority
+		reqBody.StartAt = opts.StartAt
+		reqBody.PauseAt = opts.PauseAt
+		reqBody.TempDir = opts.TempDir
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -102,8 +208,23 @@ func sendToServer(url string, mirrors []string, outPath string, filename string,
 	return nil
 }
 
+// serverRequest calls the running server's control API with the daemon's
+// auth token, which every endpoint except /health requires.
+func serverRequest(method string, port int, path string, query url.Values) (*http.Response, error) {
+	target := fmt.Sprintf("http://127.0.0.1:%d%s", port, path)
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ensureAuthToken())
+	return http.DefaultClient.Do(req)
+}
+
 func GetRemoteDownloads(port int) ([]types.DownloadStatus, error) {
-	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/list", port))
+	resp, err := serverRequest(http.MethodGet, port, "/list", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +242,37 @@ func GetRemoteDownloads(port int) ([]types.DownloadStatus, error) {
 	return statuses, nil
 }
 
+// GetRemoteSchedule fetches the bandwidth profile the server is applying.
+func GetRemoteSchedule(port int) (*types.ScheduleStatus, error) {
+	resp, err := serverRequest(http.MethodGet, port, "/schedule", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status: %s", resp.Status)
+	}
+
+	var status types.ScheduleStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// describeSchedule formats a bandwidth profile and its limits for display.
+func describeSchedule(profile string, rateLimit int64, maxDownloads int) string {
+	if profile == "" {
+		profile = "default"
+	}
+	rate := "unlimited"
+	if rateLimit > 0 {
+		rate = utils.ConvertBytesToHumanReadable(rateLimit) + "/s"
+	}
+	return fmt.Sprintf("%s (speed %s, up to %d downloads)", profile, rate, maxDownloads)
+}
+
 func resolveDownloadID(partialID string) (string, error) {
 	if len(partialID) >= 32 {
 		return partialID, nil // Already a full UUID
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Active returns a copy of the first profile whose window contains now, with
+// its name filled in, or nil when the schedule is off or no window matches.
+// Invalid profiles are skipped.
+func (s ScheduleSettings) Active(now time.Time) *ScheduleProfile {
+	if !s.Enabled {
+		return nil
+	}
+	for i, p := range s.Profiles {
+		if p.contains(now) {
+			p.Name = p.DisplayName(i)
+			return &p
+		}
+	}
+	return nil
+}
+
+// Validate reports the first profile with an unparseable time or day.
+func (s ScheduleSettings) Validate() error {
+	for i, p := range s.Profiles {
+		if _, err := parseClock(p.Start); err != nil {
+			return fmt.Errorf("schedule profile %q: start: %w", p.DisplayName(i), err)
+		}
+		if _, err := parseClock(p.End); err != nil {
+			return fmt.Errorf("schedule profile %q: end: %w", p.DisplayName(i), err)
+		}
+		for _, d := range p.Days {
+			if _, ok := dayNames[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("schedule profile %q: unknown day %q", p.DisplayName(i), d)
+			}
+		}
+		if p.RateLimit < 0 || p.MaxConcurrentDownloads < 0 {
+			return fmt.Errorf("schedule profile %q: limits must not be negative", p.DisplayName(i))
+		}
+	}
+	return nil
+}
+
+// DisplayName returns the profile's name, or a positional one when unset.
+func (p ScheduleProfile) DisplayName(index int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("profile %d", index+1)
+}
+
+// contains reports whether now falls inside the profile's window. A window
+// that wraps past midnight belongs to the day it starts on.
+func (p ScheduleProfile) contains(now time.Time) bool {
+	start, err := parseClock(p.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.End)
+	if err != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+	switch {
+	case start == end: // All day
+	case start < end:
+		if minute < start || minute >= end {
+			return false
+		}
+	case minute >= start: // Before midnight of a wrapping window
+	case minute < end: // After midnight: the window began yesterday
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return p.onDay(day)
+}
+
+func (p ScheduleProfile) onDay(day time.Weekday) bool {
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, d := range p.Days {
+		for _, wd := range dayNames[strings.ToLower(d)] {
+			if wd == day {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var dayNames = map[string][]time.Weekday{
+	"sun":      {time.Sunday},
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// parseClock converts "HH:MM" to minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// at returns the given clock time on the week of Monday 2024-05-06.
+func at(day time.Weekday, clock string) time.Time {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		panic(err)
+	}
+	monday := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)
+	offset := (int(day) + 6) % 7 // Days after Monday
+	return monday.AddDate(0, 0, offset).Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+}
+
+func TestScheduleActive(t *testing.T) {
+	s := ScheduleSettings{
+		Enabled: true,
+		Profiles: []ScheduleProfile{
+			{Name: "night", Days: []string{"weekdays"}, Start: "23:00", End: "07:00", RateLimit: 1},
+			{Start: "09:00", End: "17:00", Days: []string{"Sat"}, RateLimit: 2},
+			{Name: "broken", Start: "25:00", End: "26:00", RateLimit: 3},
+			{Name: "sunday", Days: []string{"sun"}, Start: "12:00", End: "12:00", RateLimit: 4},
+		},
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want string // Profile name, "" for none
+	}{
+		{"evening of a weekday", at(time.Monday, "23:30"), "night"},
+		{"start is inclusive", at(time.Friday, "23:00"), "night"},
+		{"after midnight on a weekday", at(time.Tuesday, "06:59"), "night"},
+		{"end is exclusive", at(time.Tuesday, "07:00"), ""},
+		{"saturday morning after friday night", at(time.Saturday, "03:00"), "night"},
+		{"monday morning after sunday night", at(time.Monday, "03:00"), ""},
+		{"saturday evening", at(time.Saturday, "23:30"), ""},
+		{"unnamed profile", at(time.Saturday, "10:00"), "profile 2"},
+		{"outside the window", at(time.Saturday, "17:00"), ""},
+		{"all day", at(time.Sunday, "00:00"), "sunday"},
+		{"all day, last minute", at(time.Sunday, "23:59"), "sunday"},
+		{"midday weekday", at(time.Wednesday, "12:00"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Active(tt.now)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("Active(%s) = %q, want %q", tt.now.Format("Mon 15:04"), name, tt.want)
+			}
+		})
+	}
+
+	s.Enabled = false
+	if got := s.Active(at(time.Monday, "23:30")); got != nil {
+		t.Errorf("disabled schedule returned %q", got.Name)
+	}
+}
+
+func TestScheduleActiveReturnsCopy(t *testing.T) {
+	s := ScheduleSettings{Enabled: true, Profiles: []ScheduleProfile{{Start: "00:00", End: "00:00"}}}
+	got := s.Active(at(time.Monday, "10:00"))
+	if got == nil {
+		t.Fatal("no active profile")
+	}
+	got.RateLimit = 99
+	if s.Profiles[0].Name != "" || s.Profiles[0].RateLimit != 0 {
+		t.Errorf("Active changed the settings: %+v", s.Profiles[0])
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile ScheduleProfile
+		wantErr string
+	}{
+		{"valid", ScheduleProfile{Start: "22:00", End: "6:30", Days: []string{"Mon", "weekends"}}, ""},
+		{"bad start", ScheduleProfile{Start: "10pm", End: "06:00"}, "start: invalid time"},
+		{"bad end", ScheduleProfile{Start: "22:00", End: "24:00"}, "end: invalid time"},
+		{"bad day", ScheduleProfile{Start: "22:00", End: "06:00", Days: []string{"monday"}}, `unknown day "monday"`},
+		{"negative limit", ScheduleProfile{Name: "x", Start: "22:00", End: "06:00", RateLimit: -1}, `"x": limits must not be negative`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ScheduleSettings{Profiles: []ScheduleProfile{tt.profile}}.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ type Settings struct {
 	Chunks      ChunkSettings       `json:"chunks"`
 	Performance PerformanceSettings `json:"performance"`
 	Network     NetworkSettings     `json:"network"`
+	Schedule    ScheduleSettings    `json:"schedule"`
 }
 
 type NetworkSettings struct {
@@ -25,15 +26,19 @@ type NetworkSettings struct {
 	MinChunkSize           int64  `json:"min_chunk_size"`
 	WorkerBufferSize       int    `json:"worker_buffer_size"`
 	ProtocolPreference     string `json:"protocol_preference"`
+	GlobalRateLimit        int64  `json:"global_rate_limit"` // Bytes per second across all downloads, 0 = unlimited
 }
 
 // GeneralSettings contains application behavior settings.
 type GeneralSettings struct {
 	DefaultDownloadDir string `json:"default_download_dir"`
+	TempDir            string `json:"temp_dir"` // Partial files go here; empty keeps them next to the destination
 	WarnOnDuplicate    bool   `json:"warn_on_duplicate"`
 	ExtensionPrompt    bool   `json:"extension_prompt"`
 	AutoResume         bool   `json:"auto_resume"`
 	SkipUpdateCheck    bool   `json:"skip_update_check"`
+	DiskReserve        int64  `json:"disk_reserve"` // Bytes to keep free on the destination filesystem
+	Preallocate        bool   `json:"preallocate"`  // Reserve a file's blocks before downloading
 
 	ClipboardMonitor  bool `json:"clipboard_monitor"`
 	Theme             int  `json:"theme"`
@@ -55,21 +60,51 @@ type ConnectionSettings struct {
 	ProxyURL               string `json:"proxy_url"`
 	SequentialDownload     bool   `json:"sequential_download"`
 	ProtocolPreference     string `json:"protocol_preference"`
+	MirrorLocations        string `json:"mirror_locations"`
 }
 
 // ChunkSettings contains download chunk configuration.
 type ChunkSettings struct {
 	MinChunkSize     int64 `json:"min_chunk_size"`
 	WorkerBufferSize int   `json:"worker_buffer_size"`
+	WriteBuffer      int64 `json:"write_buffer"`     // Bytes of writes to gather per download, 0 = off
+	WriteDropCache   bool  `json:"write_drop_cache"` // Drop written ranges from the page cache
 }
 
 // PerformanceSettings contains performance tuning parameters.
 type PerformanceSettings struct {
 	MaxTaskRetries        int           `json:"max_task_retries"`
+	RetryBaseDelay        time.Duration `json:"retry_base_delay"`
 	SlowWorkerThreshold   float64       `json:"slow_worker_threshold"`
 	SlowWorkerGracePeriod time.Duration `json:"slow_worker_grace_period"`
 	StallTimeout          time.Duration `json:"stall_timeout"`
 	SpeedEmaAlpha         float64       `json:"speed_ema_alpha"`
+
+	// Automatic retries of a whole download that failed
+	DownloadRetries       int           `json:"download_retries"` // 0 = off
+	DownloadRetryDelay    time.Duration `json:"download_retry_delay"`
+	DownloadRetryMaxDelay time.Duration `json:"download_retry_max_delay"`
+	RetryOn               string        `json:"retry_on"` // Comma-separated error kinds
+
+	OnRemoteChange string `json:"on_remote_change"` // "restart" or "fail" when a partial no longer matches the server
+}
+
+// ScheduleSettings switches speed and concurrency limits by time of day.
+// The first profile whose window contains the current time is applied;
+// outside every window the Network/Connections limits are used.
+type ScheduleSettings struct {
+	Enabled  bool              `json:"enabled"`
+	Profiles []ScheduleProfile `json:"profiles"`
+}
+
+// ScheduleProfile applies limits during a daily window on the given days.
+type ScheduleProfile struct {
+	Name                   string   `json:"name"`
+	Days                   []string `json:"days"`                     // "mon".."sun", "weekdays" or "weekends"; empty means every day
+	Start                  string   `json:"start"`                    // "HH:MM" local time, inclusive
+	End                    string   `json:"end"`                      // "HH:MM", exclusive; before Start wraps past midnight
+	RateLimit              int64    `json:"rate_limit"`               // Bytes per second across all downloads, 0 = unlimited
+	MaxConcurrentDownloads int      `json:"max_concurrent_downloads"` // 0 keeps the configured limit
 }
 
 // SettingMeta provides metadata for a single setting (for UI rendering).
@@ -85,10 +120,13 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 	return map[string][]SettingMeta{
 		"General": {
 			{Key: "default_download_dir", Label: "Default Download Dir", Description: "Default directory for new downloads. Leave empty to use current directory.", Type: "string"},
+			{Key: "temp_dir", Label: "Temp Dir", Description: "Directory for partial files while downloading, e.g. a fast local disk. Finished files are moved to their destination. Leave empty to keep partials next to the destination.", Type: "string"},
 			{Key: "warn_on_duplicate", Label: "Warn on Duplicate", Description: "Show warning when adding a download that already exists.", Type: "bool"},
 			{Key: "extension_prompt", Label: "Extension Prompt", Description: "Prompt for confirmation when adding downloads via browser extension.", Type: "bool"},
 			{Key: "auto_resume", Label: "Auto Resume", Description: "Automatically resume paused downloads on startup.", Type: "bool"},
 			{Key: "skip_update_check", Label: "Skip Update Check", Description: "Disable automatic check for new versions on startup.", Type: "bool"},
+			{Key: "disk_reserve", Label: "Disk Reserve", Description: "Free space in bytes to leave on the destination disk. Downloads that would use it wait for space instead.", Type: "int64"},
+			{Key: "preallocate", Label: "Preallocate Files", Description: "Reserve the whole file on disk before downloading (Linux), so a full disk is caught up front.", Type: "bool"},
 
 			{Key: "clipboard_monitor", Label: "Clipboard Monitor", Description: "Watch clipboard for URLs and prompt to download them.", Type: "bool"},
 			{Key: "theme", Label: "App Theme", Description: "UI Theme (System, Light, Dark).", Type: "int"},
@@ -97,20 +135,30 @@ func GetSettingsMetadata() map[string][]SettingMeta {
 		"Network": {
 			{Key: "max_connections_per_host", Label: "Max Connections/Host", Description: "Maximum concurrent connections per host (1-64).", Type: "int"},
 			{Key: "max_global_connections", Label: "Max Global Connections", Description: "Maximum total concurrent connections across all downloads.", Type: "int"},
-			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Requires restart.", Type: "int"},
+			{Key: "global_rate_limit", Label: "Global Rate Limit", Description: "Maximum combined download speed in bytes/sec. 0 means unlimited.", Type: "int64"},
+			{Key: "max_concurrent_downloads", Label: "Max Concurrent Downloads", Description: "Maximum number of downloads running at once (1-10). Lowering it lets running downloads finish first.", Type: "int"},
 			{Key: "user_agent", Label: "User Agent", Description: "Custom User-Agent string for HTTP requests. Leave empty for default.", Type: "string"},
 			{Key: "proxy_url", Label: "Proxy URL", Description: "HTTP/HTTPS proxy URL (e.g. http://127.0.0.1:8080). Leave empty to use system default.", Type: "string"},
 			{Key: "sequential_download", Label: "Sequential Download", Description: "Download pieces in order (Streaming Mode). May be slower.", Type: "bool"},
 			{Key: "min_chunk_size", Label: "Min Chunk Size", Description: "Minimum download chunk size in MB (e.g., 2).", Type: "int64"},
 			{Key: "worker_buffer_size", Label: "Worker Buffer Size", Description: "I/O buffer size per worker in KB (e.g., 512).", Type: "int"},
+			{Key: "write_buffer", Label: "Write Buffer", Description: "Memory in bytes per download for gathering neighbouring chunk writes and writing them in file order. Helps hard disks and network filesystems. 0 writes directly.", Type: "int64"},
+			{Key: "write_drop_cache", Label: "Drop Written Pages", Description: "Write buffered data out right away and drop it from the page cache (Linux), so many connections do not fill memory with dirty pages.", Type: "bool"},
 			{Key: "protocol_preference", Label: "Protocol Preference", Description: "Transport preference: auto | http1 | http2 | http3. Auto probes and prefers http3 -> http1 -> http2 for chunked downloads.", Type: "string"},
+			{Key: "mirror_locations", Label: "Mirror Locations", Description: "Preferred metalink mirror countries, comma-separated (e.g. de,nl). Leave empty to use metalink priorities only.", Type: "string"},
 		},
 		"Performance": {
 			{Key: "max_task_retries", Label: "Max Task Retries", Description: "Number of times to retry a failed chunk before giving up.", Type: "int"},
+			{Key: "retry_base_delay", Label: "Retry Base Delay", Description: "Initial backoff between retries, doubled on each attempt (e.g., 200ms).", Type: "duration"},
 			{Key: "slow_worker_threshold", Label: "Slow Worker Threshold", Description: "Restart workers slower than this fraction of mean speed (0.0-1.0).", Type: "float64"},
 			{Key: "slow_worker_grace_period", Label: "Slow Worker Grace", Description: "Grace period before checking worker speed (e.g., 5s).", Type: "duration"},
 			{Key: "stall_timeout", Label: "Stall Timeout", Description: "Restart workers with no data for this duration (e.g., 5s).", Type: "duration"},
 			{Key: "speed_ema_alpha", Label: "Speed EMA Alpha", Description: "Exponential moving average smoothing factor (0.0-1.0).", Type: "float64"},
+			{Key: "download_retries", Label: "Download Retries", Description: "Times to retry a failed download automatically, continuing from the partial file. 0 disables.", Type: "int"},
+			{Key: "download_retry_delay", Label: "Download Retry Delay", Description: "Wait before the first automatic retry, doubled for each one after (e.g., 30s).", Type: "duration"},
+			{Key: "download_retry_max_delay", Label: "Download Retry Max Delay", Description: "Longest wait between automatic retries (e.g., 10m).", Type: "duration"},
+			{Key: "retry_on", Label: "Retry On", Description: "Error kinds that are retried, comma-separated: network, http_status (5xx, 408, 429), file_changed, disk_full, permission, checksum, auth_required, unknown.", Type: "string"},
+			{Key: "on_remote_change", Label: "On Remote Change", Description: "What to do when the file on the server changed since a partial was saved: restart | fail.", Type: "string"},
 		},
 	}
 }
@@ -136,6 +184,8 @@ func DefaultSettings() *Settings {
 			WarnOnDuplicate:    true,
 			ExtensionPrompt:    false,
 			AutoResume:         false,
+			DiskReserve:        100 * MB,
+			Preallocate:        false,
 
 			ClipboardMonitor:  true,
 			Theme:             ThemeAdaptive,
@@ -155,10 +205,16 @@ func DefaultSettings() *Settings {
 		},
 		Performance: PerformanceSettings{
 			MaxTaskRetries:        3,
+			RetryBaseDelay:        200 * time.Millisecond,
 			SlowWorkerThreshold:   0.3,
 			SlowWorkerGracePeriod: 5 * time.Second,
 			StallTimeout:          3 * time.Second,
 			SpeedEmaAlpha:         0.3,
+			DownloadRetries:       3,
+			DownloadRetryDelay:    30 * time.Second,
+			DownloadRetryMaxDelay: 10 * time.Minute,
+			RetryOn:               "network,http_status,file_changed",
+			OnRemoteChange:        "restart",
 		},
 	}
 }
@@ -212,6 +268,15 @@ func SaveSettings(s *Settings) error {
 	return os.Rename(tempPath, path)
 }
 
+// MaxConcurrentDownloads returns the download concurrency limit, preferring
+// the newer network setting over the legacy connections one (0 if neither is set).
+func (s *Settings) MaxConcurrentDownloads() int {
+	if s.Network.MaxConcurrentDownloads > 0 {
+		return s.Network.MaxConcurrentDownloads
+	}
+	return s.Connections.MaxConcurrentDownloads
+}
+
 type RuntimeConfig struct {
 	MaxConnectionsPerHost int
 	MaxGlobalConnections  int
@@ -223,10 +288,21 @@ type RuntimeConfig struct {
 	ForceSingle           bool
 	ProtocolPreference    string
 	MaxTaskRetries        int
+	RetryBaseDelay        time.Duration
 	SlowWorkerThreshold   float64
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+	DownloadRetries       int
+	DownloadRetryDelay    time.Duration
+	DownloadRetryMaxDelay time.Duration
+	RetryOn               string
+	OnRemoteChange        string
+	DiskReserve           int64
+	Preallocate           bool
+	TempDir               string
+	WriteBuffer           int64
+	WriteDropCache        bool
 }
 
 // ToRuntimeConfig projects persisted settings into runtime-only config.
@@ -241,9 +317,20 @@ func (s *Settings) ToRuntimeConfig() *RuntimeConfig {
 		WorkerBufferSize:      s.Chunks.WorkerBufferSize,
 		ProtocolPreference:    s.Connections.ProtocolPreference,
 		MaxTaskRetries:        s.Performance.MaxTaskRetries,
+		RetryBaseDelay:        s.Performance.RetryBaseDelay,
 		SlowWorkerThreshold:   s.Performance.SlowWorkerThreshold,
 		SlowWorkerGracePeriod: s.Performance.SlowWorkerGracePeriod,
 		StallTimeout:          s.Performance.StallTimeout,
 		SpeedEmaAlpha:         s.Performance.SpeedEmaAlpha,
+		DownloadRetries:       s.Performance.DownloadRetries,
+		DownloadRetryDelay:    s.Performance.DownloadRetryDelay,
+		DownloadRetryMaxDelay: s.Performance.DownloadRetryMaxDelay,
+		RetryOn:               s.Performance.RetryOn,
+		OnRemoteChange:        s.Performance.OnRemoteChange,
+		DiskReserve:           s.General.DiskReserve,
+		Preallocate:           s.General.Preallocate,
+		TempDir:               s.General.TempDir,
+		WriteBuffer:           s.Chunks.WriteBuffer,
+		WriteDropCache:        s.Chunks.WriteDropCache,
 	}
 }
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/events"
+	"concurrent_downloader/internal/state"
+	"concurrent_downloader/internal/utils"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// deadline is a pending start-at or pause-at time for one download.
+type deadline struct {
+	at    time.Time
+	timer *time.Timer
+}
+
+// scheduleDownload persists cfg as a "scheduled" download and queues it at
+// startAt. The row, rate limit included, survives restarts; the rest of the
+// config (headers, connection overrides) only lives until this process exits.
+func (s *LocalDownloadService) scheduleDownload(cfg types.DownloadConfig, startAt, pauseAt time.Time) error {
+	entry := types.DownloadEntry{
+		ID:        cfg.ID,
+		URL:       cfg.URL,
+		URLHash:   state.URLHash(cfg.URL),
+		DestPath:  filepath.Join(cfg.OutputPath, cfg.Filename),
+		Filename:  cfg.Filename,
+		Status:    "scheduled",
+		Mirrors:   cfg.Mirrors,
+		Checksum:  cfg.Checksum.String(),
+		StartAt:   startAt.Unix(),
+		Priority:  cfg.Priority,
+		RateLimit: cfg.RateLimit,
+	}
+	if !pauseAt.IsZero() {
+		entry.PauseAt = pauseAt.Unix()
+	}
+	if err := state.AddToMasterList(entry); err != nil {
+		return fmt.Errorf("failed to save scheduled download: %w", err)
+	}
+
+	s.deadlineMu.Lock()
+	s.pending[cfg.ID] = cfg
+	s.deadlineMu.Unlock()
+	s.armDeadline(s.starts, cfg.ID, startAt, s.startScheduled)
+
+	if s.InputCh != nil {
+		s.InputCh <- events.DownloadScheduledMsg{
+			DownloadID: cfg.ID,
+			Filename:   cfg.Filename,
+			StartAt:    startAt,
+			PauseAt:    pauseAt,
+		}
+	}
+	return nil
+}
+
+// restoreScheduled re-arms the start times of downloads scheduled before a
+// restart. Overdue ones start right away.
+func (s *LocalDownloadService) restoreScheduled() {
+	entries, err := state.LoadScheduledDownloads()
+	if err != nil {
+		utils.Debug("Failed to load scheduled downloads: %v", err)
+		return
+	}
+	for _, e := range entries {
+		s.armDeadline(s.starts, e.ID, time.Unix(e.StartAt, 0), s.startScheduled)
+	}
+}
+
+// startScheduled moves a scheduled download into the pool's queue.
+func (s *LocalDownloadService) startScheduled(id string) {
+	s.clearDeadline(s.starts, id)
+
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil || entry.Status != "scheduled" {
+		return
+	}
+
+	s.deadlineMu.Lock()
+	cfg, ok := s.pending[id]
+	delete(s.pending, id)
+	s.deadlineMu.Unlock()
+	if !ok {
+		cfg = s.configFromEntry(entry)
+	}
+
+	if err := state.UpdateStatus(id, "queued"); err != nil {
+		utils.Debug("Failed to queue scheduled download %s: %v", id, err)
+	}
+	if err := state.UpdateSchedule(id, 0, entry.PauseAt); err != nil {
+		utils.Debug("Failed to clear start time of %s: %v", id, err)
+	}
+
+	utils.Debug("Starting scheduled download %s", id)
+	s.Pool.Add(cfg)
+	if entry.PauseAt > 0 {
+		s.armPause(id, time.Unix(entry.PauseAt, 0))
+	}
+}
+
+// configFromEntry rebuilds a fresh download config for a row that never
+// started, e.g. a scheduled or queued download restored after a restart.
+func (s *LocalDownloadService) configFromEntry(entry *types.DownloadEntry) types.DownloadConfig {
+	s.settingsMu.RLock()
+	settings := s.settings
+	s.settingsMu.RUnlock()
+
+	// Without a filename the saved path is the output directory itself.
+	outputPath := entry.DestPath
+	if entry.Filename != "" {
+		outputPath = filepath.Dir(entry.DestPath)
+	}
+
+	checksum, err := types.ParseChecksum(entry.Checksum)
+	if err != nil {
+		utils.Debug("Ignoring invalid stored checksum for %s: %v", entry.ID, err)
+	}
+
+	progress := types.NewProgressState(entry.ID, 0)
+	progress.DestPath = entry.DestPath
+
+	return types.DownloadConfig{
+		URL:        entry.URL,
+		Mirrors:    entry.Mirrors,
+		OutputPath: outputPath,
+		ID:         entry.ID,
+		Filename:   entry.Filename,
+		ProgressCh: s.InputCh,
+		State:      progress,
+		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
+		Checksum:   checksum,
+		RateLimit:  entry.RateLimit,
+		Priority:   entry.Priority,
+	}
+}
+
+// restoreRetries re-arms automatic retries that were pending at shutdown.
+// Overdue ones run right away; the attempt count carries on. Downloads
+// that were waiting for disk space are started again.
+func (s *LocalDownloadService) restoreRetries() {
+	entries, err := state.LoadRetryingDownloads()
+	if err != nil {
+		utils.Debug("Failed to load retrying downloads: %v", err)
+		return
+	}
+	for _, e := range entries {
+		s.armDeadline(s.retries, e.ID, time.Unix(e.NextRetryAt, 0), s.retryDeadline)
+	}
+
+	// Downloads that were waiting for disk space look again now; one that
+	// still does not fit goes back to waiting.
+	waiting, err := state.LoadDiskFullDownloads()
+	if err != nil {
+		utils.Debug("Failed to load downloads waiting for disk space: %v", err)
+		return
+	}
+	for _, e := range waiting {
+		if err := s.Resume(e.ID); err != nil {
+			utils.Debug("Failed to resume %s: %v", e.ID, err)
+		}
+	}
+}
+
+// retryDeadline resumes a restored download whose retry time has come.
+func (s *LocalDownloadService) retryDeadline(id string) {
+	s.clearDeadline(s.retries, id)
+	if err := s.Resume(id); err != nil {
+		utils.Debug("Failed to retry download %s: %v", id, err)
+	}
+}
+
+// armPause pauses the download at at. A deadline that has already passed is
+// dropped instead, so an explicit resume is not undone immediately.
+func (s *LocalDownloadService) armPause(id string, at time.Time) {
+	if !at.After(time.Now()) {
+		s.clearDeadline(s.pauses, id)
+		if err := state.UpdateSchedule(id, 0, 0); err != nil {
+			utils.Debug("Failed to clear pause time of %s: %v", id, err)
+		}
+		return
+	}
+	s.armDeadline(s.pauses, id, at, s.pauseDeadline)
+}
+
+// pauseDeadline pauses a download whose pause-at time has come.
+func (s *LocalDownloadService) pauseDeadline(id string) {
+	if !s.Pool.Pause(id) {
+		// Still waiting for a slot: pause it as soon as it starts.
+		if st := s.Pool.GetStatus(id); st != nil && st.Status == "queued" {
+			s.armDeadline(s.pauses, id, time.Now().Add(time.Second), s.pauseDeadline)
+			return
+		}
+	}
+
+	utils.Debug("Pause deadline reached for %s", id)
+	s.clearDeadline(s.pauses, id)
+	if err := state.UpdateSchedule(id, 0, 0); err != nil {
+		utils.Debug("Failed to clear pause time of %s: %v", id, err)
+	}
+}
+
+// armDeadline (re)sets the timer for id in m. fire runs on its own goroutine
+// and is skipped once the service has shut down.
+func (s *LocalDownloadService) armDeadline(m map[string]deadline, id string, at time.Time, fire func(id string)) {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+
+	if d, ok := m[id]; ok {
+		d.timer.Stop()
+	}
+	m[id] = deadline{
+		at: at,
+		timer: time.AfterFunc(time.Until(at), func() {
+			if s.ctx.Err() == nil {
+				fire(id)
+			}
+		}),
+	}
+}
+
+func (s *LocalDownloadService) clearDeadline(m map[string]deadline, id string) {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+
+	if d, ok := m[id]; ok {
+		d.timer.Stop()
+		delete(m, id)
+	}
+}
+
+// forgetDeadlines drops every pending time and config for a removed download.
+func (s *LocalDownloadService) forgetDeadlines(id string) {
+	s.clearDeadline(s.starts, id)
+	s.clearDeadline(s.pauses, id)
+	s.clearDeadline(s.retries, id)
+	s.deadlineMu.Lock()
+	delete(s.pending, id)
+	s.deadlineMu.Unlock()
+}
+
+// pauseTime returns the pending pause-at time of an active download.
+func (s *LocalDownloadService) pauseTime(id string) int64 {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+	if d, ok := s.pauses[id]; ok {
+		return d.at.Unix()
+	}
+	return 0
+}
+
+// stopDeadlines cancels all timers on shutdown. Persisted times are kept.
+func (s *LocalDownloadService) stopDeadlines() {
+	s.deadlineMu.Lock()
+	defer s.deadlineMu.Unlock()
+	for _, m := range []map[string]deadline{s.starts, s.pauses, s.retries} {
+		for _, d := range m {
+			d.timer.Stop()
+		}
+	}
+}
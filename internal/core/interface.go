@@ -3,6 +3,7 @@ package core
 import (
 	"concurrent_downloader/internal/download/types"
 	"context"
+	"io"
 )
 
 // DownloadService defines the interface for interacting with the download engine.
@@ -27,6 +28,31 @@ type DownloadService interface {
 	// ResumeBatch resumes multiple paused downloads efficiently.
 	ResumeBatch(ids []string) []error
 
+	// RefreshURL gives a download that is not running a new URL for the same
+	// file, keeping its progress. One waiting for a new URL resumes with it.
+	RefreshURL(id string, newURL string) error
+
+	// SetRateLimit changes a queued or running download's speed cap in
+	// bytes/sec (0 = unlimited), or the global cap when id is empty.
+	SetRateLimit(id string, bytesPerSec int64) error
+
+	// MoveInQueue moves a queued download to a 1-based queue position;
+	// 0 moves it to the bottom.
+	MoveInQueue(id string, position int) error
+
+	// SwapInQueue exchanges the queue positions of two queued downloads.
+	SwapInQueue(a, b string) error
+
+	// SetMaxConcurrentDownloads changes how many downloads run at once.
+	// Running downloads are never stopped to meet a lower limit.
+	SetMaxConcurrentDownloads(n int) error
+
+	// ReloadSettings re-reads the settings file and applies it.
+	ReloadSettings() error
+
+	// Schedule returns the bandwidth profile and limits currently in effect.
+	Schedule() types.ScheduleStatus
+
 	// Delete cancels and removes a download.
 	Delete(id string) error
 
@@ -42,6 +68,10 @@ type DownloadService interface {
 	// GetStatus returns a status for a single download by id.
 	GetStatus(id string) (*types.DownloadStatus, error)
 
+	// Open returns a reader of a download's file that blocks until the bytes
+	// at its offset are on disk, so it can be read while downloading.
+	Open(id string) (io.ReadSeekCloser, error)
+
 	// Shutdown handles graceful shutdown of the service.
 	Shutdown() error
 }
@@ -9,6 +9,7 @@ import (
 	"concurrent_downloader/internal/utils"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -39,12 +40,122 @@ func (s *LocalDownloadService) ReloadSettings() error {
 	if err != nil {
 		return err
 	}
+	s.ApplySettings(settings)
+	return nil
+}
+
+// ApplySettings replaces the cached settings and applies their speed limit,
+// concurrency limit and bandwidth schedule to the running pool.
+func (s *LocalDownloadService) ApplySettings(settings *config.Settings) {
+	if settings == nil {
+		return
+	}
+	if err := settings.Schedule.Validate(); err != nil {
+		utils.Debug("Schedule: %v", err)
+	}
 	s.settingsMu.Lock()
 	s.settings = settings
 	s.settingsMu.Unlock()
+	if n := settings.MaxConcurrentDownloads(); n > 0 {
+		s.scheduleMu.Lock()
+		s.defaultMaxDownloads = n
+		s.scheduleMu.Unlock()
+	}
+	s.applySchedule(time.Now(), true)
+}
+
+// SetMaxConcurrentDownloads changes how many downloads run at once without
+// a restart. Lowering it lets running downloads finish rather than stopping
+// them. Like `limit --global`, a bandwidth profile with its own limit
+// replaces the value at the next switch.
+func (s *LocalDownloadService) SetMaxConcurrentDownloads(n int) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if n < 1 {
+		return fmt.Errorf("max concurrent downloads must be at least 1")
+	}
+
+	s.scheduleMu.Lock()
+	defer s.scheduleMu.Unlock()
+	s.defaultMaxDownloads = n
+	s.schedule.MaxConcurrentDownloads = n
+	s.Pool.SetMaxDownloads(n)
 	return nil
 }
 
+// applySchedule switches to the profile active at now. Limits are only
+// reapplied when the profile changes (or force is set), so a manual
+// `limit --global` lasts until the next switch.
+func (s *LocalDownloadService) applySchedule(now time.Time, force bool) {
+	if s.Pool == nil {
+		return
+	}
+
+	s.settingsMu.RLock()
+	settings := s.settings
+	s.settingsMu.RUnlock()
+
+	s.scheduleMu.Lock()
+	defer s.scheduleMu.Unlock()
+
+	next := types.ScheduleStatus{
+		Enabled:                settings.Schedule.Enabled,
+		RateLimit:              settings.Network.GlobalRateLimit,
+		MaxConcurrentDownloads: s.defaultMaxDownloads,
+	}
+	if p := settings.Schedule.Active(now); p != nil {
+		next.Profile = p.Name
+		next.RateLimit = p.RateLimit
+		if p.MaxConcurrentDownloads > 0 {
+			next.MaxConcurrentDownloads = p.MaxConcurrentDownloads
+		}
+	}
+
+	prev := s.schedule
+	if !force && next.Profile == prev.Profile {
+		return
+	}
+	s.schedule = next
+	s.Pool.SetRateLimit("", next.RateLimit)
+	s.Pool.SetMaxDownloads(next.MaxConcurrentDownloads)
+
+	if next.Profile == prev.Profile {
+		return
+	}
+	utils.Debug("Schedule: switching profile %q -> %q", prev.Profile, next.Profile)
+	select {
+	case s.InputCh <- events.ScheduleProfileChangedMsg{
+		Profile:                next.Profile,
+		Previous:               prev.Profile,
+		RateLimit:              next.RateLimit,
+		MaxConcurrentDownloads: next.MaxConcurrentDownloads,
+	}:
+	case <-s.ctx.Done():
+	}
+}
+
+// scheduleLoop re-evaluates the bandwidth schedule until shutdown.
+func (s *LocalDownloadService) scheduleLoop() {
+	ticker := time.NewTicker(ScheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.applySchedule(now, false)
+		}
+	}
+}
+
+// Schedule returns the bandwidth profile and limits currently in effect.
+func (s *LocalDownloadService) Schedule() types.ScheduleStatus {
+	s.scheduleMu.Lock()
+	defer s.scheduleMu.Unlock()
+	return s.schedule
+}
+
 // LocalDownloadService implements DownloadService for the local embedded engine
 // and owns the in-process event fanout for UI clients.
 type LocalDownloadService struct {
@@ -67,11 +178,34 @@ type LocalDownloadService struct {
 	// Settings Cache
 	settings   *config.Settings
 	settingsMu sync.RWMutex
+
+	// Bandwidth schedule. scheduleMu also guards sends of schedule events
+	// against Shutdown closing InputCh.
+	defaultMaxDownloads int
+	schedule            types.ScheduleStatus
+	scheduleMu          sync.Mutex
+
+	// Start-at, pause-at and restored retry timers by download ID, and the
+	// configs of scheduled downloads added since startup.
+	deadlineMu sync.Mutex
+	starts     map[string]deadline
+	pauses     map[string]deadline
+	retries    map[string]deadline
+	pending    map[string]types.DownloadConfig
+
+	// Asked for a new URL when a download's URL expires (nil = wait for RefreshURL).
+	refresher   URLRefresher
+	refresherMu sync.RWMutex
 }
 
+// URLRefresher returns a new URL for a download whose URL stopped working,
+// e.g. by presigning the object again. An error leaves the download waiting.
+type URLRefresher func(ctx context.Context, id string, expiredURL string) (string, error)
+
 const (
 	SpeedSmoothingAlpha = 0.3
 	ReportInterval      = 150 * time.Millisecond
+	ScheduleInterval    = 15 * time.Second
 )
 
 // NewLocalDownloadService creates a new specific service instance.
@@ -87,11 +221,10 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 		Pool:      pool,
 		InputCh:   inputCh,
 		listeners: make([]chan interface{}, 0),
-	}
-
-	// Load initial settings
-	if s.settings, _ = config.LoadSettings(); s.settings == nil {
-		s.settings = config.DefaultSettings()
+		starts:    make(map[string]deadline),
+		pauses:    make(map[string]deadline),
+		retries:   make(map[string]deadline),
+		pending:   make(map[string]types.DownloadConfig),
 	}
 
 	// Lifecycle
@@ -99,6 +232,17 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 	s.ctx = ctx
 	s.cancel = cancel
 
+	// Load initial settings
+	settings, _ := config.LoadSettings()
+	if settings == nil {
+		settings = config.DefaultSettings()
+	}
+	if pool != nil {
+		// Schedule profiles fall back to the limit the pool was created with.
+		s.defaultMaxDownloads = pool.MaxDownloads()
+	}
+	s.ApplySettings(settings)
+
 	// Start broadcaster to fan out events to any number of UI listeners.
 	go s.broadcastLoop()
 
@@ -106,6 +250,9 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 	if pool != nil {
 		s.reportTicker = time.NewTicker(ReportInterval)
 		go s.reportProgressLoop()
+		go s.scheduleLoop()
+		s.restoreScheduled()
+		s.restoreRetries()
 	}
 
 	return s
@@ -113,6 +260,10 @@ func NewLocalDownloadServiceWithInput(pool *download.WorkerPool, inputCh chan in
 
 func (s *LocalDownloadService) broadcastLoop() {
 	for msg := range s.InputCh {
+		if m, ok := msg.(events.DownloadNeedsURLMsg); ok {
+			s.requestURL(m)
+		}
+
 		s.listenerMu.Lock()
 		for _, ch := range s.listeners {
 			// Check message type
@@ -306,6 +457,7 @@ func (s *LocalDownloadService) Shutdown() error {
 		if s.reportTicker != nil {
 			s.reportTicker.Stop()
 		}
+		s.stopDeadlines()
 		if s.Pool != nil {
 			s.Pool.GracefulShutdown()
 		}
@@ -315,7 +467,9 @@ func (s *LocalDownloadService) Shutdown() error {
 
 		// Close input channel to stop broadcaster
 		if s.InputCh != nil {
+			s.scheduleMu.Lock()
 			close(s.InputCh)
+			s.scheduleMu.Unlock()
 		}
 	})
 	return s.shutdownErr
@@ -352,6 +506,10 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 
 				// Get active connections count
 				status.Connections = int(connections)
+				status.RateLimit = cfg.Bandwidth.Rate()
+				status.PauseAt = s.pauseTime(cfg.ID)
+				status.Priority = cfg.Priority
+				status.HostConnections = cfg.Hosts.Usage(append([]string{cfg.URL}, cfg.Mirrors...)...)
 
 				// Update status based on state
 				if cfg.State.IsPausing() {
@@ -361,6 +519,20 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 				} else if cfg.State.Done.Load() {
 					status.Status = "completed"
 				}
+				if pos := s.Pool.QueuePosition(cfg.ID); pos > 0 {
+					status.Status = "queued"
+					status.QueuePos = pos
+				}
+				status.RetryCount = cfg.RetryAttempt
+				if attempt, at, err := s.Pool.Retrying(cfg.ID); attempt > 0 {
+					status.Status = "retrying"
+					status.NextRetryAt = at.Unix()
+					status.SetError(err)
+				}
+				if err := s.Pool.WaitingForSpace(cfg.ID); err != nil {
+					status.Status = "disk_full"
+					status.SetError(err)
+				}
 
 				// Calculate speed from progress only while actively downloading.
 				if status.Status == "downloading" {
@@ -404,7 +576,7 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 				progress = 100.0
 			}
 
-			statuses = append(statuses, types.DownloadStatus{
+			status := types.DownloadStatus{
 				ID:          d.ID,
 				URL:         d.URL,
 				Filename:    d.Filename,
@@ -417,7 +589,11 @@ func (s *LocalDownloadService) List() ([]types.DownloadStatus, error) {
 				Connections: 0,
 				TimeTaken:   d.TimeTaken,
 				AvgSpeed:    d.AvgSpeed,
-			})
+				StartAt:     d.StartAt,
+				PauseAt:     d.PauseAt,
+			}
+			status.SetEntryError(d)
+			statuses = append(statuses, status)
 		}
 	}
 
@@ -448,10 +624,15 @@ func (s *LocalDownloadService) Add(url string, path string, filename string, mir
 	id := uuid.New().String()
 
 	// Create configuration
-	state := types.NewProgressState(id, 0)
-	state.DestPath = filepath.Join(outPath, filename) // Best guess until download starts
+	progress := types.NewProgressState(id, 0)
+	progress.DestPath = filepath.Join(outPath, filename) // Best guess until download starts
 
 	runtimeCfg := types.ConvertRuntimeConfig(settings.ToRuntimeConfig())
+	var checksum *types.Checksum
+	var pieces *types.PieceHashes
+	var size, rateLimit int64
+	var startAt, pauseAt time.Time
+	var priority int
 	if opts != nil {
 		if opts.ForceSingle {
 			runtimeCfg.ForceSingle = true
@@ -459,6 +640,18 @@ func (s *LocalDownloadService) Add(url string, path string, filename string, mir
 		if opts.ChunkCount > 0 {
 			runtimeCfg.RequestedConnections = opts.ChunkCount
 		}
+		checksum = opts.Checksum
+		pieces = opts.Pieces
+		size = opts.Size
+		rateLimit = opts.RateLimit
+		startAt, pauseAt = opts.StartAt, opts.PauseAt
+		priority = opts.Priority
+		if opts.TempDir != "" {
+			runtimeCfg.TempDir = opts.TempDir
+		}
+	}
+	if err := types.ValidateSchedule(startAt, pauseAt, time.Now()); err != nil {
+		return "", err
 	}
 
 	cfg := types.DownloadConfig{
@@ -468,16 +661,97 @@ func (s *LocalDownloadService) Add(url string, path string, filename string, mir
 		ID:         id,
 		Filename:   filename, // If empty, will be auto-detected
 		ProgressCh: s.InputCh,
-		State:      state,
+		State:      progress,
 		Runtime:    runtimeCfg,
 		Headers:    headers,
+		Checksum:   checksum,
+		Pieces:     pieces,
+		Size:       size,
+		RateLimit:  rateLimit,
+		Priority:   priority,
+	}
+
+	if startAt.After(time.Now()) {
+		if err := s.scheduleDownload(cfg, startAt, pauseAt); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+
+	// Persist the queued download so its place in the queue and any pause
+	// time survive a restart.
+	entry := types.DownloadEntry{
+		ID:        id,
+		URL:       url,
+		URLHash:   state.URLHash(url),
+		DestPath:  progress.DestPath,
+		Filename:  filename,
+		Status:    "queued",
+		Mirrors:   mirrors,
+		Checksum:  checksum.String(),
+		Priority:  priority,
+		RateLimit: rateLimit,
+	}
+	if !pauseAt.IsZero() {
+		entry.PauseAt = pauseAt.Unix()
+	}
+	if err := state.AddToMasterList(entry); err != nil {
+		utils.Debug("Failed to persist queued download %s: %v", id, err)
 	}
 
 	s.Pool.Add(cfg)
+	if !pauseAt.IsZero() {
+		s.armPause(id, pauseAt)
+	}
 
 	return id, nil
 }
 
+// SetRateLimit changes a download's speed cap, or the global one when id is empty.
+func (s *LocalDownloadService) SetRateLimit(id string, bytesPerSec int64) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if bytesPerSec < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	if !s.Pool.SetRateLimit(id, bytesPerSec) {
+		return fmt.Errorf("download not active")
+	}
+	if id != "" {
+		if err := state.UpdateRateLimit(id, bytesPerSec); err != nil {
+			utils.Debug("Failed to persist rate limit for %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// MoveInQueue moves a queued download to a 1-based queue position; 0 moves
+// it to the bottom.
+func (s *LocalDownloadService) MoveInQueue(id string, position int) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if position < 0 {
+		return fmt.Errorf("queue position must not be negative")
+	}
+	if !s.Pool.MoveQueued(id, position) {
+		return fmt.Errorf("download not queued")
+	}
+	return nil
+}
+
+// SwapInQueue exchanges the queue positions of two queued downloads.
+func (s *LocalDownloadService) SwapInQueue(a, b string) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if !s.Pool.SwapQueued(a, b) {
+		return fmt.Errorf("both downloads must be queued")
+	}
+	return nil
+}
+
 // Pause pauses an active download.
 func (s *LocalDownloadService) Pause(id string) error {
 	if s.Pool == nil {
@@ -491,6 +765,15 @@ func (s *LocalDownloadService) Pause(id string) error {
 	// If not in pool, check if it's already paused/stopped in DB
 	entry, err := state.GetDownload(id)
 	if err == nil && entry != nil {
+		if entry.Status == "scheduled" {
+			return fmt.Errorf("download is scheduled to start at %s; remove it to cancel", time.Unix(entry.StartAt, 0).Format("2006-01-02 15:04"))
+		}
+		if entry.Status == "retrying" {
+			s.clearDeadline(s.retries, id)
+			if err := state.UpdateStatus(id, "paused"); err != nil {
+				utils.Debug("Failed to mark %s paused: %v", id, err)
+			}
+		}
 		// Emit paused event so UI clears "pausing" state
 		if s.InputCh != nil {
 			s.InputCh <- events.DownloadPausedMsg{
@@ -529,6 +812,37 @@ func (s *LocalDownloadService) Resume(id string) error {
 	if entry.Status == "completed" {
 		return fmt.Errorf("download already completed")
 	}
+	if entry.Status == "scheduled" {
+		// Resuming a scheduled download starts it now.
+		s.startScheduled(id)
+		return nil
+	}
+	// A pending automatic retry keeps counting its attempts.
+	var retryAttempt int
+	if entry.Status == "retrying" {
+		s.clearDeadline(s.retries, id)
+		retryAttempt = entry.RetryCount
+		if err := state.UpdateStatus(id, "queued"); err != nil {
+			utils.Debug("Failed to mark %s queued: %v", id, err)
+		}
+		entry.Status = "queued"
+	}
+	if entry.Downloaded == 0 {
+		// Nothing was transferred (e.g. still queued at shutdown): start afresh
+		// with the saved destination rather than resuming.
+		if entry.Status != "queued" {
+			if err := state.UpdateStatus(id, "queued"); err != nil {
+				utils.Debug("Failed to mark %s queued: %v", id, err)
+			}
+		}
+		cfg := s.configFromEntry(entry)
+		cfg.RetryAttempt = retryAttempt
+		s.Pool.Add(cfg)
+		if entry.PauseAt > 0 {
+			s.armPause(id, time.Unix(entry.PauseAt, 0))
+		}
+		return nil
+	}
 
 	s.settingsMu.RLock()
 	settings := s.settings
@@ -545,6 +859,8 @@ func (s *LocalDownloadService) Resume(id string) error {
 
 	var mirrorURLs []string
 	var dmState *types.ProgressState
+	var pieces *types.PieceHashes
+	checksumStr := entry.Checksum
 
 	if stateErr == nil && savedState != nil {
 		dmState = types.NewProgressState(id, savedState.TotalSize)
@@ -563,6 +879,10 @@ func (s *LocalDownloadService) Resume(id string) error {
 		}
 		dmState.DestPath = entry.DestPath
 		dmState.SyncSessionStart()
+		if savedState.Checksum != "" {
+			checksumStr = savedState.Checksum
+		}
+		pieces = savedState.Pieces
 	} else {
 		dmState = types.NewProgressState(id, entry.TotalSize)
 		dmState.Downloaded.Store(entry.Downloaded)
@@ -572,6 +892,12 @@ func (s *LocalDownloadService) Resume(id string) error {
 		mirrorURLs = []string{entry.URL}
 	}
 
+	// A stored checksum was validated on add; a parse failure here means the row was edited.
+	checksum, err := types.ParseChecksum(checksumStr)
+	if err != nil {
+		utils.Debug("Ignoring invalid stored checksum for %s: %v", id, err)
+	}
+
 	cfg := types.DownloadConfig{
 		URL:        entry.URL,
 		OutputPath: outputPath,
@@ -584,9 +910,18 @@ func (s *LocalDownloadService) Resume(id string) error {
 		SavedState: savedState, // Pass loaded state to avoid re-query
 		Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 		Mirrors:    mirrorURLs,
+		Checksum:   checksum,
+		Pieces:     pieces,
+		RateLimit:  entry.RateLimit,
+		Priority:   entry.Priority,
+
+		RetryAttempt: retryAttempt,
 	}
 
 	s.Pool.Add(cfg)
+	if entry.PauseAt > 0 {
+		s.armPause(id, time.Unix(entry.PauseAt, 0))
+	}
 	if s.InputCh != nil {
 		s.InputCh <- events.DownloadResumedMsg{
 			DownloadID: id,
@@ -596,6 +931,89 @@ func (s *LocalDownloadService) Resume(id string) error {
 	return nil
 }
 
+// RefreshURL replaces the URL of a download that is not running, e.g. an
+// expired presigned link. newURL must serve the same file (size, ETag and
+// Last-Modified), so the partial and its chunk map are kept. A download that
+// stopped for a new URL resumes with it.
+func (s *LocalDownloadService) RefreshURL(id string, newURL string) error {
+	if s.Pool == nil {
+		return fmt.Errorf("worker pool not initialized")
+	}
+	if newURL == "" {
+		return fmt.Errorf("missing url")
+	}
+
+	entry, err := state.GetDownload(id)
+	if err != nil || entry == nil {
+		return fmt.Errorf("download not found")
+	}
+	if entry.Status == "completed" {
+		return fmt.Errorf("download already completed")
+	}
+	if st := s.Pool.GetStatus(id); st != nil && (st.Status == "downloading" || st.Status == "pausing") {
+		return fmt.Errorf("download is running; pause it before changing its URL")
+	}
+
+	if err := download.VerifyReplacementURL(s.ctx, entry, newURL, s.Pool.Headers(id)); err != nil {
+		return err
+	}
+	if _, err := s.Pool.ReplaceURL(id, newURL); err != nil {
+		return err
+	}
+
+	var mirrors []string
+	for _, m := range entry.Mirrors {
+		if m != entry.URL && m != newURL {
+			mirrors = append(mirrors, m)
+		}
+	}
+	if err := state.UpdateURL(id, newURL, mirrors); err != nil {
+		return err
+	}
+	utils.Debug("Refreshed URL of %s", id)
+
+	if s.InputCh != nil {
+		s.InputCh <- events.DownloadURLRefreshedMsg{
+			DownloadID: id,
+			Filename:   entry.Filename,
+			URL:        newURL,
+		}
+	}
+	if entry.Status == "needs_url" {
+		return s.Resume(id)
+	}
+	return nil
+}
+
+// SetURLRefresher installs fn to be asked for a new URL whenever a download's
+// URL stops working. nil removes it.
+func (s *LocalDownloadService) SetURLRefresher(fn URLRefresher) {
+	s.refresherMu.Lock()
+	defer s.refresherMu.Unlock()
+	s.refresher = fn
+}
+
+// requestURL asks the URL refresher, if any, for a replacement URL.
+func (s *LocalDownloadService) requestURL(m events.DownloadNeedsURLMsg) {
+	s.refresherMu.RLock()
+	fn := s.refresher
+	s.refresherMu.RUnlock()
+	if fn == nil {
+		return
+	}
+
+	go func() {
+		newURL, err := fn(s.ctx, m.DownloadID, m.URL)
+		if err != nil {
+			utils.Debug("URL refresher failed for %s: %v", m.DownloadID, err)
+			return
+		}
+		if err := s.RefreshURL(m.DownloadID, newURL); err != nil {
+			utils.Debug("Refreshed URL rejected for %s: %v", m.DownloadID, err)
+		}
+	}()
+}
+
 // ResumeBatch resumes multiple paused downloads efficiently.
 func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 	errs := make([]error, len(ids))
@@ -610,6 +1028,7 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 	// 1. Try pool resume first for all to avoid extra state IO.
 	toLoad := []string{}
 	idMap := make(map[string]int)
+	entries := make(map[string]*types.DownloadEntry)
 
 	for i, id := range ids {
 		if st := s.Pool.GetStatus(id); st != nil && st.Status == "pausing" {
@@ -619,10 +1038,19 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 
 		if s.Pool.Resume(id) {
 			errs[i] = nil // Success
-		} else {
-			// Need cold resume
-			toLoad = append(toLoad, id)
-			idMap[id] = i
+			continue
+		}
+		entry, err := state.GetDownload(id)
+		if err == nil && entry != nil && (entry.Status == "scheduled" || entry.Downloaded == 0) {
+			// Downloads that never started are queued afresh, as in Resume.
+			errs[i] = s.Resume(id)
+			continue
+		}
+		// Need cold resume; the entry keeps the priority and pause time.
+		toLoad = append(toLoad, id)
+		idMap[id] = i
+		if entry != nil {
+			entries[id] = entry
 		}
 	}
 
@@ -682,6 +1110,11 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 		dmState.DestPath = savedState.DestPath
 		dmState.SyncSessionStart()
 
+		checksum, err := types.ParseChecksum(savedState.Checksum)
+		if err != nil {
+			utils.Debug("Ignoring invalid stored checksum for %s: %v", id, err)
+		}
+
 		cfg := types.DownloadConfig{
 			URL:        savedState.URL,
 			OutputPath: outputPath,
@@ -694,9 +1127,19 @@ func (s *LocalDownloadService) ResumeBatch(ids []string) []error {
 			SavedState: savedState, // Pass loaded state to avoid re-query
 			Runtime:    types.ConvertRuntimeConfig(settings.ToRuntimeConfig()),
 			Mirrors:    mirrorURLs,
+			Checksum:   checksum,
+			Pieces:     savedState.Pieces,
+			RateLimit:  savedState.RateLimit,
+		}
+		entry := entries[id]
+		if entry != nil {
+			cfg.Priority = entry.Priority
 		}
 
 		s.Pool.Add(cfg)
+		if entry != nil && entry.PauseAt > 0 {
+			s.armPause(id, time.Unix(entry.PauseAt, 0))
+		}
 		errs[idx] = nil
 	}
 
@@ -711,6 +1154,7 @@ func (s *LocalDownloadService) Delete(id string) error {
 
 	removedFilename := ""
 
+	s.forgetDeadlines(id)
 	s.Pool.Cancel(id)
 
 	// Cleanup persisted state and partials if available
@@ -718,7 +1162,7 @@ func (s *LocalDownloadService) Delete(id string) error {
 		removedFilename = entry.Filename
 		_ = state.DeleteState(entry.ID, entry.URL, entry.DestPath)
 		if entry.DestPath != "" && entry.Status != "completed" {
-			_ = os.Remove(entry.DestPath + types.IncompleteSuffix)
+			_ = os.Remove(entry.WorkingPath)
 		}
 	}
 
@@ -772,13 +1216,28 @@ func (s *LocalDownloadService) GetStatus(id string) (*types.DownloadStatus, erro
 			Status:     entry.Status,
 			TimeTaken:  entry.TimeTaken,
 			AvgSpeed:   entry.AvgSpeed,
+			StartAt:    entry.StartAt,
+			PauseAt:    entry.PauseAt,
+			Priority:   entry.Priority,
 		}
+		status.SetEntryError(*entry)
 		return &status, nil
 	}
 
 	return nil, fmt.Errorf("download not found")
 }
 
+// Open returns a reader that follows a download's file as it is written.
+func (s *LocalDownloadService) Open(id string) (io.ReadSeekCloser, error) {
+	if id == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+	if s.Pool == nil {
+		return nil, fmt.Errorf("worker pool not initialized")
+	}
+	return s.Pool.Open(id)
+}
+
 // History returns completed downloads
 func (s *LocalDownloadService) History() ([]types.DownloadEntry, error) {
 	// For local service, we can directly access the state DB
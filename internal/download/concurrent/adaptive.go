@@ -0,0 +1,135 @@
+package concurrent
+
+import (
+	"sync"
+	"time"
+
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// connController tunes how many connections a download asks for, based on
+// measured throughput. Every ScaleInterval it compares the download speed with
+// the previous sample: while the last added connection raised it by at least
+// ScaleMinGain, one more is added, up to the ceiling. A step that brings no
+// gain is undone and the count holds for a while before probing again. A 429
+// or 503 from the server drops a share of the connections and lowers the
+// ceiling, so the server is not pushed that far again.
+//
+// The controller only changes the lease's want; the allocator still decides
+// the grant, and workers follow the grant through the workerScaler.
+type connController struct {
+	lease *connlimit.Lease
+
+	mu        sync.Mutex
+	want      int
+	ceiling   int
+	lastRate  float64 // Bytes/sec at the previous sample
+	grew      bool    // The previous step added a connection
+	hold      int     // Samples to skip before probing again
+	throttled bool    // The server returned 429/503 since the last sample
+}
+
+func newConnController(lease *connlimit.Lease, initial, ceiling int) *connController {
+	if ceiling < initial {
+		ceiling = initial
+	}
+	// Let the first connections get up to speed before judging them.
+	return &connController{lease: lease, want: initial, ceiling: ceiling, hold: 1}
+}
+
+// Throttled records that the server pushed back with 429 or 503.
+func (c *connController) Throttled() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.throttled = true
+	c.mu.Unlock()
+}
+
+// step takes one throughput sample and returns the new want, or 0 when it is
+// unchanged. running is the number of workers currently started; limited is
+// set while a speed limit, not the server, caps the rate.
+func (c *connController) step(rate float64, running int, limited bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prevRate := c.lastRate
+	c.lastRate = rate
+	want := c.want
+
+	switch {
+	case c.throttled:
+		c.throttled = false
+		want -= max(1, want/types.ScaleThrottleStep)
+		want = max(1, want)
+		c.ceiling = want
+		c.grew = false
+		c.hold = types.ScaleHoldSamples
+		utils.Debug("Scaling: server throttled, backing off to %d connections", want)
+	case running != c.want:
+		// Workers are still following the last change (or the budget holds
+		// them back), so this sample says nothing about the current count.
+		return 0
+	case limited:
+		c.grew = false
+		return 0
+	case c.grew && rate < prevRate*(1+types.ScaleMinGain):
+		// No real gain from the last connection: give it back.
+		want--
+		c.grew = false
+		c.hold = types.ScaleHoldSamples
+		utils.Debug("Scaling: throughput plateaued at %s/s, retiring to %d connections",
+			utils.ConvertBytesToHumanReadable(int64(prevRate)), want)
+	case c.hold > 0:
+		c.hold--
+		c.grew = false
+		return 0
+	case c.want < c.ceiling:
+		want++
+		c.grew = true
+		utils.Debug("Scaling: %s/s with %d connections, trying %d",
+			utils.ConvertBytesToHumanReadable(int64(rate)), c.want, want)
+	default:
+		c.grew = false
+		return 0
+	}
+
+	if want == c.want {
+		return 0
+	}
+	c.want = want
+	c.lease.SetWant(want)
+	return want
+}
+
+// connectionCeiling bounds adaptive scaling by the per-host limit and by how
+// many minimum-size chunks the file can be split into.
+func (d *ConcurrentDownloader) connectionCeiling(fileSize int64) int {
+	ceiling := d.Runtime.GetMaxConnectionsPerHost()
+	if minChunk := d.Runtime.GetMinChunkSize(); minChunk > 0 {
+		ceiling = min(ceiling, int(max(1, fileSize/minChunk)))
+	}
+	return ceiling
+}
+
+// adjustConnections samples the download speed since the last call and lets
+// the controller add or retire a connection.
+func (d *ConcurrentDownloader) adjustConnections(lastBytes *int64, lastTime *time.Time) {
+	now := time.Now()
+	downloaded := d.State.Downloaded.Load()
+	elapsed := now.Sub(*lastTime).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := float64(downloaded-*lastBytes) / elapsed
+	*lastBytes, *lastTime = downloaded, now
+
+	if d.scaling.step(rate, d.workers.Running(), d.Bandwidth.Limited()) > 0 {
+		// Start workers for a raised grant right away; idle ones get work
+		// from the balancer. Lowered grants retire workers between tasks.
+		d.workers.Scale()
+	}
+}
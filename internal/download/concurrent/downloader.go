@@ -1,24 +1,26 @@
 package concurrent
 
 import (
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/diskspace"
+	"concurrent_downloader/internal/download/httpclient"
+	"concurrent_downloader/internal/download/integrity"
+	"concurrent_downloader/internal/download/ratelimit"
+	"concurrent_downloader/internal/download/stream"
 	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/download/writeback"
+	"concurrent_downloader/internal/events"
 	"concurrent_downloader/internal/state"
 	"concurrent_downloader/internal/utils"
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
-	"net"
-	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
-
-	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/http3"
 )
 
 // ConcurrentDownloader handles multi-connection downloads and owns the
@@ -33,28 +35,20 @@ type ConcurrentDownloader struct {
 	DestPath     string // For pause/resume
 	Runtime      *types.RuntimeConfig
 	bufPool      sync.Pool
-	Headers      map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
-}
-
-type protocolClient struct {
-	name   string
-	client *http.Client
-}
-
-type clientSet struct {
-	primary        protocolClient
-	fallbacks      []protocolClient
-	http3Transport *http3.Transport
-}
-
-func (c *clientSet) Close() {
-	if c == nil || c.http3Transport == nil {
-		return
-	}
-
-	if err := c.http3Transport.Close(); err != nil {
-		utils.Debug("Error closing HTTP/3 transport: %v", err)
-	}
+	Headers      map[string]string    // Custom HTTP headers from browser (cookies, auth, etc.)
+	Checksum     *types.Checksum      // Expected digest, verified before the final rename
+	Pieces       *types.PieceHashes   // Per-piece digests, verified as pieces complete
+	Connections  *connlimit.Allocator // Connection budget shared with other downloads (nil = unlimited)
+	Hosts        *connlimit.Hosts     // Per-origin connection limit shared with other downloads (nil = unlimited)
+	Bandwidth    *ratelimit.Limiter   // Speed cap for this download (nil = unlimited)
+	ETag         string               // Validators from the probe, sent in If-Range to the primary URL
+	LastModified string
+	WorkingPath  string         // Partial file, moved to the destination on completion (default: destPath + IncompleteSuffix)
+	Stream       *stream.Window // Hand the bytes to a reader in order instead of writing a file
+	hasher       *integrity.PrefixHasher
+	pieces       *integrity.PieceVerifier
+	workers      *workerScaler
+	scaling      *connController // nil when the connection count is fixed
 }
 
 // NewConcurrentDownloader creates a new concurrent downloader with all required parameters.
@@ -219,164 +213,75 @@ func createTasks(fileSize, chunkSize int64) []types.Task {
 	return tasks
 }
 
-// newConcurrentClient creates an http.Client tuned for concurrent downloads.
-func (d *ConcurrentDownloader) newConcurrentClients(numConns int, supportsHTTP2 bool, supportsHTTP3 bool) *clientSet {
-	// Ensure we have enough connections per host
-	maxConns := d.Runtime.GetMaxConnectionsPerHost()
-	if numConns > maxConns {
-		maxConns = numConns
-	}
-
-	// Keep proxy handling explicit to avoid surprising env interactions.
-	var proxyFunc func(*http.Request) (*url.URL, error)
-	if d.Runtime.ProxyURL != "" {
-		if parsedURL, err := url.Parse(d.Runtime.ProxyURL); err == nil {
-			proxyFunc = http.ProxyURL(parsedURL)
-		} else {
-			// Fallback or log error? For now fallback to environment
-			utils.Debug("Invalid proxy URL %s: %v", d.Runtime.ProxyURL, err)
-			proxyFunc = http.ProxyFromEnvironment
-		}
-	} else {
-		proxyFunc = http.ProxyFromEnvironment
-	}
-
-	buildHTTPTransport := func(forceHTTP2 bool) *http.Transport {
-		transport := &http.Transport{
-			// Connection pooling
-			MaxIdleConns:        types.DefaultMaxIdleConns,
-			MaxIdleConnsPerHost: maxConns + 2, // Slightly more than max to handle bursts
-			MaxConnsPerHost:     maxConns,
-			Proxy:               proxyFunc,
-
-			// Timeouts to prevent hung connections
-			IdleConnTimeout:       types.DefaultIdleConnTimeout,
-			TLSHandshakeTimeout:   types.DefaultTLSHandshakeTimeout,
-			ResponseHeaderTimeout: types.DefaultResponseHeaderTimeout,
-			ExpectContinueTimeout: types.DefaultExpectContinueTimeout,
+// completedRanges returns the parts of [0, fileSize) not covered by the remaining tasks.
+func completedRanges(fileSize int64, remaining []types.Task) []types.Task {
+	sorted := make([]types.Task, len(remaining))
+	copy(sorted, remaining)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
 
-			// Performance tuning
-			DisableCompression: true, // Files are usually already compressed
-			ForceAttemptHTTP2:  forceHTTP2,
-
-			// Dial settings for TCP reliability
-			DialContext: (&net.Dialer{
-				Timeout:   types.DialTimeout,
-				KeepAlive: types.KeepAliveDuration,
-			}).DialContext,
+	var done []types.Task
+	cursor := int64(0)
+	for _, task := range sorted {
+		if task.Offset > cursor {
+			done = append(done, types.Task{Offset: cursor, Length: task.Offset - cursor})
 		}
-
-		if !forceHTTP2 {
-			transport.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+		if end := task.Offset + task.Length; end > cursor {
+			cursor = end
 		}
-
-		return transport
 	}
-
-	newHTTPClient := func(transport http.RoundTripper) *http.Client {
-		return &http.Client{
-			Transport: transport,
-			// Preserve headers on redirects for authenticated downloads.
-			// These headers were explicitly provided by the caller and should remain intact.
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
-					return fmt.Errorf("stopped after 10 redirects")
-				}
-				// Copy headers from original request to redirect request
-				if len(via) > 0 {
-					for key, vals := range via[0].Header {
-						// Skip Range header - we set our own for parallel downloads
-						if key == "Range" {
-							continue
-						}
-						req.Header[key] = vals
-					}
-				}
-				return nil
-			},
-		}
+	if cursor < fileSize {
+		done = append(done, types.Task{Offset: cursor, Length: fileSize - cursor})
 	}
+	return done
+}
 
-	protocol := d.Runtime.GetProtocolPreference()
-	if d.Runtime != nil && d.Runtime.ProxyURL != "" && supportsHTTP3 {
-		utils.Debug("HTTP/3 disabled because proxy is configured")
-		supportsHTTP3 = false
+// commitWritten hands a flushed range to the piece verifier and prefix hasher.
+// Pieces that fail verification are uncounted and queued for another attempt.
+func (d *ConcurrentDownloader) commitWritten(queue *TaskQueue, offset, length int64) {
+	if d.pieces == nil {
+		d.hasher.MarkWritten(offset, length)
+		d.markWritten(offset, length)
+		return
 	}
 
-	http1Client := protocolClient{name: types.ProtocolHTTP1, client: newHTTPClient(buildHTTPTransport(false))}
-	http2Client := protocolClient{name: types.ProtocolHTTP2, client: newHTTPClient(buildHTTPTransport(true))}
-
-	var http3Client protocolClient
-	var http3Transport *http3.Transport
-	if supportsHTTP3 {
-		http3Transport = &http3.Transport{
-			TLSClientConfig: &tls.Config{
-				NextProtos: []string{"h3"},
-			},
-			QUICConfig: &quic.Config{
-				HandshakeIdleTimeout: types.DefaultTLSHandshakeTimeout,
-				MaxIdleTimeout:       types.DefaultIdleConnTimeout,
-				KeepAlivePeriod:      types.KeepAliveDuration,
-			},
-		}
-		http3Client = protocolClient{name: types.ProtocolHTTP3, client: newHTTPClient(http3Transport)}
+	verified, failed := d.pieces.MarkWritten(offset, length)
+	for _, r := range verified {
+		d.hasher.MarkWritten(r.Offset, r.Length)
+		d.markWritten(r.Offset, r.Length)
 	}
-
-	formatNames := func(primary protocolClient, fallbacks []protocolClient) string {
-		if len(fallbacks) == 0 {
-			return primary.name
-		}
-		names := make([]string, 0, 1+len(fallbacks))
-		names = append(names, primary.name)
-		for _, fallback := range fallbacks {
-			names = append(names, fallback.name)
+	for _, r := range failed {
+		if d.State != nil {
+			d.State.Downloaded.Add(-r.Length)
 		}
-		return strings.Join(names, " -> ")
+		queue.Push(r)
 	}
+}
 
-	makeSet := func(primary protocolClient, fallbacks ...protocolClient) *clientSet {
-		set := &clientSet{primary: primary, fallbacks: fallbacks}
-		if http3Transport != nil {
-			if primary.name == types.ProtocolHTTP3 {
-				set.http3Transport = http3Transport
-			} else {
-				for _, fallback := range fallbacks {
-					if fallback.name == types.ProtocolHTTP3 {
-						set.http3Transport = http3Transport
-						break
-					}
-				}
-			}
-		}
-		utils.Debug("Transport selection: pref=%s supports[h2=%t h3=%t] chain=%s", protocol, supportsHTTP2, supportsHTTP3, formatNames(primary, fallbacks))
-		return set
+// markWritten lets readers that follow the download see a range on disk.
+func (d *ConcurrentDownloader) markWritten(offset, length int64) {
+	if d.State != nil {
+		d.State.MarkWritten(offset, length)
 	}
+}
 
-	switch protocol {
-	case types.ProtocolHTTP1:
-		return makeSet(http1Client)
-	case types.ProtocolHTTP2:
-		if supportsHTTP2 {
-			return makeSet(http2Client, http1Client)
-		}
-		return makeSet(http1Client)
-	case types.ProtocolHTTP3:
-		if supportsHTTP3 {
-			return makeSet(http3Client, http1Client)
-		}
-		return makeSet(http1Client)
-	default:
-		if supportsHTTP3 {
-			if supportsHTTP2 {
-				return makeSet(http3Client, http1Client, http2Client)
-			}
-			return makeSet(http3Client, http1Client)
-		}
-		if supportsHTTP2 {
-			return makeSet(http1Client, http2Client)
-		}
-		return makeSet(http1Client)
+// sink takes the bytes workers download: a write-back buffer in front of
+// the working file, or a stream window.
+type sink interface {
+	WriteAt(p []byte, off int64) (int, error)
+	Commit(off, length int64, fn func())
+	Flush() error
+	Pending() []types.Task
+	Stats() types.WriteStats
+}
+
+// newConcurrentClients creates HTTP clients tuned for concurrent downloads.
+func (d *ConcurrentDownloader) newConcurrentClients(numConns int, supportsHTTP2 bool, supportsHTTP3 bool) *httpclient.Set {
+	// Ensure we have enough connections per host
+	maxConns := d.Runtime.GetMaxConnectionsPerHost()
+	if numConns > maxConns {
+		maxConns = numConns
 	}
+	return httpclient.NewSet(d.Runtime, maxConns, supportsHTTP2, supportsHTTP3)
 }
 
 // Download downloads a file using multiple concurrent connections.
@@ -415,7 +320,15 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	}
 
 	// Working file has .GoFetch suffix until download completes.
-	workingPath := destPath + types.IncompleteSuffix
+	workingPath := d.WorkingPath
+	if workingPath == "" {
+		workingPath = destPath + types.IncompleteSuffix
+	}
+
+	// Without a shared limiter, still keep the origin's backoff state between workers.
+	if d.Hosts == nil {
+		d.Hosts = connlimit.NewHosts(0)
+	}
 
 	// Create cancellable context for pause support
 	downloadCtx, cancel := context.WithCancel(ctx)
@@ -434,6 +347,16 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	numConns := d.getInitialConnections(fileSize)
 	chunkSize := d.determineChunkSize(fileSize, numConns)
 
+	// Ask for numConns connections from the budget shared with other downloads.
+	// The grant can change while we run; workers follow it between tasks.
+	lease := d.Connections.Acquire(numConns)
+	defer lease.Release()
+
+	// Unless the user fixed the count, adapt it to the measured throughput.
+	if d.Runtime.GetRequestedConnections() == 0 && d.State != nil {
+		d.scaling = newConnController(lease, numConns, d.connectionCeiling(fileSize))
+	}
+
 	// Create tuned HTTP clients for concurrent downloads
 	clients := d.newConcurrentClients(numConns, supportsHTTP2, supportsHTTP3)
 	defer clients.Close()
@@ -443,21 +366,29 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		d.State.InitBitmap(fileSize, chunkSize)
 	}
 
-	// Create and preallocate output file with .GoFetch suffix.
-	outFile, err := os.OpenFile(workingPath, os.O_CREATE|os.O_RDWR, 0o644)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer func() {
-		if err := outFile.Close(); err != nil {
-			utils.Debug("Error closing file: %v", err)
+	// Create and preallocate output file with .GoFetch suffix. A stream has
+	// no file and always starts from the beginning.
+	var outFile *os.File
+	if d.Stream == nil {
+		f, err := os.OpenFile(workingPath, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
 		}
-	}()
+		outFile = f
+		defer func() {
+			if err := outFile.Close(); err != nil {
+				utils.Debug("Error closing file: %v", err)
+			}
+		}()
+	}
 
 	tasks := createTasks(fileSize, chunkSize)
 	// Check for saved state BEFORE truncating (resume case).
-	savedState, err := state.LoadState(rawurl, destPath)
-	isResume := err == nil && savedState != nil && len(savedState.Tasks) > 0
+	var savedState *types.DownloadState
+	if d.Stream == nil {
+		savedState, _ = state.LoadState(rawurl, destPath)
+	}
+	isResume := savedState != nil && len(savedState.Tasks) > 0
 
 	if isResume {
 		// Resume: use saved tasks and restore downloaded counter.
@@ -484,9 +415,14 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			}
 		}
 		utils.Debug("Resuming from saved state: %d tasks, %d bytes downloaded", len(tasks), savedState.Downloaded)
-	} else {
-		// Fresh download: preallocate file and create new tasks.
-		if err := outFile.Truncate(fileSize); err != nil {
+	} else if outFile != nil {
+		// Fresh download: preallocate file and create new tasks. Truncate
+		// leaves a sparse file; Allocate reserves the blocks up front.
+		allocate := outFile.Truncate
+		if d.Runtime.GetPreallocate() {
+			allocate = func(size int64) error { return diskspace.Allocate(outFile, size) }
+		}
+		if err := allocate(fileSize); err != nil {
 			return fmt.Errorf("failed to preallocate file: %w", err)
 		}
 		// Robustness: ensure state counter starts at 0 for fresh download
@@ -495,12 +431,117 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			d.State.SyncSessionStart()
 		}
 	}
+
+	// A stream has no file to read back: its window checks the checksum as
+	// the reader takes the bytes, and piece digests go unused.
+	checksum, pieceHashes := d.Checksum, d.Pieces
+	if d.Stream != nil {
+		checksum, pieceHashes = nil, nil
+	}
+
+	// Hash the contiguous prefix as workers fill it so verification is cheap at the end.
+	hasher, err := integrity.NewPrefixHasher(checksum)
+	if err != nil {
+		return err
+	}
+
+	// Check published piece digests as soon as each piece is fully written.
+	pieces, err := integrity.NewPieceVerifier(pieceHashes, fileSize, outFile)
+	if err != nil {
+		utils.Debug("Ignoring piece hashes: %v", err)
+	}
+
+	var written []types.Task
+	if isResume {
+		if err := hasher.Restore(savedState.HashState, savedState.HashedBytes); err != nil {
+			utils.Debug("Discarding saved hash state: %v", err)
+		}
+		// Everything outside the remaining tasks is already on disk.
+		// With piece hashes, only whole verified pieces count for the prefix hash.
+		written = pieces.Restore(completedRanges(fileSize, tasks))
+		for _, done := range written {
+			hasher.MarkWritten(done.Offset, done.Length)
+		}
+	}
+	if d.State != nil {
+		d.State.SetWritten(written)
+	}
+	d.hasher = hasher
+	d.pieces = pieces
+
+	hashCtx, cancelHashing := context.WithCancel(downloadCtx)
+	var wgHasher sync.WaitGroup
+	wgHasher.Add(1)
+	go func() {
+		defer wgHasher.Done()
+		hasher.Run(hashCtx, outFile)
+	}()
+	// Stop the hasher before snapshotting it or touching outFile again.
+	stopHashing := func() {
+		cancelHashing()
+		wgHasher.Wait()
+	}
+	defer stopHashing()
+
+	// Workers write through a buffer that merges neighbouring chunks when a
+	// write budget is set.
+	var out sink = d.Stream
+	if d.Stream == nil {
+		out = writeback.New(outFile, d.Runtime.GetWriteBuffer(), d.Runtime.GetWriteDropCache())
+	}
+
+	// Readers that follow the download want the earliest missing range next.
 	queue := NewTaskQueue()
+	queue.ordered = d.Stream != nil || d.Runtime.SequentialDownload
 	queue.PushMultiple(tasks)
 
 	// Start time for stats
 	startTime := time.Now()
 
+	// Workers are started and retired by the scaler as the connection grant changes.
+	var wg sync.WaitGroup
+	workerErrors := make(chan error, numConns)
+
+	// Combine primary + secondary for workers
+	// We want to ensure the primary is included if it was valid (it should be, otherwise TUIDownload would have failed)
+	var workerMirrors []string
+
+	// Add primary if compatible (check active map or assume yes since we are here)
+	// TUIDownload checks primary support before calling us.
+	workerMirrors = append(workerMirrors, rawurl)
+
+	// Add other valid mirrors
+	for _, v := range activeMirrors {
+		if v != rawurl {
+			workerMirrors = append(workerMirrors, v)
+		}
+	}
+
+	// Double check we have at least one mirror
+	if len(workerMirrors) == 0 {
+		// Should have been caught by early check but safe fallback
+		workerMirrors = []string{rawurl}
+	}
+
+	d.workers = newWorkerScaler(lease, func(workerID int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := d.worker(downloadCtx, workerID, workerMirrors, out, queue, fileSize, startTime, clients)
+			if err == errWorkerRetired {
+				return
+			}
+			d.workers.Exit()
+			if errors.Is(err, types.ErrFileChanged) || types.NeedsNewURL(err) || types.IsDiskFull(err) {
+				// No worker can finish the file from this URL; stop everyone.
+				cancel()
+			}
+			if err != nil && err != context.Canceled {
+				workerErrors <- err
+			}
+		}()
+	})
+
 	// Start balancer goroutine for dynamic chunk splitting.
 	balancerCtx, cancelBalancer := context.WithCancel(downloadCtx)
 	defer cancelBalancer()
@@ -516,6 +557,9 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			case <-balancerCtx.Done():
 				return
 			case <-ticker.C:
+				// Fetch what readers of the partial wait for first.
+				d.followReaders(queue, fileSize)
+
 				// Aggressively fill idle workers
 				// Continue splitting/stealing as long as we have idle workers and are making progress
 				for queue.IdleWorkers() > 0 {
@@ -562,7 +606,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			case <-ticker.C:
 				// Ensure queue is empty (no pending retries) before considering byte count.
 				// This avoids early exit when overlaps inflate counters.
-				if queue.Len() == 0 && (int(queue.IdleWorkers()) == numConns || (d.State != nil && d.State.Downloaded.Load() >= fileSize)) {
+				if queue.Len() == 0 && (int(queue.IdleWorkers()) == d.workers.Running() || (d.State != nil && d.State.Downloaded.Load() >= fileSize)) {
 					queue.Close()
 					return
 				}
@@ -586,40 +630,54 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		}
 	}()
 
-	// Start workers.
-	var wg sync.WaitGroup
-	workerErrors := make(chan error, numConns)
-
-	// Combine primary + secondary for workers
-	// We want to ensure the primary is included if it was valid (it should be, otherwise TUIDownload would have failed)
-	var workerMirrors []string
-
-	// Add primary if compatible (check active map or assume yes since we are here)
-	// TUIDownload checks primary support before calling us.
-	workerMirrors = append(workerMirrors, rawurl)
+	// Keep buffered writes from waiting long, so the hasher and piece checks
+	// keep up and the UI sees current write counts.
+	wgHelpers.Add(1)
+	go func() {
+		defer wgHelpers.Done()
+		ticker := time.NewTicker(writeback.FlushInterval)
+		defer ticker.Stop()
 
-	// Add other valid mirrors
-	for _, v := range activeMirrors {
-		if v != rawurl {
-			workerMirrors = append(workerMirrors, v)
+		for {
+			select {
+			case <-balancerCtx.Done():
+				return
+			case <-ticker.C:
+				if err := out.Flush(); err != nil {
+					utils.Debug("Failed to write buffered data: %v", err)
+				}
+				if d.State != nil {
+					d.State.SetWriteStats(out.Stats())
+				}
+			}
 		}
-	}
+	}()
 
-	// Double check we have at least one mirror
-	if len(workerMirrors) == 0 {
-		// Should have been caught by early check but safe fallback
-		workerMirrors = []string{rawurl}
+	if d.scaling != nil {
+		wgHelpers.Add(1)
+		go func() {
+			defer wgHelpers.Done()
+			ticker := time.NewTicker(types.ScaleInterval)
+			defer ticker.Stop()
+
+			lastBytes, lastTime := d.State.Downloaded.Load(), time.Now()
+			for {
+				select {
+				case <-balancerCtx.Done():
+					return
+				case <-ticker.C:
+					d.adjustConnections(&lastBytes, &lastTime)
+				}
+			}
+		}()
 	}
 
-	for i := 0; i < numConns; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			err := d.worker(downloadCtx, workerID, workerMirrors, outFile, queue, fileSize, startTime, clients)
-			if err != nil && err != context.Canceled {
-				workerErrors <- err
-			}
-		}(i)
+	// Start workers once the budget has room for at least one connection.
+	// A pause while waiting leaves every task in the queue for the pause handler.
+	if err := d.workers.Start(downloadCtx); err == nil {
+		if running := d.workers.Running(); running < numConns {
+			utils.Debug("Connection budget allows %d of %d connections", running, numConns)
+		}
 	}
 
 	// Wait for all workers to complete
@@ -637,8 +695,30 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		}
 	}
 
-	// Handle pause: state saved.
-	if d.State != nil && d.State.IsPaused() {
+	// Write out what is still buffered. Ranges that do not make it to disk
+	// are saved as remaining work below.
+	if err := out.Flush(); err != nil && downloadErr == nil {
+		downloadErr = fmt.Errorf("write error: %w", err)
+	}
+	writes := out.Stats()
+	if d.State != nil {
+		d.State.SetWriteStats(writes)
+	}
+
+	// A stream cannot be paused or resumed: whatever stopped it ends it.
+	if d.Stream != nil {
+		if downloadErr == nil && downloadCtx.Err() != nil {
+			downloadErr = context.Canceled
+		}
+		d.Stream.CloseWithError(downloadErr)
+		return downloadErr
+	}
+
+	// Handle pause: state saved. An expired URL or a full disk keeps its
+	// progress the same way, so the download can continue once it has a new
+	// URL or more space.
+	stopped := types.NeedsNewURL(downloadErr) || types.IsDiskFull(downloadErr)
+	if (d.State != nil && d.State.IsPaused()) || stopped {
 		// 1. Collect active tasks as remaining work FIRST.
 		var activeRemaining []types.Task
 		d.activeMu.Lock()
@@ -652,6 +732,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		// 2. Collect remaining tasks from queue.
 		remainingTasks := queue.DrainRemaining()
 		remainingTasks = append(remainingTasks, activeRemaining...)
+		remainingTasks = append(remainingTasks, out.Pending()...)
 
 		// Calculate Downloaded from remaining tasks (ensures consistency).
 		var remainingBytes int64
@@ -677,6 +758,9 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			totalElapsed = time.Since(startTime)
 		}
 
+		stopHashing()
+		hashState, hashedBytes := hasher.Snapshot()
+
 		// Save state for resume (use computed value for consistency).
 		s := &types.DownloadState{
 			URL:             d.URL,
@@ -690,6 +774,13 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 			Mirrors:         candidateMirrors,
 			ChunkBitmap:     chunkBitmap,
 			ActualChunkSize: actualChunkSize,
+			Checksum:        d.Checksum.String(),
+			HashState:       hashState,
+			HashedBytes:     hashedBytes,
+			Pieces:          d.Pieces,
+			ETag:            d.ETag,
+			LastModified:    d.LastModified,
+			WorkingPath:     workingPath,
 		}
 		if err := state.SaveState(d.URL, destPath, s); err != nil {
 			utils.Debug("Failed to save pause state: %v", err)
@@ -697,9 +788,24 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 
 		utils.Debug("Download paused, state saved (Downloaded=%d, RemainingTasks=%d, RemainingBytes=%d)",
 			computedDownloaded, len(remainingTasks), remainingBytes)
+		if stopped {
+			return downloadErr
+		}
 		return types.ErrPaused // Signal valid pause to caller
 	}
 
+	// The file changed on the server mid-download, so the partial mixes two
+	// versions. Restarting has to begin from an empty file; the caller
+	// starts over.
+	if errors.Is(downloadErr, types.ErrFileChanged) {
+		if d.Runtime.GetOnRemoteChange() == types.RemoteChangeRestart {
+			stopHashing()
+			_ = os.Remove(workingPath)
+			_ = state.DeleteState(d.ID, d.URL, destPath)
+		}
+		return downloadErr
+	}
+
 	// Handle cancel: context was cancelled but not via Pause().
 	// Propagate cancellation so callers don't treat this as a successful completion.
 	if downloadCtx.Err() == context.Canceled {
@@ -710,16 +816,40 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 		return downloadErr
 	}
 
+	// Pieces that failed verification at the very end may not have been retried.
+	if n := pieces.Pending(); n > 0 {
+		return types.NewError(types.KindChecksum, fmt.Errorf("%d pieces failed verification", n))
+	}
+
+	utils.Debug("Wrote %s in %d writes from %d worker writes (write amplification %.2f)",
+		utils.ConvertBytesToHumanReadable(writes.DiskBytes), writes.DiskWrites, writes.Writes,
+		float64(writes.DiskBytes)/float64(fileSize))
+
 	// Final sync
 	if err := outFile.Sync(); err != nil {
 		return fmt.Errorf("failed to sync file: %w", err)
 	}
 
+	// Verify content before exposing it under the final name.
+	// Only the bytes the hasher has not reached yet are read here.
+	stopHashing()
+	verifyErr := hasher.Finish(outFile, fileSize)
+
 	// Close file before renaming
 	_ = outFile.Close()
 
-	// Rename from .GoFetch to final destination.
-	if err := os.Rename(workingPath, destPath); err != nil {
+	if verifyErr != nil {
+		if errors.Is(verifyErr, types.ErrChecksumMismatch) {
+			// The partial cannot be repaired by resuming, so discard it.
+			_ = os.Remove(workingPath)
+			_ = state.DeleteState(d.ID, d.URL, destPath)
+		}
+		return verifyErr
+	}
+
+	// Move from .GoFetch to final destination; from a temp dir on another
+	// filesystem this is a copy.
+	if err := utils.MoveFile(workingPath, destPath, d.reportMove(destPath)); err != nil {
 		// Check for race condition: did someone else already rename it?
 		if os.IsNotExist(err) {
 			if info, statErr := os.Stat(destPath); statErr == nil && info.Size() == fileSize {
@@ -729,7 +859,7 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 				return nil
 			}
 		}
-		return fmt.Errorf("failed to rename completed file: %w", err)
+		return fmt.Errorf("failed to move completed file: %w", err)
 	}
 
 	// Delete state file on successful completion.
@@ -738,3 +868,18 @@ func (d *ConcurrentDownloader) Download(ctx context.Context, rawurl string, cand
 	// Note: Download completion notifications are handled by the TUI via DownloadCompleteMsg
 	return nil
 }
+
+// reportMove returns a MoveFile progress callback that announces the copy
+// of a finished file to destPath.
+func (d *ConcurrentDownloader) reportMove(destPath string) func(copied, total int64) {
+	return func(copied, total int64) {
+		if d.ProgressChan != nil {
+			d.ProgressChan <- events.DownloadMovingMsg{
+				DownloadID: d.ID,
+				Filename:   filepath.Base(destPath),
+				Moved:      copied,
+				Total:      total,
+			}
+		}
+	}
+}
@@ -81,8 +81,9 @@ func (d *ConcurrentDownloader) checkWorkerHealth() {
 		}
 
 		// Check for slow worker (relative speed)
-		// Only cancel if: below threshold
-		if meanSpeed > 0 {
+		// Only cancel if: below threshold, and not because of the rate limit
+		// or a stream reader setting the pace
+		if meanSpeed > 0 && !d.Bandwidth.Limited() && d.Stream == nil {
 			workerSpeed := active.GetSpeed()
 			threshold := d.Runtime.GetSlowWorkerThreshold()
 			isBelowThreshold := workerSpeed > 0 && workerSpeed < threshold*meanSpeed
@@ -0,0 +1,80 @@
+package concurrent
+
+import (
+	"concurrent_downloader/internal/download/types"
+	"io"
+	"os"
+)
+
+// partMemory is how much of a part held back for its digest stays in
+// memory; the rest goes to a scratch file.
+const partMemory = 16 * types.MB
+
+// partBuffer holds the bytes of a part until its digest checks out, so that
+// a corrupt part never reaches the working file or a stream.
+type partBuffer struct {
+	tempDir string
+	mem     []byte
+	file    *os.File // Bytes past partMemory, created on demand
+	size    int64
+}
+
+func newPartBuffer(length int64, tempDir string) *partBuffer {
+	return &partBuffer{tempDir: tempDir, mem: make([]byte, 0, min(length, partMemory))}
+}
+
+// Write appends p to the part.
+func (b *partBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := partMemory - len(b.mem); room > 0 {
+		k := min(room, len(p))
+		b.mem = append(b.mem, p[:k]...)
+		b.size += int64(k)
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	if b.file == nil {
+		f, err := os.CreateTemp(b.tempDir, "gofetch-part-*")
+		if err != nil {
+			return n - len(p), err
+		}
+		b.file = f
+	}
+	written, err := b.file.WriteAt(p, b.size-int64(len(b.mem)))
+	b.size += int64(written)
+	return n - len(p) + written, err
+}
+
+// ReadAt reads the part's bytes at off, counted from the start of the part.
+func (b *partBuffer) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < int64(len(b.mem)) {
+		n = copy(p, b.mem[off:])
+	}
+	if n == len(p) {
+		return n, nil
+	}
+	if b.file == nil {
+		return n, io.EOF
+	}
+	m, err := b.file.ReadAt(p[n:], off+int64(n)-int64(len(b.mem)))
+	return n + m, err
+}
+
+// Size returns how many bytes the part holds.
+func (b *partBuffer) Size() int64 {
+	return b.size
+}
+
+// Close drops the part and removes its scratch file.
+func (b *partBuffer) Close() {
+	b.mem = nil
+	b.size = 0
+	if b.file != nil {
+		_ = b.file.Close()
+		_ = os.Remove(b.file.Name())
+		b.file = nil
+	}
+}
@@ -0,0 +1,119 @@
+package concurrent
+
+import (
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+	"sync/atomic"
+)
+
+const (
+	// readerLead is how far past a reader's position missing bytes are
+	// fetched before anything else.
+	readerLead = 16 * types.MB
+
+	// readerReach is how far a worker may still be from a reader's next
+	// missing byte and count as fetching it.
+	readerReach = types.MinChunk
+)
+
+// followReaders moves the work that readers of the working file wait for
+// to the front. The queued range holding a reader's next missing byte is
+// taken next; an active range that gets there only later is split there.
+// When no worker is idle to take it, the worker furthest from every reader
+// hands its range back.
+func (d *ConcurrentDownloader) followReaders(queue *TaskQueue, fileSize int64) {
+	if d.State == nil {
+		return
+	}
+	positions := d.State.ReadPositions()
+	for _, pos := range positions {
+		n, _ := d.State.WrittenAt(pos)
+		need := pos + n
+		if need >= fileSize || n >= readerLead || d.fetching(need) {
+			continue
+		}
+		if !queue.Prioritise(need) && !d.splitActive(queue, need) {
+			continue
+		}
+		if queue.IdleWorkers() == 0 {
+			d.yieldWorker(positions)
+		}
+	}
+}
+
+// fetching reports whether a worker is about to write offset.
+func (d *ConcurrentDownloader) fetching(offset int64) bool {
+	d.activeMu.Lock()
+	defer d.activeMu.Unlock()
+	for _, active := range d.activeTasks {
+		current := atomic.LoadInt64(&active.CurrentOffset)
+		stopAt := atomic.LoadInt64(&active.StopAt)
+		if current <= offset && offset < stopAt && offset-current <= readerReach {
+			return true
+		}
+	}
+	return false
+}
+
+// splitActive stops the active task that would write offset only later at
+// offset, and queues the rest of its range as urgent.
+func (d *ConcurrentDownloader) splitActive(queue *TaskQueue, offset int64) bool {
+	d.activeMu.Lock()
+	defer d.activeMu.Unlock()
+
+	for id, active := range d.activeTasks {
+		if !active.Splittable() {
+			continue
+		}
+		current := atomic.LoadInt64(&active.CurrentOffset)
+		stopAt := atomic.LoadInt64(&active.StopAt)
+		split := offset / types.AlignSize * types.AlignSize
+		if split <= current || split >= stopAt {
+			continue
+		}
+
+		atomic.StoreInt64(&active.StopAt, split)
+		// The worker may have written past split meanwhile.
+		start := max(split, atomic.LoadInt64(&active.CurrentOffset))
+		if start >= stopAt {
+			return false
+		}
+		queue.PushUrgent(types.Task{Offset: start, Length: stopAt - start})
+		utils.Debug("Reader waits at %d: split worker %d's range at %d", offset, id, start)
+		return true
+	}
+	return false
+}
+
+// yieldWorker cancels the task of the worker furthest from every reader,
+// which queues what it has left and takes the urgent range next. A part
+// held back for its digest is left to finish, as it would start over.
+func (d *ConcurrentDownloader) yieldWorker(positions []int64) {
+	d.activeMu.Lock()
+	defer d.activeMu.Unlock()
+
+	var victim *ActiveTask
+	var victimID int
+	var maxRemaining int64
+	for id, active := range d.activeTasks {
+		if !active.Splittable() {
+			continue
+		}
+		current := atomic.LoadInt64(&active.CurrentOffset)
+		serving := false
+		for _, pos := range positions {
+			if current >= pos-readerReach && current < pos+readerLead {
+				serving = true
+				break
+			}
+		}
+		if remaining := active.RemainingBytes(); !serving && remaining > maxRemaining {
+			victim, victimID, maxRemaining = active, id, remaining
+		}
+	}
+	if victim == nil || victim.Cancel == nil {
+		return
+	}
+	utils.Debug("Reader waits: worker %d hands back %s", victimID, utils.ConvertBytesToHumanReadable(maxRemaining))
+	victim.Cancel()
+}
@@ -0,0 +1,110 @@
+package concurrent
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"concurrent_downloader/internal/download/connlimit"
+)
+
+// errWorkerRetired is returned by a worker that exited because the
+// connection grant shrank.
+var errWorkerRetired = errors.New("worker retired")
+
+// workerScaler keeps the number of running workers in line with the
+// download's connection grant. Workers call Retire and Grow between tasks, so
+// changes take effect at task boundaries without interrupting transfers.
+// Each worker holds one of the lease's connections while it runs. At least one
+// worker keeps running so a started download never stalls.
+type workerScaler struct {
+	lease *connlimit.Lease
+	start func(id int) // Launches a worker goroutine
+
+	mu      sync.Mutex
+	running int
+	nextID  int
+}
+
+func newWorkerScaler(lease *connlimit.Lease, start func(id int)) *workerScaler {
+	return &workerScaler{lease: lease, start: start}
+}
+
+// target returns how many workers should be running.
+func (s *workerScaler) target() int {
+	if g := s.lease.Granted(); g > 1 {
+		return g
+	}
+	return 1
+}
+
+// Start waits until the lease has room for a connection, then grows to the
+// grant. It returns ctx's error if the download is cancelled first.
+func (s *workerScaler) Start(ctx context.Context) error {
+	for s.Running() == 0 {
+		if err := s.lease.Wait(ctx); err != nil {
+			return err
+		}
+		s.Grow()
+	}
+	return nil
+}
+
+// Grow starts workers until the running count reaches the grant, as long as
+// the global budget has free connections. It must be called from a running
+// worker, or before the workers are waited on.
+func (s *workerScaler) Grow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grow()
+}
+
+// Scale is Grow for callers outside the workers, e.g. after the grant was
+// raised. It does nothing once every worker has exited, since the download
+// is then finishing.
+func (s *workerScaler) Scale() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running > 0 {
+		s.grow()
+	}
+}
+
+// grow does the work of Grow. Callers hold s.mu.
+func (s *workerScaler) grow() {
+	for s.running < s.target() && s.lease.TryStart() {
+		id := s.nextID
+		s.nextID++
+		s.running++
+		s.start(id)
+	}
+}
+
+// Retire reports whether the calling worker should exit to give a connection
+// back. A true result already accounts for the worker leaving.
+func (s *workerScaler) Retire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running > s.target() {
+		s.running--
+		s.lease.Stop()
+		return true
+	}
+	return false
+}
+
+// Exit records that a worker stopped for any reason other than Retire.
+func (s *workerScaler) Exit() {
+	s.mu.Lock()
+	s.running--
+	s.lease.Stop()
+	s.mu.Unlock()
+}
+
+// Running returns the number of workers currently running.
+func (s *workerScaler) Running() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
@@ -27,6 +27,13 @@ type ActiveTask struct {
 	WindowBytes int64
 
 	Hedged int32 // Atomic: 1 if an idle worker is already racing this task
+	Whole  int32 // Atomic: 1 while the part is held back for its digest; not split
+}
+
+// Splittable reports whether the task's range may be cut short. A part held
+// back for its digest is only checked, and written, whole.
+func (at *ActiveTask) Splittable() bool {
+	return atomic.LoadInt32(&at.Whole) == 0
 }
 
 func (at *ActiveTask) RemainingBytes() int64 {
@@ -13,6 +13,8 @@ type TaskQueue struct {
 	cond        *sync.Cond
 	done        bool
 	idleWorkers int64
+	ordered     bool         // Pop the task with the lowest offset rather than the oldest
+	urgent      []types.Task // Ranges a reader waits for, popped before the rest
 }
 
 func NewTaskQueue() *TaskQueue {
@@ -35,20 +37,76 @@ func (q *TaskQueue) PushMultiple(tasks []types.Task) {
 	q.mu.Unlock()
 }
 
+// PushUrgent queues t ahead of every task pushed with Push.
+func (q *TaskQueue) PushUrgent(t types.Task) {
+	q.mu.Lock()
+	q.urgent = append(q.urgent, t)
+	q.cond.Signal()
+	q.mu.Unlock()
+}
+
+// Prioritise makes the queued range holding offset urgent, splitting off
+// the part before offset so that stays where it was. It reports whether a
+// queued range holds offset.
+func (q *TaskQueue) Prioritise(offset int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.urgent {
+		if offset >= t.Offset && offset < t.Offset+t.Length {
+			return true
+		}
+	}
+	for i := q.head; i < len(q.tasks); i++ {
+		t := q.tasks[i]
+		if offset < t.Offset || offset >= t.Offset+t.Length {
+			continue
+		}
+		split := max(t.Offset, offset/types.AlignSize*types.AlignSize)
+		if split > t.Offset {
+			q.tasks[i].Length = split - t.Offset
+		} else {
+			q.tasks = append(q.tasks[:i], q.tasks[i+1:]...)
+			if q.head >= len(q.tasks) {
+				q.tasks, q.head = nil, 0
+			}
+		}
+		q.urgent = append(q.urgent, types.Task{Offset: split, Length: t.Offset + t.Length - split})
+		q.cond.Signal()
+		return true
+	}
+	return false
+}
+
 func (q *TaskQueue) Pop() (types.Task, bool) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	for len(q.tasks) == 0 && !q.done {
+	for len(q.tasks) == 0 && len(q.urgent) == 0 && !q.done {
 		atomic.AddInt64(&q.idleWorkers, 1)
 		q.cond.Wait()
 		atomic.AddInt64(&q.idleWorkers, -1)
 	}
 
+	if len(q.urgent) > 0 {
+		t := q.urgent[0]
+		q.urgent = q.urgent[1:]
+		return t, true
+	}
 	if len(q.tasks) == 0 {
 		return types.Task{}, false
 	}
 
+	if q.ordered {
+		lowest := q.head
+		for i := q.head + 1; i < len(q.tasks); i++ {
+			if q.tasks[i].Offset < q.tasks[lowest].Offset {
+				lowest = i
+			}
+		}
+		q.tasks[q.head], q.tasks[lowest] = q.tasks[lowest], q.tasks[q.head]
+	}
+
 	t := q.tasks[q.head]
 	q.head++
 	// Compact the slice occasionally to keep memory bounded on long runs.
@@ -62,7 +120,7 @@ func (q *TaskQueue) Pop() (types.Task, bool) {
 func (q *TaskQueue) Len() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	return len(q.tasks) - q.head
+	return len(q.tasks) - q.head + len(q.urgent)
 }
 
 func (q *TaskQueue) Close() {
@@ -80,12 +138,12 @@ func (q *TaskQueue) DrainRemaining() []types.Task {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.head >= len(q.tasks) {
+	if q.head >= len(q.tasks) && len(q.urgent) == 0 {
 		return nil
 	}
 
-	remaining := make([]types.Task, len(q.tasks)-q.head)
-	copy(remaining, q.tasks[q.head:])
+	remaining := append(q.urgent, q.tasks[q.head:]...)
+	q.urgent = nil
 	q.tasks = nil
 	q.head = 0
 	return remaining
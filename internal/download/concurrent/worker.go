@@ -1,23 +1,24 @@
 package concurrent
 
 import (
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/httpclient"
+	"concurrent_downloader/internal/download/integrity"
 	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/events"
 	"concurrent_downloader/internal/utils"
 	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"net"
 	"net/http"
-	"os"
-	"strings"
+	"path/filepath"
 	"sync/atomic"
 	"time"
-
-	"github.com/quic-go/quic-go"
 )
 
-func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []string, file *os.File, queue *TaskQueue, totalSize int64, startTime time.Time, clients *clientSet) error {
+func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []string, out sink, queue *TaskQueue, totalSize int64, startTime time.Time, clients *httpclient.Set) error {
 	// Get pooled buffer
 	bufPtr := d.bufPool.Get().(*[]byte)
 	defer d.bufPool.Put(bufPtr)
@@ -30,6 +31,12 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 	currentMirrorIdx := id % len(mirrors)
 
 	for {
+		// Give the connection back if the grant shrank.
+		if d.workers.Retire() {
+			utils.Debug("Worker %d retired: connection grant lowered", id)
+			return errWorkerRetired
+		}
+
 		// Get next task
 		task, ok := queue.Pop()
 
@@ -37,6 +44,9 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			return nil // Queue closed, no more work
 		}
 
+		// There is work left, so pick up connections granted since the last task.
+		d.workers.Grow()
+
 		// Update active workers
 		if d.State != nil {
 			d.State.ActiveWorkers.Add(1)
@@ -46,15 +56,18 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 		maxRetries := d.Runtime.GetMaxTaskRetries()
 		for attempt := 0; attempt < maxRetries; attempt++ {
 			if attempt > 0 {
+				// A throttled origin is waited out in Hosts.Acquire, and
+				// being throttled does not make a mirror bad.
+				if !errors.Is(lastErr, types.ErrThrottled) {
+					if len(mirrors) == 1 {
+						time.Sleep(time.Duration(1<<attempt) * d.Runtime.GetRetryBaseDelay()) // Exponential backoff incase of failure
+					}
 
-				if len(mirrors) == 1 {
-					time.Sleep(time.Duration(1<<attempt) * types.RetryBaseDelay) // Exponential backoff incase of failure
+					// Fail over to another mirror on retry to avoid a bad host.
+					// Report error for the previous mirror.
+					d.ReportMirrorError(mirrors[currentMirrorIdx])
 				}
 
-				// Fail over to another mirror on retry to avoid a bad host.
-				// Report error for the previous mirror.
-				d.ReportMirrorError(mirrors[currentMirrorIdx])
-
 				currentMirrorIdx = (currentMirrorIdx + 1) % len(mirrors)
 				utils.Debug("Worker %d: switching to mirror %s (attempt %d)", id, mirrors[currentMirrorIdx], attempt+1)
 			}
@@ -87,7 +100,7 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			}
 
 			taskStart := time.Now()
-			lastErr = d.downloadTask(taskCtx, currentURL, file, activeTask, buf, clients, totalSize)
+			lastErr = d.downloadTask(taskCtx, currentURL, out, queue, activeTask, buf, clients, totalSize)
 
 			// Capture external cancellation BEFORE calling taskCancel();
 			// otherwise taskCtx.Err() will always be non-nil.
@@ -140,6 +153,28 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 			delete(d.activeTasks, id)
 			d.activeMu.Unlock()
 
+			// No retry or mirror can complete a file that has been replaced.
+			if errors.Is(lastErr, types.ErrFileChanged) {
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				return lastErr
+			}
+
+			// An expired URL with nothing to fail over to needs a new one, and
+			// a full disk needs space. Hand the task back so the saved state
+			// still covers it.
+			if (len(mirrors) == 1 && types.NeedsNewURL(lastErr)) || types.IsDiskFull(lastErr) {
+				if current := atomic.LoadInt64(&activeTask.CurrentOffset); current > task.Offset {
+					task = types.Task{Offset: current, Length: task.Offset + task.Length - current}
+				}
+				queue.Push(task)
+				if d.State != nil {
+					d.State.ActiveWorkers.Add(-1)
+				}
+				return lastErr
+			}
+
 			if lastErr == nil {
 				// Check if we stopped early due to stealing
 				stopAt := atomic.LoadInt64(&activeTask.StopAt)
@@ -175,44 +210,28 @@ func (d *ConcurrentDownloader) worker(ctx context.Context, id int, mirrors []str
 
 // downloadTask downloads a single byte range and writes to file at offset
 
-func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string, file *os.File, activeTask *ActiveTask, buf []byte, clients *clientSet, totalSize int64) error {
+func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string, out sink, queue *TaskQueue, activeTask *ActiveTask, buf []byte, clients *httpclient.Set, totalSize int64) error {
 	task := activeTask.Task
-	clientsToTry := append([]protocolClient{clients.primary}, clients.fallbacks...)
-
-	var resp *http.Response
-	var err error
-	for idx, protocol := range clientsToTry {
-		req, reqErr := d.newRangeRequest(ctx, rawurl, task)
-		if reqErr != nil {
-			return reqErr
-		}
 
-		resp, err = protocol.client.Do(req)
-		if err != nil {
-			if idx < len(clientsToTry)-1 && shouldFallbackForProtocol(err) {
-				utils.Debug("Protocol %s failed, retrying with fallback transport", protocol.name)
-				continue
-			}
-			return err
-		}
-
-		if resp.StatusCode == http.StatusMisdirectedRequest {
-			_ = resp.Body.Close()
-			if idx < len(clientsToTry)-1 {
-				utils.Debug("Protocol %s returned 421, retrying with fallback transport", protocol.name)
-				continue
-			}
-			return fmt.Errorf("unexpected status: %d", resp.StatusCode)
-		}
-
-		break
+	// Count the connection against the origin's limit shared with other downloads.
+	// Waiting for it (or for the origin's backoff) is not a stall.
+	atomic.StoreInt64(&activeTask.LastActivity, 0)
+	release, err := d.Hosts.Acquire(ctx, rawurl)
+	if err != nil {
+		return err
 	}
+	defer release()
+	now := time.Now()
+	d.activeMu.Lock()
+	activeTask.StartTime = now
+	d.activeMu.Unlock()
+	atomic.StoreInt64(&activeTask.LastActivity, now.UnixNano())
 
-	if resp == nil {
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("request failed without response")
+	resp, err := clients.Do(func() (*http.Request, error) {
+		return d.newRangeRequest(ctx, rawurl, task)
+	})
+	if err != nil {
+		return err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -220,9 +239,12 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 		}
 	}()
 
-	// Handle rate limiting explicitly
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return fmt.Errorf("rate limited (429)")
+	// A throttled origin backs off for every worker and download using it,
+	// and this download uses fewer connections from now on.
+	if throttled := types.ThrottledResponse(resp); throttled != nil {
+		d.scaling.Throttled()
+		d.backOff(rawurl, throttled)
+		return throttled
 	}
 
 	// Validate status code
@@ -230,10 +252,35 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 		// Valid only if we requested the full file
 		// If we wanted a partial range but got the whole file (200), that's an error because we can't handle the full stream at a non-zero offset
 		if task.Offset != 0 || task.Length != totalSize {
-			return fmt.Errorf("server indicated success (200) but ignored range request (expected 206)")
+			if ifRange := types.IfRange(d.ETag, d.LastModified); rawurl == d.URL && ifRange != "" {
+				// The validator no longer matches: the file was replaced.
+				return types.NewError(types.KindFileChanged,
+					fmt.Errorf("remote file changed during download (If-Range %s failed)", ifRange))
+			}
+			return &types.DownloadError{
+				Kind:       types.KindHTTPStatus,
+				StatusCode: resp.StatusCode,
+				Err:        fmt.Errorf("server indicated success (200) but ignored range request (expected 206)"),
+			}
 		}
 	} else if resp.StatusCode != http.StatusPartialContent {
-		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		return types.StatusError(resp.StatusCode)
+	}
+	d.Hosts.Succeeded(rawurl)
+
+	// Range responses may carry a digest of just this part. Such a part is
+	// held back whole until it checks out, so its bytes only reach the file
+	// once they are known to be good.
+	var partHash hash.Hash
+	var part *partBuffer
+	partDigest := integrity.ContentDigest(resp.Header)
+	if partDigest != nil {
+		partHash, _ = integrity.NewHash(partDigest.Algorithm)
+	}
+	if partHash != nil {
+		atomic.StoreInt32(&activeTask.Whole, 1)
+		part = newPartBuffer(task.Length, d.Runtime.GetTempDir())
+		defer part.Close()
 	}
 
 	// Batching state limits lock contention on shared progress counters.
@@ -245,12 +292,22 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 
 	// Helper to flush pending updates to global state.
 	flushUpdates := func() {
-		if pendingBytes > 0 && d.State != nil {
-			// Update Chunk Map (Global Lock)
-			d.State.UpdateChunkStatus(pendingStart, pendingBytes, types.ChunkCompleted)
+		if pendingBytes > 0 {
+			// Let piece verification and the prefix hasher pick up the newly
+			// written range once it has left the write buffer; a stream
+			// hands it to its reader only now.
+			start, length := pendingStart, pendingBytes
+			out.Commit(start, length, func() { d.commitWritten(queue, start, length) })
 
-			// Update Downloaded Counter (Atomic)
-			d.State.Downloaded.Add(pendingBytes)
+			if d.State != nil {
+				// Update Chunk Map (Global Lock)
+				d.State.UpdateChunkStatus(pendingStart, pendingBytes, types.ChunkCompleted)
+
+				// Update Downloaded Counter (Atomic); held-back parts were counted as they streamed
+				if part == nil {
+					d.State.Downloaded.Add(pendingBytes)
+				}
+			}
 
 			pendingBytes = 0
 			pendingStart = -1
@@ -260,27 +317,107 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 	// Ensure we flush whatever we have on exit
 	defer flushUpdates()
 
+	// Helper to write bytes at off and batch them for the next flush.
+	writeOut := func(p []byte, off int64) error {
+		// A stream reader takes bytes in order and only sees committed ones:
+		// hand it what is written, don't run further ahead of it than its
+		// window, and don't count the wait as a stall.
+		if d.Stream != nil && !d.Stream.Fits(off+int64(len(p))) {
+			flushUpdates()
+			atomic.StoreInt64(&activeTask.LastActivity, 0)
+			if err := d.Stream.WaitRoom(ctx, off+int64(len(p))); err != nil {
+				return err
+			}
+			atomic.StoreInt64(&activeTask.LastActivity, time.Now().UnixNano())
+		}
+
+		if _, err := out.WriteAt(p, off); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		atomic.StoreInt64(&activeTask.CurrentOffset, off+int64(len(p)))
+
+		if pendingStart == -1 {
+			pendingStart = off
+		}
+		pendingBytes += int64(len(p))
+		if pendingBytes >= batchSizeThreshold || time.Since(lastUpdate) >= batchTimeThreshold {
+			flushUpdates()
+		}
+		return nil
+	}
+
+	// Helper to drop a held-back part so the worker fetches it again.
+	discardPart := func() {
+		if d.State != nil {
+			d.State.Downloaded.Add(-part.Size())
+		}
+		part.Close()
+	}
+
+	// Helper to check a held-back part once it has been read and write
+	// what the worker still owns of it.
+	finishPart := func() error {
+		partEnd := task.Offset + task.Length
+		if part.Size() < task.Length {
+			discardPart()
+			return fmt.Errorf("range %d-%d: %w", task.Offset, partEnd-1, io.ErrUnexpectedEOF)
+		}
+		if err := integrity.Compare(partDigest, partHash.Sum(nil)); err != nil {
+			discardPart()
+			return fmt.Errorf("range %d-%d: %w", task.Offset, partEnd-1, err)
+		}
+
+		// Work stealing may have cut the range before the part was held
+		// back; the tail is someone else's now.
+		end := min(atomic.LoadInt64(&activeTask.StopAt), partEnd)
+		for off := task.Offset; off < end; {
+			n := min(int64(len(buf)), end-off)
+			if _, err := part.ReadAt(buf[:n], off-task.Offset); err != nil {
+				discardPart()
+				return fmt.Errorf("read part: %w", err)
+			}
+			if err := writeOut(buf[:n], off); err != nil {
+				// What was not written is fetched again.
+				if d.State != nil {
+					d.State.Downloaded.Add(off - partEnd)
+				}
+				return err
+			}
+			off += n
+		}
+		if d.State != nil && end < partEnd {
+			d.State.Downloaded.Add(end - partEnd)
+		}
+		return nil
+	}
+
 	// Read and write at offset.
 	offset := task.Offset
 	for {
 		// Check if we should stop
 		stopAt := atomic.LoadInt64(&activeTask.StopAt)
+		if part != nil {
+			// A held-back part is read whole for its digest.
+			stopAt = task.Offset + task.Length
+		}
 		if offset >= stopAt {
-			// Stealing happened, stop here
-			return nil
+			// Range finished, or stealing happened: stop here
+			break
 		}
 
 		// Fill buffer as much as possible to minimize WriteAt calls.
 
 		// Limit by remaining length to stopAt
-		remaining := stopAt - offset
-		if remaining <= 0 {
-			return nil
-		}
-
-		readSize := int64(len(buf))
-		if readSize > remaining {
-			readSize = remaining
+		readSize := min(int64(len(buf)), stopAt-offset)
+
+		// Throttle: keep reads small enough that a wait stays short, and
+		// don't let the wait count as a stall.
+		if d.Bandwidth.Limited() {
+			readSize = int64(d.Bandwidth.ReadSize(int(readSize)))
+			if err := d.Bandwidth.WaitN(ctx, int(readSize)); err != nil {
+				return err
+			}
+			atomic.StoreInt64(&activeTask.LastActivity, time.Now().UnixNano())
 		}
 
 		readSoFar := 0
@@ -302,41 +439,35 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 		}
 
 		if readSoFar > 0 {
-
-			// Re-check stopAt before writing in case of work stealing.
-			currentStopAt := atomic.LoadInt64(&activeTask.StopAt)
-			if offset+int64(readSoFar) > currentStopAt {
-				readSoFar = int(currentStopAt - offset)
-				if readSoFar <= 0 {
-					return nil // stolen completely
+			if part != nil {
+				partHash.Write(buf[:readSoFar])
+				if _, err := part.Write(buf[:readSoFar]); err != nil {
+					discardPart()
+					return fmt.Errorf("write error: %w", err)
+				}
+				// Count bytes now so speed stays live; discardPart takes them back on mismatch.
+				if d.State != nil {
+					d.State.Downloaded.Add(int64(readSoFar))
+				}
+			} else {
+				// Re-check stopAt before writing in case of work stealing.
+				currentStopAt := atomic.LoadInt64(&activeTask.StopAt)
+				if offset+int64(readSoFar) > currentStopAt {
+					readSoFar = int(currentStopAt - offset)
+					if readSoFar <= 0 {
+						return nil // stolen completely
+					}
+				}
+				if err := writeOut(buf[:readSoFar], offset); err != nil {
+					return err
 				}
-			}
-
-			_, writeErr := file.WriteAt(buf[:readSoFar], offset)
-			if writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
 			}
 
 			now := time.Now()
-			rangeStart := offset // Start of this write
 			offset += int64(readSoFar)
-			atomic.StoreInt64(&activeTask.CurrentOffset, offset)
 			atomic.AddInt64(&activeTask.WindowBytes, int64(readSoFar))
 			atomic.StoreInt64(&activeTask.LastActivity, now.UnixNano())
 
-			// Calculate effective contribution (clamping to StopAt is done above via readSoFar truncation)
-			// So readSoFar is exactly what we wrote and what we "own"
-
-			if pendingStart == -1 {
-				pendingStart = rangeStart
-			}
-			pendingBytes += int64(readSoFar)
-
-			// Check thresholds
-			if pendingBytes >= batchSizeThreshold || now.Sub(lastUpdate) >= batchTimeThreshold {
-				flushUpdates()
-			}
-
 			// Update EMA speed using a sliding window (2 second window).
 			// This relies on WindowBytes updated atomically above, independent of batching.
 			windowElapsed := now.Sub(activeTask.WindowStart).Seconds()
@@ -361,62 +492,56 @@ func (d *ConcurrentDownloader) downloadTask(ctx context.Context, rawurl string,
 			break
 		}
 		if readErr != nil {
+			if part != nil {
+				discardPart()
+			}
 			return fmt.Errorf("read error: %w", readErr)
 		}
 	}
 
+	if part != nil {
+		return finishPart()
+	}
 	return nil
 }
 
+// backOff starts the origin's shared backoff and announces it, once per
+// backoff rather than once per worker that was turned away.
+func (d *ConcurrentDownloader) backOff(rawurl string, throttled *types.ThrottledError) {
+	until, extended := d.Hosts.Throttle(rawurl, throttled.RetryAfter)
+	if !extended {
+		return
+	}
+	utils.Debug("Host %s throttled (%d), backing off until %s", connlimit.Origin(rawurl), throttled.StatusCode, until.Format(time.TimeOnly))
+	if d.ProgressChan != nil {
+		d.ProgressChan <- events.DownloadBackoffMsg{
+			DownloadID: d.ID,
+			Filename:   filepath.Base(d.DestPath),
+			Host:       connlimit.Origin(rawurl),
+			StatusCode: throttled.StatusCode,
+			Until:      until,
+		}
+	}
+}
+
 func (d *ConcurrentDownloader) newRangeRequest(ctx context.Context, rawurl string, task types.Task) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply custom headers first (from browser extension: cookies, auth, referer, etc.).
-	for key, val := range d.Headers {
-		// Skip Range header - we set it ourselves for parallel downloads
-		if key != "Range" {
-			req.Header.Set(key, val)
-		}
-	}
-
-	// Set User-Agent from config only if not provided in custom headers.
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
-	}
+	// Custom headers from the browser extension (cookies, auth, referer, etc.) and User-Agent.
+	httpclient.ApplyHeaders(req, d.Headers, d.Runtime)
 	// Range header is always set for partial downloads (overrides any browser Range header).
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", task.Offset, task.Offset+task.Length-1))
-
-	return req, nil
-}
-
-func shouldFallbackForProtocol(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	var protoErr *http.ProtocolError
-	if errors.As(err, &protoErr) {
-		return true
-	}
-
-	var quicErr *quic.TransportError
-	if errors.As(err, &quicErr) {
-		return true
-	}
-
-	var netErr net.Error
-	if errors.As(err, &netErr) {
-		lower := strings.ToLower(err.Error())
-		if strings.Contains(lower, "alpn") || strings.Contains(lower, "http2") || strings.Contains(lower, "http/2") || strings.Contains(lower, "http3") || strings.Contains(lower, "quic") || strings.Contains(lower, "protocol") {
-			return true
+	// Only the primary URL is validated: mirrors compute their own ETags.
+	if rawurl == d.URL {
+		if ifRange := types.IfRange(d.ETag, d.LastModified); ifRange != "" {
+			req.Header.Set("If-Range", ifRange)
 		}
 	}
 
-	lower := strings.ToLower(err.Error())
-	return strings.Contains(lower, "alpn") || strings.Contains(lower, "http2") || strings.Contains(lower, "http/2") || strings.Contains(lower, "http3") || strings.Contains(lower, "quic") || strings.Contains(lower, "protocol")
+	return req, nil
 }
 
 // StealWork tries to split an active task from a busy worker
@@ -433,6 +558,9 @@ func (d *ConcurrentDownloader) StealWork(queue *TaskQueue) bool {
 	// Find the worker with the MOST remaining work
 	// Only consider workers with enough remaining work to split
 	for id, active := range d.activeTasks {
+		if !active.Splittable() {
+			continue
+		}
 		remaining := active.RemainingBytes()
 		if remaining > types.MinChunk && remaining > maxRemaining {
 			maxRemaining = remaining
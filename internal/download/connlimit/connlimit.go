@@ -0,0 +1,269 @@
+// Package connlimit shares connection budgets between downloads: a
+// process-wide total (Allocator) and a per-origin limit (Hosts).
+package connlimit
+
+import (
+	"context"
+	"sync"
+)
+
+// Allocator divides a budget of connections between the downloads holding a
+// Lease. Every lease gets one connection first, in the order they were
+// acquired; the rest of the budget is spread evenly up to what each lease
+// wants. Grants are recomputed whenever a lease is acquired or released, or
+// the limit changes, so running downloads grow and shrink as others come and go.
+//
+// A grant is what a lease may use; a connection is only counted once the
+// lease starts it with TryStart. A lease whose grant grew may therefore have
+// to wait for another lease to stop connections it no longer owns, which keeps
+// the number of open connections under the limit while grants move.
+//
+// All methods are safe to call on a nil *Allocator, which means "no limit".
+type Allocator struct {
+	mu      sync.Mutex
+	limit   int
+	leases  []*Lease      // Acquisition order
+	changed chan struct{} // Closed and replaced whenever a connection may have become available
+}
+
+// Lease is one download's share of the budget.
+type Lease struct {
+	a       *Allocator
+	want    int
+	granted int // Guarded by a.mu
+	held    int // Connections started and not yet stopped; guarded by a.mu
+}
+
+// New returns an allocator for limit connections; limit <= 0 means unlimited.
+func New(limit int) *Allocator {
+	return &Allocator{limit: limit, changed: make(chan struct{})}
+}
+
+// SetLimit changes the budget and rebalances existing leases.
+func (a *Allocator) SetLimit(limit int) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limit == limit {
+		return
+	}
+	a.limit = limit
+	a.rebalance()
+}
+
+// Limit returns the current budget (0 = unlimited).
+func (a *Allocator) Limit() int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.limit
+}
+
+// InUse returns the number of connections currently started.
+func (a *Allocator) InUse() int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.held()
+}
+
+// Acquire registers a download that would like want connections.
+func (a *Allocator) Acquire(want int) *Lease {
+	if want < 1 {
+		want = 1
+	}
+	l := &Lease{a: a, want: want}
+	if a == nil {
+		l.granted = want
+		return l
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.leases = append(a.leases, l)
+	a.rebalance()
+	return l
+}
+
+// SetWant changes how many connections the lease would like and rebalances.
+func (l *Lease) SetWant(want int) {
+	if want < 1 {
+		want = 1
+	}
+	a := l.a
+	if a == nil {
+		l.want = want
+		l.granted = want
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if l.want == want {
+		return
+	}
+	l.want = want
+	a.rebalance()
+}
+
+// Granted returns how many connections the lease may use right now.
+func (l *Lease) Granted() int {
+	if l.a == nil {
+		return l.granted
+	}
+	l.a.mu.Lock()
+	defer l.a.mu.Unlock()
+	return l.granted
+}
+
+// Wait blocks until TryStart could succeed. Another lease may still take the
+// connection first, so callers retry when TryStart fails.
+func (l *Lease) Wait(ctx context.Context) error {
+	a := l.a
+	if a == nil {
+		return nil
+	}
+
+	for {
+		a.mu.Lock()
+		ok := l.canStart()
+		changed := a.changed
+		a.mu.Unlock()
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryStart counts one more connection against the lease if its grant and the
+// global limit both allow it.
+func (l *Lease) TryStart() bool {
+	a := l.a
+	if a == nil {
+		if l.held < l.granted {
+			l.held++
+			return true
+		}
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !l.canStart() {
+		return false
+	}
+	l.held++
+	return true
+}
+
+// Stop gives back a connection counted by TryStart.
+func (l *Lease) Stop() {
+	a := l.a
+	if a == nil {
+		if l.held > 0 {
+			l.held--
+		}
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if l.held > 0 {
+		l.held--
+		a.notify()
+	}
+}
+
+// Release returns the lease's connections to the budget.
+func (l *Lease) Release() {
+	a := l.a
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, other := range a.leases {
+		if other == l {
+			a.leases = append(a.leases[:i], a.leases[i+1:]...)
+			l.granted = 0
+			l.held = 0
+			a.rebalance()
+			return
+		}
+	}
+}
+
+// canStart reports whether one more connection fits. Callers hold a.mu.
+func (l *Lease) canStart() bool {
+	if l.held >= l.granted {
+		return false
+	}
+	return l.a.limit <= 0 || l.a.held() < l.a.limit
+}
+
+// held sums the started connections of every lease. Callers hold a.mu.
+func (a *Allocator) held() int {
+	total := 0
+	for _, l := range a.leases {
+		total += l.held
+	}
+	return total
+}
+
+// notify wakes every Wait. Callers hold a.mu.
+func (a *Allocator) notify() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// rebalance recomputes every grant. Callers hold a.mu.
+func (a *Allocator) rebalance() {
+	if a.limit <= 0 {
+		for _, l := range a.leases {
+			l.granted = l.want
+		}
+		a.notify()
+		return
+	}
+
+	remaining := a.limit
+	for _, l := range a.leases {
+		l.granted = 0
+		if remaining > 0 {
+			l.granted = 1
+			remaining--
+		}
+	}
+	// Hand out the rest one at a time so shares stay even.
+	for remaining > 0 {
+		progressed := false
+		for _, l := range a.leases {
+			if remaining == 0 {
+				break
+			}
+			if l.granted > 0 && l.granted < l.want {
+				l.granted++
+				remaining--
+				progressed = true
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+	a.notify()
+}
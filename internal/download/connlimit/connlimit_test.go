@@ -0,0 +1,143 @@
+package connlimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func grants(leases []*Lease) []int {
+	got := make([]int, len(leases))
+	for i, l := range leases {
+		got[i] = l.Granted()
+	}
+	return got
+}
+
+func TestAllocatorGrants(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		wants []int
+		want  []int
+	}{
+		{"unlimited", 0, []int{8, 3}, []int{8, 3}},
+		{"enough for all", 20, []int{8, 4}, []int{8, 4}},
+		{"even split", 8, []int{8, 8}, []int{4, 4}},
+		{"odd split favours earlier", 7, []int{8, 8}, []int{4, 3}},
+		{"small want leaves the rest", 8, []int{2, 8, 8}, []int{2, 3, 3}},
+		{"one each first", 2, []int{4, 4, 4}, []int{1, 1, 0}},
+		{"want below one", 4, []int{0, -3}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(tt.limit)
+			var leases []*Lease
+			for _, w := range tt.wants {
+				leases = append(leases, a.Acquire(w))
+			}
+			if got := grants(leases); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grants = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocatorRebalance(t *testing.T) {
+	a := New(6)
+	first := a.Acquire(6)
+	second := a.Acquire(6)
+	if got := grants([]*Lease{first, second}); !reflect.DeepEqual(got, []int{3, 3}) {
+		t.Fatalf("grants = %v, want [3 3]", got)
+	}
+
+	second.SetWant(1)
+	if got := grants([]*Lease{first, second}); !reflect.DeepEqual(got, []int{5, 1}) {
+		t.Errorf("after SetWant grants = %v, want [5 1]", got)
+	}
+	a.SetLimit(3)
+	if got := grants([]*Lease{first, second}); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Errorf("after SetLimit grants = %v, want [2 1]", got)
+	}
+	second.Release()
+	if got := first.Granted(); got != 3 {
+		t.Errorf("after Release granted = %d, want 3", got)
+	}
+	second.Release() // Releasing twice is harmless
+	if got := first.Granted(); got != 3 {
+		t.Errorf("after second Release granted = %d, want 3", got)
+	}
+}
+
+func TestTryStartKeepsUnderLimit(t *testing.T) {
+	a := New(4)
+	first := a.Acquire(4)
+	for range 4 {
+		if !first.TryStart() {
+			t.Fatal("TryStart failed within the grant")
+		}
+	}
+	if first.TryStart() {
+		t.Error("TryStart went beyond the grant")
+	}
+
+	// The new lease is granted 2, but first still holds all 4 connections.
+	second := a.Acquire(4)
+	if second.Granted() != 2 || second.TryStart() {
+		t.Fatalf("second lease granted %d and started before first stopped", second.Granted())
+	}
+	if got := a.InUse(); got != 4 {
+		t.Errorf("InUse = %d, want 4", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- second.Wait(ctx) }()
+	first.Stop()
+	if err := <-done; err != nil {
+		t.Fatalf("Wait = %v after a connection stopped", err)
+	}
+	if !second.TryStart() {
+		t.Error("TryStart failed after Wait")
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	a := New(1)
+	first := a.Acquire(1)
+	first.TryStart()
+	second := a.Acquire(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestNilAllocator(t *testing.T) {
+	var a *Allocator
+	a.SetLimit(3)
+	if a.Limit() != 0 || a.InUse() != 0 {
+		t.Error("nil allocator reports a limit or connections")
+	}
+
+	l := a.Acquire(2)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !l.TryStart() || !l.TryStart() || l.TryStart() {
+		t.Error("nil allocator lease does not honour its own want")
+	}
+	l.Stop()
+	if !l.TryStart() {
+		t.Error("TryStart failed after Stop")
+	}
+	l.SetWant(5)
+	if l.Granted() != 5 {
+		t.Errorf("Granted = %d after SetWant(5)", l.Granted())
+	}
+	l.Release()
+}
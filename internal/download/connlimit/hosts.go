@@ -0,0 +1,284 @@
+package connlimit
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backoff delays for throttled origins without a Retry-After hint; the delay
+// doubles with each throttle until a request succeeds.
+const (
+	BackoffBase = 1 * time.Second
+	BackoffMax  = 5 * time.Minute
+)
+
+// Hosts limits how many connections all downloads together open to one
+// origin (scheme, host and port), so mirrors and downloads that share a
+// server also share its allowance.
+//
+// An origin that throttles (429/503) backs off for everyone: no new
+// connection is opened to it until the backoff ends, and then only half as
+// many as before are allowed, growing by one with each successful request.
+//
+// All methods are safe to call on a nil *Hosts, which means "no limit".
+type Hosts struct {
+	mu      sync.Mutex
+	limit   int
+	inUse   map[string]int
+	backoff map[string]*backoff
+	changed chan struct{} // Closed and replaced whenever a slot may have freed up
+}
+
+// backoff is the recovery state of one throttled origin.
+type backoff struct {
+	until   time.Time
+	strikes int // Throttles since the last successful request
+	cap     int // Connections allowed while recovering
+	before  int // Connections open when first throttled; recovery ends there
+}
+
+// NewHosts returns a limiter for limit connections per origin; limit <= 0
+// means unlimited.
+func NewHosts(limit int) *Hosts {
+	return &Hosts{
+		limit:   limit,
+		inUse:   make(map[string]int),
+		backoff: make(map[string]*backoff),
+		changed: make(chan struct{}),
+	}
+}
+
+// SetLimit changes the per-origin limit. Connections already open are kept;
+// a lower limit applies as they finish.
+func (h *Hosts) SetLimit(limit int) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.limit == limit {
+		return
+	}
+	h.limit = limit
+	h.notify()
+}
+
+// Acquire blocks until rawurl's origin has room for one more connection and
+// is not backing off, then counts the connection. The returned release gives
+// it back and may be called more than once.
+func (h *Hosts) Acquire(ctx context.Context, rawurl string) (release func(), err error) {
+	if h == nil {
+		return func() {}, nil
+	}
+
+	origin := Origin(rawurl)
+	for {
+		h.mu.Lock()
+		wait := h.backoffLeft(origin, time.Now())
+		if limit := h.limitFor(origin); wait == 0 && (limit <= 0 || h.inUse[origin] < limit) {
+			h.inUse[origin]++
+			h.mu.Unlock()
+
+			var once sync.Once
+			return func() { once.Do(func() { h.release(origin) }) }, nil
+		}
+		changed := h.changed
+		h.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-changed:
+		case <-expired:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Throttle starts or extends a backoff for rawurl's origin after it answered
+// 429 or 503. retryAfter is the server's hint; without one the delay doubles
+// with every throttle since the last success. It returns when the backoff
+// ends and whether this call moved that time later.
+func (h *Hosts) Throttle(rawurl string, retryAfter time.Duration) (until time.Time, extended bool) {
+	if h == nil {
+		return time.Time{}, false
+	}
+
+	origin := Origin(rawurl)
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.backoff[origin]
+	if b == nil {
+		// The throttled request's own connection is still counted.
+		b = &backoff{before: max(1, h.inUse[origin])}
+		h.backoff[origin] = b
+	}
+	if !now.Before(b.until) {
+		// Halve once per backoff, not once per worker that got the same answer.
+		if b.cap == 0 {
+			b.cap = b.before
+		}
+		b.cap = max(1, b.cap/2)
+		b.strikes++
+	}
+
+	delay := retryAfter
+	if delay <= 0 {
+		delay = BackoffMax
+		if b.strikes <= 16 {
+			delay = min(BackoffBase<<(b.strikes-1), BackoffMax)
+		}
+	}
+	if next := now.Add(delay); next.After(b.until) {
+		b.until = next
+		extended = true
+	}
+	h.notify()
+	return b.until, extended
+}
+
+// Succeeded records a successful request to rawurl's origin, letting a
+// recovering origin have one more connection.
+func (h *Hosts) Succeeded(rawurl string) {
+	if h == nil {
+		return
+	}
+
+	origin := Origin(rawurl)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.backoff[origin]
+	if b == nil || time.Now().Before(b.until) {
+		return
+	}
+	b.strikes = 0
+	b.cap++
+	if b.cap >= b.before || (h.limit > 0 && b.cap >= h.limit) {
+		delete(h.backoff, origin)
+	}
+	h.notify()
+}
+
+// BackoffUntil returns the latest time any of the URLs' origins is backing
+// off until, or the zero time when none is.
+func (h *Hosts) BackoffUntil(urls ...string) time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	var until time.Time
+	for _, u := range urls {
+		if b := h.backoff[Origin(u)]; b != nil && b.until.After(now) && b.until.After(until) {
+			until = b.until
+		}
+	}
+	return until
+}
+
+// backoffLeft returns how long origin still backs off. Callers hold h.mu.
+func (h *Hosts) backoffLeft(origin string, now time.Time) time.Duration {
+	if b := h.backoff[origin]; b != nil && now.Before(b.until) {
+		return b.until.Sub(now)
+	}
+	return 0
+}
+
+// limitFor returns origin's connection limit, lowered while it recovers from
+// a backoff (0 = unlimited). Callers hold h.mu.
+func (h *Hosts) limitFor(origin string) int {
+	limit := h.limit
+	if b := h.backoff[origin]; b != nil && b.cap > 0 && (limit <= 0 || b.cap < limit) {
+		limit = b.cap
+	}
+	return limit
+}
+
+// Usage returns the connections open to the origin of each URL, across all
+// downloads. Origins without open connections are omitted.
+func (h *Hosts) Usage(urls ...string) map[string]int {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var usage map[string]int
+	for _, u := range urls {
+		origin := Origin(u)
+		if n := h.inUse[origin]; n > 0 {
+			if usage == nil {
+				usage = make(map[string]int)
+			}
+			usage[origin] = n
+		}
+	}
+	return usage
+}
+
+func (h *Hosts) release(origin string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.inUse[origin] <= 1 {
+		delete(h.inUse, origin)
+	} else {
+		h.inUse[origin]--
+	}
+	h.notify()
+}
+
+// notify wakes every Acquire. Callers hold h.mu.
+func (h *Hosts) notify() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// Origin returns the scheme://host:port key of rawurl, filling in the
+// scheme's default port. Unparseable URLs are returned unchanged.
+func Origin(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+	host := strings.ToLower(u.Hostname())
+	if port == "" {
+		return scheme + "://" + host
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
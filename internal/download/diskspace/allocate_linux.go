@@ -0,0 +1,24 @@
+//go:build linux
+
+package diskspace
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Allocate extends f to size bytes and reserves the blocks with fallocate,
+// so a full disk fails here rather than at some later write. Filesystems
+// without fallocate get a sparse file, as with Truncate.
+func Allocate(f *os.File, size int64) error {
+	err := unix.Fallocate(int(f.Fd()), 0, 0, size)
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOSYS) {
+		return f.Truncate(size)
+	}
+	if err != nil {
+		return &os.PathError{Op: "fallocate", Path: f.Name(), Err: err}
+	}
+	return nil
+}
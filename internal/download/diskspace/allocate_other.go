@@ -0,0 +1,11 @@
+//go:build !linux
+
+package diskspace
+
+import "os"
+
+// Allocate extends f to size bytes. Only Linux reserves the blocks up
+// front; elsewhere this is Truncate.
+func Allocate(f *os.File, size int64) error {
+	return f.Truncate(size)
+}
@@ -0,0 +1,48 @@
+// Package diskspace checks free space on a download's destination filesystem
+// and reserves the space a file needs before it is written.
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// Check reports a KindDiskFull error if the filesystem holding path cannot
+// take a file of size bytes and still keep reserve bytes free. Blocks
+// already allocated to path, e.g. by an earlier preallocation, count as
+// available. An unknown size or free space passes.
+func Check(path string, size, reserve int64) error {
+	need := size - Allocated(path)
+	if size <= 0 || need <= 0 {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	free, err := Free(dir)
+	if err != nil {
+		utils.Debug("Free space of %s unknown: %v", dir, err)
+		return nil
+	}
+	if int64(free)-reserve >= need {
+		return nil
+	}
+	msg := fmt.Sprintf("not enough disk space in %s: need %s, %s free",
+		dir, utils.ConvertBytesToHumanReadable(need), utils.ConvertBytesToHumanReadable(int64(free)))
+	if reserve > 0 {
+		msg += fmt.Sprintf(" (keeping %s in reserve)", utils.ConvertBytesToHumanReadable(reserve))
+	}
+	return types.NewError(types.KindDiskFull, errors.New(msg))
+}
+
+// fileSize returns the size of path, or 0 if it does not exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package diskspace
+
+import "errors"
+
+// Free is not implemented on this platform, so Check lets every download through.
+func Free(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// Allocated returns the size of path.
+func Allocated(path string) int64 {
+	return fileSize(path)
+}
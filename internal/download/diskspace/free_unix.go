@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd
+
+package diskspace
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Free returns the bytes an unprivileged user may still write to the
+// filesystem holding dir.
+func Free(dir string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// Allocated returns the bytes of disk actually used by path, which is less
+// than its size for a sparse file. It returns 0 if path does not exist.
+func Allocated(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Blocks * 512
+	}
+	return info.Size()
+}
+
+// SameFilesystem reports whether dirs a and b are on the same filesystem,
+// so moving a file between them is a rename. It reports false if either
+// cannot be inspected.
+func SameFilesystem(a, b string) bool {
+	var sa, sb syscall.Stat_t
+	if syscall.Stat(a, &sa) != nil || syscall.Stat(b, &sb) != nil {
+		return false
+	}
+	return sa.Dev == sb.Dev
+}
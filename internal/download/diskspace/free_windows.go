@@ -0,0 +1,25 @@
+//go:build windows
+
+package diskspace
+
+import "golang.org/x/sys/windows"
+
+// Free returns the bytes the current user may still write to the volume
+// holding dir.
+func Free(dir string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
+
+// Allocated returns the bytes of disk used by path. NTFS allocates the
+// whole length of a non-sparse file, so this is its size.
+func Allocated(path string) int64 {
+	return fileSize(path)
+}
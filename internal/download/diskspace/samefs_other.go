@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd
+
+package diskspace
+
+import "path/filepath"
+
+// SameFilesystem reports whether dirs a and b are on the same volume, so
+// moving a file between them is a rename.
+func SameFilesystem(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return filepath.VolumeName(absA) == filepath.VolumeName(absB)
+}
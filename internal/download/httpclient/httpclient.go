@@ -0,0 +1,290 @@
+// Package httpclient builds the HTTP clients shared by the downloaders so
+// that proxy selection, protocol preference, redirect handling and custom
+// header forwarding behave the same on every download path.
+package httpclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// maxRedirects matches the default limit of net/http.
+const maxRedirects = 10
+
+// Client is an http.Client labelled with the protocol it speaks.
+type Client struct {
+	Name string
+	*http.Client
+}
+
+// Set is a primary client plus fallbacks, in protocol preference order.
+type Set struct {
+	Primary   Client
+	Fallbacks []Client
+
+	http3Transport *http3.Transport
+}
+
+// All returns the primary client followed by its fallbacks.
+func (s *Set) All() []Client {
+	return append([]Client{s.Primary}, s.Fallbacks...)
+}
+
+// Close releases the HTTP/3 transport, if one was created.
+func (s *Set) Close() {
+	if s == nil || s.http3Transport == nil {
+		return
+	}
+
+	if err := s.http3Transport.Close(); err != nil {
+		utils.Debug("Error closing HTTP/3 transport: %v", err)
+	}
+}
+
+// Do sends the request built by newReq, moving to the next client in the set
+// when a protocol fails to negotiate or the server answers 421.
+func (s *Set) Do(newReq func() (*http.Request, error)) (*http.Response, error) {
+	clients := s.All()
+
+	var resp *http.Response
+	var err error
+	for idx, c := range clients {
+		req, reqErr := newReq()
+		if reqErr != nil {
+			return nil, reqErr
+		}
+
+		resp, err = c.Do(req)
+		if err != nil {
+			if idx < len(clients)-1 && ShouldFallback(err) {
+				utils.Debug("Protocol %s failed, retrying with fallback transport", c.Name)
+				continue
+			}
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusMisdirectedRequest {
+			_ = resp.Body.Close()
+			if idx < len(clients)-1 {
+				utils.Debug("Protocol %s returned 421, retrying with fallback transport", c.Name)
+				continue
+			}
+			return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		}
+
+		return resp, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("request failed without response")
+}
+
+// NewSet creates clients for up to maxConns connections per host, ordered by
+// the runtime protocol preference and what the server supports.
+func NewSet(runtime *types.RuntimeConfig, maxConns int, supportsHTTP2 bool, supportsHTTP3 bool) *Set {
+	proxyFunc := ProxyFunc(runtime)
+
+	buildHTTPTransport := func(forceHTTP2 bool) *http.Transport {
+		transport := &http.Transport{
+			// Connection pooling
+			MaxIdleConns:        types.DefaultMaxIdleConns,
+			MaxIdleConnsPerHost: maxConns + 2, // Slightly more than max to handle bursts
+			MaxConnsPerHost:     maxConns,
+			Proxy:               proxyFunc,
+
+			// Timeouts to prevent hung connections
+			IdleConnTimeout:       types.DefaultIdleConnTimeout,
+			TLSHandshakeTimeout:   types.DefaultTLSHandshakeTimeout,
+			ResponseHeaderTimeout: types.DefaultResponseHeaderTimeout,
+			ExpectContinueTimeout: types.DefaultExpectContinueTimeout,
+
+			// Performance tuning
+			DisableCompression: true, // Files are usually already compressed
+			ForceAttemptHTTP2:  forceHTTP2,
+
+			// Dial settings for TCP reliability
+			DialContext: (&net.Dialer{
+				Timeout:   types.DialTimeout,
+				KeepAlive: types.KeepAliveDuration,
+			}).DialContext,
+		}
+
+		if !forceHTTP2 {
+			transport.TLSNextProto = make(map[string]func(authority string, c *tls.Conn) http.RoundTripper)
+		}
+
+		return transport
+	}
+
+	newHTTPClient := func(transport http.RoundTripper) *http.Client {
+		return &http.Client{
+			Transport:     transport,
+			CheckRedirect: PreserveHeadersOnRedirect,
+		}
+	}
+
+	protocol := runtime.GetProtocolPreference()
+	if runtime != nil && runtime.ProxyURL != "" && supportsHTTP3 {
+		utils.Debug("HTTP/3 disabled because proxy is configured")
+		supportsHTTP3 = false
+	}
+
+	http1Client := Client{Name: types.ProtocolHTTP1, Client: newHTTPClient(buildHTTPTransport(false))}
+	http2Client := Client{Name: types.ProtocolHTTP2, Client: newHTTPClient(buildHTTPTransport(true))}
+
+	var http3Client Client
+	var http3Transport *http3.Transport
+	if supportsHTTP3 {
+		http3Transport = &http3.Transport{
+			TLSClientConfig: &tls.Config{
+				NextProtos: []string{"h3"},
+			},
+			QUICConfig: &quic.Config{
+				HandshakeIdleTimeout: types.DefaultTLSHandshakeTimeout,
+				MaxIdleTimeout:       types.DefaultIdleConnTimeout,
+				KeepAlivePeriod:      types.KeepAliveDuration,
+			},
+		}
+		http3Client = Client{Name: types.ProtocolHTTP3, Client: newHTTPClient(http3Transport)}
+	}
+
+	formatNames := func(primary Client, fallbacks []Client) string {
+		if len(fallbacks) == 0 {
+			return primary.Name
+		}
+		names := make([]string, 0, 1+len(fallbacks))
+		names = append(names, primary.Name)
+		for _, fallback := range fallbacks {
+			names = append(names, fallback.Name)
+		}
+		return strings.Join(names, " -> ")
+	}
+
+	makeSet := func(primary Client, fallbacks ...Client) *Set {
+		set := &Set{Primary: primary, Fallbacks: fallbacks}
+		if http3Transport != nil {
+			if primary.Name == types.ProtocolHTTP3 {
+				set.http3Transport = http3Transport
+			} else {
+				for _, fallback := range fallbacks {
+					if fallback.Name == types.ProtocolHTTP3 {
+						set.http3Transport = http3Transport
+						break
+					}
+				}
+			}
+		}
+		utils.Debug("Transport selection: pref=%s supports[h2=%t h3=%t] chain=%s", protocol, supportsHTTP2, supportsHTTP3, formatNames(primary, fallbacks))
+		return set
+	}
+
+	switch protocol {
+	case types.ProtocolHTTP1:
+		return makeSet(http1Client)
+	case types.ProtocolHTTP2:
+		if supportsHTTP2 {
+			return makeSet(http2Client, http1Client)
+		}
+		return makeSet(http1Client)
+	case types.ProtocolHTTP3:
+		if supportsHTTP3 {
+			return makeSet(http3Client, http1Client)
+		}
+		return makeSet(http1Client)
+	default:
+		if supportsHTTP3 {
+			if supportsHTTP2 {
+				return makeSet(http3Client, http1Client, http2Client)
+			}
+			return makeSet(http3Client, http1Client)
+		}
+		if supportsHTTP2 {
+			return makeSet(http1Client, http2Client)
+		}
+		return makeSet(http1Client)
+	}
+}
+
+// ProxyFunc returns the proxy selector for runtime: the configured proxy URL,
+// or the environment when none (or an invalid one) is set.
+func ProxyFunc(runtime *types.RuntimeConfig) func(*http.Request) (*url.URL, error) {
+	// Keep proxy handling explicit to avoid surprising env interactions.
+	if runtime == nil || runtime.ProxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	parsedURL, err := url.Parse(runtime.ProxyURL)
+	if err != nil {
+		utils.Debug("Invalid proxy URL %s: %v", runtime.ProxyURL, err)
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(parsedURL)
+}
+
+// PreserveHeadersOnRedirect is an http.Client CheckRedirect that carries the
+// original request headers across redirects for authenticated downloads.
+// These headers were explicitly provided by the caller and should remain
+// intact. Range is skipped because each request sets its own.
+func PreserveHeadersOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	// Copy headers from original request to redirect request
+	if len(via) > 0 {
+		for key, vals := range via[0].Header {
+			if key == "Range" {
+				continue
+			}
+			req.Header[key] = vals
+		}
+	}
+	return nil
+}
+
+// ApplyHeaders sets custom headers (from the browser extension: cookies,
+// auth, referer, etc.) on req, then the configured User-Agent unless one was
+// provided. Range is skipped; callers set it themselves.
+func ApplyHeaders(req *http.Request, headers map[string]string, runtime *types.RuntimeConfig) {
+	for key, val := range headers {
+		if !strings.EqualFold(key, "Range") {
+			req.Header.Set(key, val)
+		}
+	}
+
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", runtime.GetUserAgent())
+	}
+}
+
+// ShouldFallback reports whether err looks like a protocol negotiation
+// failure that another transport may not hit.
+func ShouldFallback(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var protoErr *http.ProtocolError
+	if errors.As(err, &protoErr) {
+		return true
+	}
+
+	var quicErr *quic.TransportError
+	if errors.As(err, &quicErr) {
+		return true
+	}
+
+	lower := strings.ToLower(err.Error())
+	return strings.Contains(lower, "alpn") || strings.Contains(lower, "http2") || strings.Contains(lower, "http/2") || strings.Contains(lower, "http3") || strings.Contains(lower, "quic") || strings.Contains(lower, "protocol")
+}
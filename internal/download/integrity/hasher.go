@@ -0,0 +1,254 @@
+package integrity
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// hashReadSize is the read size used when catching up on written bytes.
+const hashReadSize = 1 * types.MB
+
+// PrefixHasher incrementally hashes the contiguous prefix of a file while
+// workers write ranges out of order. Bytes are hashed shortly after they are
+// written (normally from the page cache), so verifying the digest at
+// completion costs almost no extra I/O.
+//
+// All methods are safe to call on a nil *PrefixHasher, which means
+// "no checksum configured".
+type PrefixHasher struct {
+	expected *types.Checksum
+
+	mu    sync.Mutex
+	spans []span // Written ranges, sorted and merged
+
+	hashMu sync.Mutex // Protects h and hashed
+	h      hash.Hash
+	hashed int64 // Bytes [0, hashed) have been fed into h
+
+	wake chan struct{}
+}
+
+// NewPrefixHasher returns a hasher for the expected checksum, or nil when
+// there is nothing to verify.
+func NewPrefixHasher(expected *types.Checksum) (*PrefixHasher, error) {
+	if expected == nil {
+		return nil, nil
+	}
+	h, err := NewHash(expected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &PrefixHasher{
+		expected: expected,
+		h:        h,
+		wake:     make(chan struct{}, 1),
+	}, nil
+}
+
+// Restore loads a hash state saved by Snapshot. The first hashed bytes of the
+// file are assumed to be unchanged since the snapshot was taken.
+func (p *PrefixHasher) Restore(state []byte, hashed int64) error {
+	if p == nil || len(state) == 0 || hashed <= 0 {
+		return nil
+	}
+
+	p.hashMu.Lock()
+	defer p.hashMu.Unlock()
+
+	u, ok := p.h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%s hash state cannot be restored", p.expected.Algorithm)
+	}
+	if err := u.UnmarshalBinary(state); err != nil {
+		// Start over rather than trust a state from another algorithm.
+		p.h.Reset()
+		return fmt.Errorf("failed to restore hash state: %w", err)
+	}
+	p.hashed = hashed
+
+	p.mu.Lock()
+	p.spans = insertSpan(p.spans, span{0, hashed})
+	p.mu.Unlock()
+
+	utils.Debug("Restored %s hash state at offset %d", p.expected.Algorithm, hashed)
+	return nil
+}
+
+// Snapshot returns the serialized hash state and how many bytes it covers.
+// Call it only after Run has returned.
+func (p *PrefixHasher) Snapshot() ([]byte, int64) {
+	if p == nil {
+		return nil, 0
+	}
+
+	p.hashMu.Lock()
+	defer p.hashMu.Unlock()
+
+	m, ok := p.h.(encoding.BinaryMarshaler)
+	if !ok || p.hashed == 0 {
+		return nil, 0
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		utils.Debug("Failed to snapshot hash state: %v", err)
+		return nil, 0
+	}
+	return state, p.hashed
+}
+
+// MarkWritten records that [offset, offset+length) has been written to disk.
+func (p *PrefixHasher) MarkWritten(offset, length int64) {
+	if p == nil || length <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	p.spans = insertSpan(p.spans, span{offset, offset + length})
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Write hashes bytes appended at the current prefix end. It is used by
+// sequential writers that already hold the data in memory.
+func (p *PrefixHasher) Write(b []byte) {
+	if p == nil || len(b) == 0 {
+		return
+	}
+
+	p.hashMu.Lock()
+	start := p.hashed
+	_, _ = p.h.Write(b)
+	p.hashed += int64(len(b))
+	p.hashMu.Unlock()
+
+	p.mu.Lock()
+	p.spans = insertSpan(p.spans, span{start, start + int64(len(b))})
+	p.mu.Unlock()
+}
+
+// CatchUp hashes the file prefix up to end from r, for bytes that were on
+// disk before hashing started (such as a partial without a saved state).
+func (p *PrefixHasher) CatchUp(r io.ReaderAt, end int64) error {
+	if p == nil {
+		return nil
+	}
+	if err := p.catchUp(context.Background(), r, end); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	p.mu.Lock()
+	p.spans = insertSpan(p.spans, span{0, end})
+	p.mu.Unlock()
+	return nil
+}
+
+// Hashed returns how many leading bytes have been hashed so far.
+func (p *PrefixHasher) Hashed() int64 {
+	if p == nil {
+		return 0
+	}
+	p.hashMu.Lock()
+	defer p.hashMu.Unlock()
+	return p.hashed
+}
+
+// Run hashes newly contiguous bytes from r until ctx is cancelled.
+func (p *PrefixHasher) Run(ctx context.Context, r io.ReaderAt) {
+	if p == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		}
+
+		if err := p.catchUp(ctx, r, p.frontier()); err != nil && ctx.Err() == nil {
+			// Finish will retry the remaining bytes, so just note it here.
+			utils.Debug("Incremental hashing stalled at %d: %v", p.Hashed(), err)
+		}
+	}
+}
+
+// Finish hashes any bytes not yet covered up to size and compares the result
+// with the expected checksum. Call it only after Run has returned.
+func (p *PrefixHasher) Finish(r io.ReaderAt, size int64) error {
+	if p == nil {
+		return nil
+	}
+
+	remaining := size - p.Hashed()
+	if err := p.catchUp(context.Background(), r, size); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	if remaining > 0 {
+		utils.Debug("Checksum finish hashed %d trailing bytes", remaining)
+	}
+
+	p.hashMu.Lock()
+	sum := p.h.Sum(nil)
+	p.hashMu.Unlock()
+
+	return Compare(p.expected, sum)
+}
+
+// frontier returns the end of the written range that starts at offset 0.
+func (p *PrefixHasher) frontier() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.spans) == 0 || p.spans[0].start > 0 {
+		return 0
+	}
+	return p.spans[0].end
+}
+
+// catchUp feeds bytes [hashed, end) from r into the hash.
+func (p *PrefixHasher) catchUp(ctx context.Context, r io.ReaderAt, end int64) error {
+	var buf []byte
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p.hashMu.Lock()
+		offset := p.hashed
+		if offset >= end {
+			p.hashMu.Unlock()
+			return nil
+		}
+		if buf == nil {
+			buf = make([]byte, hashReadSize)
+		}
+
+		n := int64(len(buf))
+		if end-offset < n {
+			n = end - offset
+		}
+		read, err := r.ReadAt(buf[:n], offset)
+		if read > 0 {
+			_, _ = p.h.Write(buf[:read])
+			p.hashed += int64(read)
+		}
+		p.hashMu.Unlock()
+
+		if err != nil && !(errors.Is(err, io.EOF) && int64(read) == n) {
+			return err
+		}
+	}
+}
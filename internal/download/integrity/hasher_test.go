@@ -0,0 +1,188 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+func sha256Checksum(data []byte) *types.Checksum {
+	sum := sha256.Sum256(data)
+	return &types.Checksum{Algorithm: types.ChecksumSHA256, Value: hex.EncodeToString(sum[:])}
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestPrefixHasherOutOfOrder(t *testing.T) {
+	data := testData(3*hashReadSize + 123)
+	h, err := NewPrefixHasher(sha256Checksum(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.Run(ctx, bytes.NewReader(data))
+	}()
+
+	// Nothing is hashed until the range at offset 0 arrives.
+	third := int64(len(data) / 3)
+	h.MarkWritten(2*third, int64(len(data))-2*third)
+	h.MarkWritten(third, third)
+	if got := h.frontier(); got != 0 {
+		t.Errorf("frontier = %d before offset 0 is written, want 0", got)
+	}
+	h.MarkWritten(0, third)
+	if got := h.frontier(); got != int64(len(data)) {
+		t.Errorf("frontier = %d, want %d", got, len(data))
+	}
+
+	cancel()
+	wg.Wait()
+	if err := h.Finish(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Errorf("Finish: %v", err)
+	}
+}
+
+func TestPrefixHasherMismatch(t *testing.T) {
+	data := testData(1000)
+	h, err := NewPrefixHasher(sha256Checksum(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := append([]byte(nil), data...)
+	corrupt[500] ^= 0xff
+
+	err = h.Finish(bytes.NewReader(corrupt), int64(len(corrupt)))
+	var mismatch *types.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Finish error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestPrefixHasherSnapshotRestore(t *testing.T) {
+	data := testData(10000)
+	expected := sha256Checksum(data)
+
+	first, _ := NewPrefixHasher(expected)
+	first.Write(data[:4096])
+	state, hashed := first.Snapshot()
+	if hashed != 4096 || len(state) == 0 {
+		t.Fatalf("Snapshot = %d bytes of state at %d, want state at 4096", len(state), hashed)
+	}
+
+	second, _ := NewPrefixHasher(expected)
+	if err := second.Restore(state, hashed); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := second.Hashed(); got != 4096 {
+		t.Errorf("Hashed after Restore = %d, want 4096", got)
+	}
+	// Restore must not re-read the prefix: give it a reader without those bytes.
+	tail := append(make([]byte, 4096), data[4096:]...)
+	if err := second.Finish(bytes.NewReader(tail), int64(len(data))); err != nil {
+		t.Errorf("Finish after Restore: %v", err)
+	}
+}
+
+func TestPrefixHasherNil(t *testing.T) {
+	h, err := NewPrefixHasher(nil)
+	if h != nil || err != nil {
+		t.Fatalf("NewPrefixHasher(nil) = %v, %v; want nil, nil", h, err)
+	}
+	// Every method is a no-op on a nil hasher.
+	h.MarkWritten(0, 10)
+	h.Write([]byte("x"))
+	if state, n := h.Snapshot(); state != nil || n != 0 {
+		t.Errorf("Snapshot on nil = %v, %d", state, n)
+	}
+	if err := h.Finish(bytes.NewReader(nil), 10); err != nil {
+		t.Errorf("Finish on nil = %v", err)
+	}
+}
+
+func TestInsertSpan(t *testing.T) {
+	tests := []struct {
+		name  string
+		spans []span
+		add   span
+		want  []span
+	}{
+		{"into empty", nil, span{5, 10}, []span{{5, 10}}},
+		{"before", []span{{10, 20}}, span{0, 5}, []span{{0, 5}, {10, 20}}},
+		{"after", []span{{10, 20}}, span{25, 30}, []span{{10, 20}, {25, 30}}},
+		{"adjacent below", []span{{10, 20}}, span{5, 10}, []span{{5, 20}}},
+		{"adjacent above", []span{{10, 20}}, span{20, 30}, []span{{10, 30}}},
+		{"overlapping", []span{{10, 20}}, span{15, 25}, []span{{10, 25}}},
+		{"contained", []span{{10, 20}}, span{12, 18}, []span{{10, 20}}},
+		{"bridging", []span{{0, 5}, {10, 15}, {20, 25}}, span{5, 20}, []span{{0, 25}}},
+		{"one short of bridging", []span{{0, 5}, {10, 15}}, span{6, 9}, []span{{0, 5}, {6, 9}, {10, 15}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insertSpan(append([]span(nil), tt.spans...), tt.add); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("insertSpan(%v, %v) = %v, want %v", tt.spans, tt.add, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveSpan(t *testing.T) {
+	tests := []struct {
+		name   string
+		spans  []span
+		remove span
+		want   []span
+	}{
+		{"disjoint", []span{{0, 10}}, span{10, 20}, []span{{0, 10}}},
+		{"whole", []span{{0, 10}}, span{0, 10}, nil},
+		{"middle", []span{{0, 10}}, span{3, 6}, []span{{0, 3}, {6, 10}}},
+		{"head", []span{{0, 10}}, span{0, 4}, []span{{4, 10}}},
+		{"tail", []span{{0, 10}}, span{8, 12}, []span{{0, 8}}},
+		{"across two", []span{{0, 10}, {20, 30}}, span{5, 25}, []span{{0, 5}, {25, 30}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeSpan(tt.spans, tt.remove); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeSpan(%v, %v) = %v, want %v", tt.spans, tt.remove, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoversSpan(t *testing.T) {
+	spans := []span{{0, 10}, {20, 30}}
+	tests := []struct {
+		s    span
+		want bool
+	}{
+		{span{0, 10}, true},
+		{span{2, 8}, true},
+		{span{20, 30}, true},
+		{span{5, 15}, false},
+		{span{9, 21}, false},
+		{span{10, 20}, false},
+		{span{25, 31}, false},
+	}
+	for _, tt := range tests {
+		if got := coversSpan(spans, tt.s); got != tt.want {
+			t.Errorf("coversSpan(%v, %v) = %v, want %v", spans, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package integrity
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+// algorithmRank orders supported algorithms from strongest to weakest.
+var algorithmRank = []string{
+	types.ChecksumSHA512,
+	types.ChecksumSHA256,
+	types.ChecksumSHA1,
+	types.ChecksumMD5,
+	types.ChecksumCRC32C,
+	types.ChecksumCRC32,
+}
+
+// amzChecksumHeaders maps S3 checksum headers to algorithms.
+var amzChecksumHeaders = map[string]string{
+	"X-Amz-Checksum-Sha256": types.ChecksumSHA256,
+	"X-Amz-Checksum-Sha1":   types.ChecksumSHA1,
+	"X-Amz-Checksum-Crc32c": types.ChecksumCRC32C,
+	"X-Amz-Checksum-Crc32":  types.ChecksumCRC32,
+}
+
+// RepresentationDigest returns the strongest whole-file digest advertised in h.
+// For 206 responses (partial=true), digests of the body itself such as
+// Content-MD5 only cover the range and are ignored.
+func RepresentationDigest(h http.Header, partial bool) *types.Checksum {
+	if isEncoded(h) {
+		return nil
+	}
+
+	var found []*types.Checksum
+	found = append(found, parseStructuredDigests(h.Values("Repr-Digest"))...)
+	found = append(found, parseDigestList(h.Values("Digest"))...)
+	found = append(found, parseDigestList(h.Values("X-Goog-Hash"))...)
+	if !partial {
+		found = append(found, contentDigests(h)...)
+		found = append(found, parseAmzChecksums(h)...)
+	}
+	return Strongest(found)
+}
+
+// ContentDigest returns the strongest digest of the response body itself,
+// which for a range response covers only the returned bytes.
+func ContentDigest(h http.Header) *types.Checksum {
+	if isEncoded(h) {
+		return nil
+	}
+	return Strongest(contentDigests(h))
+}
+
+// contentDigests returns every body digest found in Content-Digest and Content-MD5.
+func contentDigests(h http.Header) []*types.Checksum {
+	found := parseStructuredDigests(h.Values("Content-Digest"))
+	if raw := h.Get("Content-MD5"); raw != "" {
+		if c := decodeDigest(types.ChecksumMD5, raw); c != nil {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// isEncoded reports whether digests describe content-coded bytes we never see.
+func isEncoded(h http.Header) bool {
+	ce := strings.TrimSpace(h.Get("Content-Encoding"))
+	return ce != "" && !strings.EqualFold(ce, "identity")
+}
+
+// parseStructuredDigests parses RFC 9530 fields: sha-256=:<base64>:, ...
+func parseStructuredDigests(values []string) []*types.Checksum {
+	var found []*types.Checksum
+	for _, raw := range values {
+		for _, item := range strings.Split(raw, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				continue
+			}
+			value, _, _ = strings.Cut(value, ";") // Drop parameters
+			value = strings.Trim(strings.TrimSpace(value), ":")
+			if c := decodeDigest(key, value); c != nil {
+				found = append(found, c)
+			}
+		}
+	}
+	return found
+}
+
+// parseDigestList parses RFC 3230 Digest and x-goog-hash: SHA-256=<base64>, md5=<base64>
+func parseDigestList(values []string) []*types.Checksum {
+	var found []*types.Checksum
+	for _, raw := range values {
+		for _, item := range strings.Split(raw, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if !ok {
+				continue
+			}
+			if c := decodeDigest(key, value); c != nil {
+				found = append(found, c)
+			}
+		}
+	}
+	return found
+}
+
+// parseAmzChecksums reads x-amz-checksum-* headers, skipping multipart composites.
+func parseAmzChecksums(h http.Header) []*types.Checksum {
+	if strings.EqualFold(h.Get("X-Amz-Checksum-Type"), "COMPOSITE") {
+		return nil
+	}
+
+	var found []*types.Checksum
+	for header, algo := range amzChecksumHeaders {
+		raw := h.Get(header)
+		// Composite checksums look like "<base64>-<parts>" and can't be checked against the file.
+		if raw == "" || strings.Contains(raw, "-") {
+			continue
+		}
+		if c := decodeDigest(algo, raw); c != nil {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// decodeDigest converts a header algorithm token and base64 (or hex) value
+// into a Checksum, returning nil for unsupported or malformed input.
+func decodeDigest(algo string, value string) *types.Checksum {
+	algo = strings.ToLower(strings.TrimSpace(algo))
+	if algo == "sha" {
+		algo = types.ChecksumSHA1
+	}
+	value = strings.TrimSpace(value)
+
+	if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+		if c, err := types.NewChecksum(algo, hex.EncodeToString(decoded)); err == nil {
+			return c
+		}
+	}
+	// Some servers send CRC values as plain hex.
+	if c, err := types.NewChecksum(algo, value); err == nil {
+		return c
+	}
+	return nil
+}
+
+// Strongest picks the digest with the best-ranked algorithm.
+func Strongest(found []*types.Checksum) *types.Checksum {
+	for _, algo := range algorithmRank {
+		for _, c := range found {
+			if c.Algorithm == algo {
+				return c
+			}
+		}
+	}
+	return nil
+}
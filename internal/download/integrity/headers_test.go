@@ -0,0 +1,98 @@
+package integrity
+
+import (
+	"net/http"
+	"testing"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+// Digests of "abc".
+const (
+	abcSHA256B64 = "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0="
+	abcSHA256Hex = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	abcSHA512B64 = "3a81oZNherrMQXNJriBBMRLm+k6JqX6iCp7u5ktV05ohkpkqJ0/BqDa6PCOj/uu9RU1EI2Q86A4qmslPpUyknw=="
+	abcMD5B64    = "kAFQmDzST7DWlj99KOF/cg=="
+	abcMD5Hex    = "900150983cd24fb0d6963f7d28e17f72"
+	abcCRC32CHex = "364b3fb7"
+)
+
+func header(kv ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i < len(kv); i += 2 {
+		h.Add(kv[i], kv[i+1])
+	}
+	return h
+}
+
+func TestContentDigest(t *testing.T) {
+	tests := []struct {
+		name string
+		h    http.Header
+		want string
+	}{
+		{"none", header(), ""},
+		{"content-digest", header("Content-Digest", "sha-256=:"+abcSHA256B64+":"), "sha256:" + abcSHA256Hex},
+		{"content-md5", header("Content-MD5", abcMD5B64), "md5:" + abcMD5Hex},
+		{"strongest wins", header("Content-MD5", abcMD5B64, "Content-Digest", "sha-256=:"+abcSHA256B64+":"), "sha256:" + abcSHA256Hex},
+		{"list with parameters", header("Content-Digest", "unknown=:AAAA:, sha-256=:"+abcSHA256B64+":;q=1"), "sha256:" + abcSHA256Hex},
+		{"malformed", header("Content-Digest", "sha-256=:bm90IGEgZGlnZXN0:"), ""},
+		{"encoded body", header("Content-Encoding", "gzip", "Content-Digest", "sha-256=:"+abcSHA256B64+":"), ""},
+		{"identity encoding", header("Content-Encoding", "identity", "Content-MD5", abcMD5B64), "md5:" + abcMD5Hex},
+		{"representation only", header("Repr-Digest", "sha-256=:"+abcSHA256B64+":"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContentDigest(tt.h).String(); got != tt.want {
+				t.Errorf("ContentDigest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepresentationDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       http.Header
+		partial bool
+		want    string
+	}{
+		{"repr-digest", header("Repr-Digest", "sha-512=:"+abcSHA512B64+":"), false, "sha512:" + hexOf(t, abcSHA512B64)},
+		{"rfc 3230 digest", header("Digest", "SHA-256="+abcSHA256B64), false, "sha256:" + abcSHA256Hex},
+		{"sha means sha1", header("Digest", "SHA=qZk+NkcGgWq6PiVxeFDCbJzQ2J0="), false, "sha1:a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"x-goog-hash", header("X-Goog-Hash", "crc32c=Nks/tw==, md5="+abcMD5B64), false, "md5:" + abcMD5Hex},
+		{"amz crc32c as hex", header("X-Amz-Checksum-Crc32c", abcCRC32CHex), false, "crc32c:" + abcCRC32CHex},
+		{"amz composite", header("X-Amz-Checksum-Sha256", abcSHA256B64+"-3"), false, ""},
+		{"amz composite type", header("X-Amz-Checksum-Type", "COMPOSITE", "X-Amz-Checksum-Sha256", abcSHA256B64), false, ""},
+		{"body digest of a whole response", header("Content-MD5", abcMD5B64), false, "md5:" + abcMD5Hex},
+		{"body digest of a range", header("Content-MD5", abcMD5B64), true, ""},
+		{"repr digest of a range", header("Repr-Digest", "sha-256=:"+abcSHA256B64+":"), true, "sha256:" + abcSHA256Hex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepresentationDigest(tt.h, tt.partial).String(); got != tt.want {
+				t.Errorf("RepresentationDigest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrongest(t *testing.T) {
+	md5 := &types.Checksum{Algorithm: types.ChecksumMD5, Value: abcMD5Hex}
+	sha := &types.Checksum{Algorithm: types.ChecksumSHA256, Value: abcSHA256Hex}
+	if got := Strongest([]*types.Checksum{md5, sha}); got != sha {
+		t.Errorf("Strongest = %v, want %v", got, sha)
+	}
+	if got := Strongest(nil); got != nil {
+		t.Errorf("Strongest(nil) = %v, want nil", got)
+	}
+}
+
+func hexOf(t *testing.T, b64 string) string {
+	t.Helper()
+	c := decodeDigest(types.ChecksumSHA512, b64)
+	if c == nil {
+		t.Fatalf("cannot decode %q", b64)
+	}
+	return c.Value
+}
@@ -0,0 +1,82 @@
+package integrity
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// NewHash returns a fresh hash.Hash for a normalized algorithm name.
+func NewHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case types.ChecksumSHA512:
+		return sha512.New(), nil
+	case types.ChecksumSHA256:
+		return sha256.New(), nil
+	case types.ChecksumSHA1:
+		return sha1.New(), nil
+	case types.ChecksumMD5:
+		return md5.New(), nil
+	case types.ChecksumCRC32C:
+		return crc32.New(crc32cTable), nil
+	case types.ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+}
+
+// Compare checks a computed digest against the expected checksum.
+func Compare(expected *types.Checksum, sum []byte) error {
+	actual := hex.EncodeToString(sum)
+	if actual != expected.Value {
+		return &types.ChecksumMismatchError{
+			Algorithm: expected.Algorithm,
+			Expected:  expected.Value,
+			Actual:    actual,
+		}
+	}
+	return nil
+}
+
+// VerifyFile hashes the file at path and compares it with the expected checksum.
+// A nil checksum means "nothing to verify" and always succeeds.
+func VerifyFile(path string, expected *types.Checksum) error {
+	if expected == nil {
+		return nil
+	}
+
+	h, err := NewHash(expected.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for verification: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	if err := Compare(expected, h.Sum(nil)); err != nil {
+		utils.Debug("Checksum verification failed for %s: %v", path, err)
+		return err
+	}
+	utils.Debug("Checksum verified for %s (%s)", path, expected.Algorithm)
+	return nil
+}
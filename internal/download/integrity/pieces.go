@@ -0,0 +1,153 @@
+package integrity
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// PieceVerifier checks fixed-size pieces against published digests as soon
+// as every byte of a piece has been written. Pieces are read back while they
+// are still in the page cache, so a bad mirror costs one piece, not the file.
+//
+// All methods are safe to call on a nil *PieceVerifier.
+type PieceVerifier struct {
+	pieces   *types.PieceHashes
+	fileSize int64
+	file     io.ReaderAt
+
+	mu       sync.Mutex
+	spans    []span // Written ranges, sorted and merged
+	verified []bool
+	checking []bool
+}
+
+// NewPieceVerifier returns a verifier for pieces, or nil when there are none.
+func NewPieceVerifier(pieces *types.PieceHashes, fileSize int64, file io.ReaderAt) (*PieceVerifier, error) {
+	if pieces == nil {
+		return nil, nil
+	}
+	if !pieces.Matches(fileSize) {
+		return nil, fmt.Errorf("%d pieces of %d bytes do not match file size %d", len(pieces.Hashes), pieces.Length, fileSize)
+	}
+	if _, err := NewHash(pieces.Algorithm); err != nil {
+		return nil, err
+	}
+
+	return &PieceVerifier{
+		pieces:   pieces,
+		fileSize: fileSize,
+		file:     file,
+		verified: make([]bool, len(pieces.Hashes)),
+		checking: make([]bool, len(pieces.Hashes)),
+	}, nil
+}
+
+// Restore records ranges written in an earlier session. Pieces they fully
+// cover were verified before the pause and are trusted without re-reading.
+// It returns the ranges of those pieces.
+func (v *PieceVerifier) Restore(done []types.Task) []types.Task {
+	if v == nil {
+		return done
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, r := range done {
+		if r.Length > 0 {
+			v.spans = insertSpan(v.spans, span{r.Offset, r.Offset + r.Length})
+		}
+	}
+
+	var verified []types.Task
+	for i := range v.verified {
+		r, _ := v.pieces.Piece(i, v.fileSize)
+		if coversSpan(v.spans, span{r.Offset, r.Offset + r.Length}) {
+			v.verified[i] = true
+			verified = append(verified, r)
+		}
+	}
+	return verified
+}
+
+// MarkWritten records a written range and verifies any pieces it completes.
+// It returns the ranges of pieces that passed and of pieces that failed;
+// failed ranges are forgotten so they can be written again.
+func (v *PieceVerifier) MarkWritten(offset, length int64) (verified []types.Task, failed []types.Task) {
+	if v == nil || length <= 0 {
+		return nil, nil
+	}
+
+	v.mu.Lock()
+	v.spans = insertSpan(v.spans, span{offset, offset + length})
+	first := int(offset / v.pieces.Length)
+	last := int((offset + length - 1) / v.pieces.Length)
+	var ready []int
+	for i := first; i <= last && i < len(v.verified); i++ {
+		if v.verified[i] || v.checking[i] {
+			continue
+		}
+		r, _ := v.pieces.Piece(i, v.fileSize)
+		if coversSpan(v.spans, span{r.Offset, r.Offset + r.Length}) {
+			v.checking[i] = true
+			ready = append(ready, i)
+		}
+	}
+	v.mu.Unlock()
+
+	for _, i := range ready {
+		r, expected := v.pieces.Piece(i, v.fileSize)
+		err := v.check(r, expected)
+
+		v.mu.Lock()
+		v.checking[i] = false
+		if err == nil {
+			v.verified[i] = true
+		} else {
+			v.spans = removeSpan(v.spans, span{r.Offset, r.Offset + r.Length})
+		}
+		v.mu.Unlock()
+
+		if err != nil {
+			utils.Debug("Piece %d (%d-%d) failed verification: %v", i, r.Offset, r.Offset+r.Length-1, err)
+			failed = append(failed, r)
+			continue
+		}
+		verified = append(verified, r)
+	}
+	return verified, failed
+}
+
+// Pending returns how many pieces have not been verified yet.
+func (v *PieceVerifier) Pending() int {
+	if v == nil {
+		return 0
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	pending := 0
+	for _, ok := range v.verified {
+		if !ok {
+			pending++
+		}
+	}
+	return pending
+}
+
+// check hashes one piece from the file and compares it with its digest.
+func (v *PieceVerifier) check(r types.Task, expected *types.Checksum) error {
+	h, err := NewHash(expected.Algorithm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, io.NewSectionReader(v.file, r.Offset, r.Length)); err != nil {
+		return fmt.Errorf("failed to read piece: %w", err)
+	}
+	return Compare(expected, h.Sum(nil))
+}
@@ -0,0 +1,52 @@
+package integrity
+
+import "sort"
+
+// span is a half-open byte range [start, end).
+type span struct {
+	start int64
+	end   int64
+}
+
+// insertSpan adds s to a sorted span list, merging overlapping or adjacent ranges.
+func insertSpan(spans []span, s span) []span {
+	spans = append(spans, s)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	merged := spans[:1]
+	for _, next := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if next.start <= last.end {
+			if next.end > last.end {
+				last.end = next.end
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+// removeSpan cuts s out of a sorted span list.
+func removeSpan(spans []span, s span) []span {
+	var out []span
+	for _, cur := range spans {
+		if cur.end <= s.start || cur.start >= s.end {
+			out = append(out, cur)
+			continue
+		}
+		if cur.start < s.start {
+			out = append(out, span{cur.start, s.start})
+		}
+		if cur.end > s.end {
+			out = append(out, span{s.end, cur.end})
+		}
+	}
+	return out
+}
+
+// coversSpan reports whether a sorted span list fully contains s.
+func coversSpan(spans []span, s span) bool {
+	i := sort.Search(len(spans), func(i int) bool { return spans[i].end >= s.end })
+	return i < len(spans) && spans[i].start <= s.start
+}
@@ -3,6 +3,7 @@ package download
 import (
 	engine "concurrent_downloader/internal"
 	"concurrent_downloader/internal/download/concurrent"
+	"concurrent_downloader/internal/download/diskspace"
 	"concurrent_downloader/internal/download/single"
 	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/events"
@@ -21,6 +22,10 @@ import (
 
 var probeClient = &http.Client{Timeout: types.ProbeTimeout}
 
+// remoteChangeRestarts bounds how often a download whose file keeps
+// changing on the server starts over before it fails.
+const remoteChangeRestarts = 3
+
 var ua = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
 	"AppleWebKit/537.36 (KHTML, like Gecko) " +
 	"Chrome/120.0.0.0 Safari/537.36"
@@ -36,13 +41,22 @@ type ProbeResult struct {
 }
 
 // uniqueFilePath picks a collision-free path while preserving the base name
-// so user expectations around filenames remain intact.
-func uniqueFilePath(path string) string {
-	// Check if file exists (both final and incomplete)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if _, err := os.Stat(path + types.IncompleteSuffix); os.IsNotExist(err) {
-			return path // Neither exists, use original
+// so user expectations around filenames remain intact. Paths that another
+// started download will finish at count as taken, since its partial may be
+// in a temp dir.
+func uniqueFilePath(path string, id string) string {
+	taken := func(p string) bool {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			return true
+		}
+		if _, err := os.Stat(p + types.IncompleteSuffix); !os.IsNotExist(err) {
+			return true
 		}
+		return state.DestPathInUse(p, id)
+	}
+
+	if !taken(path) {
+		return path // Neither exists, use original
 	}
 
 	// File exists, generate unique name without clobbering in-progress partials.
@@ -73,10 +87,8 @@ func uniqueFilePath(path string) string {
 
 	for i := 0; i < 100; i++ { // Try next 100 numbers
 		candidate := filepath.Join(dir, fmt.Sprintf("%s(%d)%s", base, counter+i, ext))
-		if _, err := os.Stat(candidate); os.IsNotExist(err) {
-			if _, err := os.Stat(candidate + types.IncompleteSuffix); os.IsNotExist(err) {
-				return candidate
-			}
+		if !taken(candidate) {
+			return candidate
 		}
 	}
 
@@ -96,6 +108,47 @@ func ensureFilenameExt(filename string, probeFilename string) string {
 	return filename + probeExt
 }
 
+// checkDiskSpace reports a KindDiskFull error if a file of size bytes
+// cannot be written at workingPath, or copied to destPath when the two are
+// on different filesystems. Space the partial already holds counts as
+// available.
+func checkDiskSpace(workingPath, destPath string, size, reserve int64) error {
+	if err := diskspace.Check(workingPath, size, reserve); err != nil {
+		return err
+	}
+	if diskspace.SameFilesystem(filepath.Dir(workingPath), filepath.Dir(destPath)) {
+		return nil
+	}
+	return diskspace.Check(destPath, size, reserve)
+}
+
+// probeMirrors returns the mirrors of cfg that answer a probe, without the
+// primary URL.
+func probeMirrors(ctx context.Context, cfg *types.DownloadConfig) []string {
+	if len(cfg.Mirrors) == 0 {
+		return nil
+	}
+	utils.Debug("Probing %d mirrors", len(cfg.Mirrors))
+	// Always check primary + mirrors to ensure we are using the best set
+	allToCheck := append([]string{cfg.URL}, cfg.Mirrors...)
+	valid, errs := engine.ProbeMirrors(ctx, allToCheck)
+
+	// Log errors
+	for u, e := range errs {
+		utils.Debug("Mirror probe failed for %s: %v", u, e)
+	}
+
+	// Filter valid mirrors (excluding primary as it is handled separately)
+	var activeMirrors []string
+	for _, v := range valid {
+		if v != cfg.URL {
+			activeMirrors = append(activeMirrors, v)
+		}
+	}
+	utils.Debug("Found %d active mirrors from %d candidates", len(activeMirrors), len(cfg.Mirrors))
+	return activeMirrors
+}
+
 func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 	// Probe once to decide strategy and gather canonical filename/size.
@@ -107,9 +160,59 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	probe, err := engine.ProbeServer(ctx, cfg.URL, probeHint, cfg.Headers)
 	if err != nil {
 		utils.Debug("CLIDownload: Probe failed: %v", err)
+		// Make other downloads from this host wait as well.
+		var throttled *types.ThrottledError
+		if errors.As(err, &throttled) {
+			cfg.Hosts.Throttle(cfg.URL, throttled.RetryAfter)
+		}
+		if ctx.Err() == nil {
+			// Record why, e.g. missing credentials, instead of leaving the row queued.
+			entry := types.DownloadEntry{
+				ID:       cfg.ID,
+				URL:      cfg.URL,
+				URLHash:  state.URLHash(cfg.URL),
+				DestPath: filepath.Join(cfg.OutputPath, cfg.Filename),
+				Filename: cfg.Filename,
+				Status:   "error",
+				Mirrors:  cfg.Mirrors,
+				Checksum: cfg.Checksum.String(),
+				Priority: cfg.Priority,
+
+				RetryCount: cfg.RetryAttempt,
+			}
+			if cfg.IsResume && cfg.DestPath != "" {
+				// Keep pointing at the partial; it is still good for a later resume.
+				entry.DestPath = cfg.DestPath
+				entry.Filename = filepath.Base(cfg.DestPath)
+			}
+			if cfg.State != nil {
+				_, entry.TotalSize, _, _, _, _ = cfg.State.GetProgress()
+				entry.Downloaded = cfg.State.VerifiedProgress.Load()
+			}
+			if types.NeedsNewURL(err) {
+				entry.Status = "needs_url"
+			}
+			entry.SetError(err)
+			if err := state.AddToMasterList(entry); err != nil {
+				utils.Debug("Failed to persist error state: %v", err)
+			}
+		}
 		return err
 	}
 	utils.Debug("CLIDownload: Probe success, size=%d", probe.FileSize)
+
+	// A size that disagrees with the one we were given means a stale or wrong mirror.
+	if cfg.Size > 0 && probe.FileSize > 0 && probe.FileSize != cfg.Size {
+		return types.NewError(types.KindFileChanged,
+			fmt.Errorf("size mismatch: expected %d bytes, server reports %d", cfg.Size, probe.FileSize))
+	}
+
+	// Without an explicit checksum, verify against whatever digest the server advertises.
+	serverChecksum := cfg.Checksum == nil
+	if cfg.Checksum == nil && probe.Checksum != nil {
+		utils.Debug("CLIDownload: Using server checksum %s", probe.Checksum)
+		cfg.Checksum = probe.Checksum
+	}
 	// Start download timer (exclude probing time) for accurate throughput stats.
 	start := time.Now()
 	defer func() {
@@ -164,9 +267,13 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 		// Resume: use saved destination path directly (don't generate new unique name)
 		destPath = savedState.DestPath
 		utils.Debug("Resuming download, using saved destPath: %s", destPath)
+	} else if cfg.IsResume && cfg.DestPath != "" {
+		// Nothing saved to continue from (e.g. a retry after an early failure):
+		// start over in our own file rather than next to it.
+		destPath = cfg.DestPath
 	} else {
 		// Fresh download without TUI-provided filename: generate unique filename if file already exists
-		destPath = uniqueFilePath(destPath)
+		destPath = uniqueFilePath(destPath, cfg.ID)
 	}
 	finalFilename := filepath.Base(destPath)
 	utils.Debug("Destination path: %s", destPath)
@@ -175,6 +282,25 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	cfg.Filename = finalFilename
 	cfg.DestPath = destPath // Save resolved path for resume logic (WorkerPool)
 
+	// A resumed download keeps its partial where it was started; a new one
+	// is written to the temp dir, if any, and moved into place when done.
+	workingPath := types.PartialPath(cfg.ID, destPath, cfg.Runtime.GetTempDir())
+	if isResume {
+		workingPath = savedState.WorkingPath
+	}
+	if dir := filepath.Dir(workingPath); dir != filepath.Dir(destPath) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			utils.Debug("Failed to create temp directory: %v", err)
+		}
+	}
+	utils.Debug("Working path: %s", workingPath)
+	if cfg.State != nil {
+		cfg.State.SetWorkingPath(workingPath)
+	}
+	if err := state.UpdatePaths(cfg.ID, destPath, workingPath); err != nil {
+		utils.Debug("Failed to record paths of %s: %v", cfg.ID, err)
+	}
+
 	// Send download started message
 	if cfg.ProgressCh != nil {
 		cfg.ProgressCh <- events.DownloadStartedMsg{
@@ -194,40 +320,100 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 
 	// Choose downloader based on probe results and runtime overrides.
 	var downloadErr error
+
+	// Never complete a partial of an older version with bytes of a newer one.
+	if isResume {
+		if change := savedState.RemoteChange(probe.FileSize, probe.ETag, probe.LastModified); change != "" {
+			if cfg.Runtime.GetOnRemoteChange() == types.RemoteChangeFail {
+				downloadErr = types.NewError(types.KindFileChanged, fmt.Errorf("remote file changed since the partial was saved: %s", change))
+			} else {
+				utils.Debug("Remote file changed (%s), restarting %s", change, destPath)
+				_ = os.Remove(workingPath)
+				_ = state.DeleteState(cfg.ID, cfg.URL, destPath)
+				if cfg.State != nil {
+					cfg.State.SetSavedElapsed(0)
+				}
+			}
+		}
+	}
+
+	// Stop now rather than at some write halfway through if the rest of the
+	// file does not fit.
+	if downloadErr == nil {
+		downloadErr = checkDiskSpace(workingPath, destPath, probe.FileSize, cfg.Runtime.GetDiskReserve())
+	}
+
 	forceSingle := cfg.Runtime != nil && cfg.Runtime.ForceSingle
-	if !forceSingle && probe.SupportsRange && probe.FileSize > 0 {
+	if downloadErr != nil {
+		utils.Debug("CLIDownload: Not starting: %v", downloadErr)
+	} else if !forceSingle && probe.SupportsRange && probe.FileSize > 0 {
 		utils.Debug("Using concurrent downloader")
 
 		// Probe mirrors to filter invalid hosts before we schedule workers.
-		var activeMirrors []string
-		if len(cfg.Mirrors) > 0 {
-			utils.Debug("Probing %d mirrors", len(cfg.Mirrors))
-			// Always check primary + mirrors to ensure we are using the best set
-			allToCheck := append([]string{cfg.URL}, cfg.Mirrors...)
-			valid, errs := engine.ProbeMirrors(ctx, allToCheck)
-
-			// Log errors
-			for u, e := range errs {
-				utils.Debug("Mirror probe failed for %s: %v", u, e)
+		activeMirrors := probeMirrors(ctx, cfg)
+
+		for restarts := 0; ; restarts++ {
+			d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
+			d.Headers = cfg.Headers // Forward custom headers from browser extension
+			d.Checksum = cfg.Checksum
+			d.Pieces = cfg.Pieces
+			d.Connections = cfg.Connections
+			d.Hosts = cfg.Hosts
+			d.Bandwidth = cfg.Bandwidth
+			d.ETag = probe.ETag
+			d.LastModified = probe.LastModified
+			d.WorkingPath = workingPath
+			utils.Debug("Calling Download with mirrors: %v", cfg.Mirrors)
+			downloadErr = d.Download(ctx, cfg.URL, cfg.Mirrors, activeMirrors, destPath, probe.FileSize, probe.SupportsHTTP2, probe.SupportsHTTP3)
+
+			// The file changed on the server mid-download and the downloader
+			// discarded the partial: start the new version over, as a resume
+			// that finds the file changed does.
+			if !errors.Is(downloadErr, types.ErrFileChanged) || cfg.Runtime.GetOnRemoteChange() != types.RemoteChangeRestart ||
+				restarts >= remoteChangeRestarts || ctx.Err() != nil {
+				break
 			}
-
-			// Filter valid mirrors (excluding primary as it is handled separately)
-			for _, v := range valid {
-				if v != cfg.URL {
-					activeMirrors = append(activeMirrors, v)
-				}
+			utils.Debug("Remote file changed during download, restarting %s", destPath)
+			next, err := engine.ProbeServer(ctx, cfg.URL, "", cfg.Headers)
+			if err != nil {
+				downloadErr = err
+				break
+			}
+			if cfg.Size > 0 && next.FileSize != cfg.Size {
+				downloadErr = types.NewError(types.KindFileChanged,
+					fmt.Errorf("size mismatch: expected %d bytes, server reports %d", cfg.Size, next.FileSize))
+				break
+			}
+			if !next.SupportsRange || next.FileSize <= 0 {
+				downloadErr = types.NewError(types.KindFileChanged,
+					fmt.Errorf("remote file changed during download and can no longer be fetched in ranges"))
+				break
+			}
+			// A digest the server advertised belonged to the old version.
+			if serverChecksum {
+				cfg.Checksum = next.Checksum
+			}
+			probe = next
+			if cfg.State != nil {
+				cfg.State.SetTotalSize(probe.FileSize)
+				cfg.State.SetSavedElapsed(0)
+			}
+			if downloadErr = checkDiskSpace(workingPath, destPath, probe.FileSize, cfg.Runtime.GetDiskReserve()); downloadErr != nil {
+				break
 			}
-			utils.Debug("Found %d active mirrors from %d candidates", len(activeMirrors), len(cfg.Mirrors))
 		}
-
-		d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
-		d.Headers = cfg.Headers // Forward custom headers from browser extension
-		utils.Debug("Calling Download with mirrors: %v", cfg.Mirrors)
-		downloadErr = d.Download(ctx, cfg.URL, cfg.Mirrors, activeMirrors, destPath, probe.FileSize, probe.SupportsHTTP2, probe.SupportsHTTP3)
 	} else {
 		// Fallback to single-threaded downloader
 		utils.Debug("Using single-threaded downloader")
 		d := single.NewSingleDownloader(cfg.ID, cfg.ProgressCh, cfg.State, cfg.Runtime)
+		d.Checksum = cfg.Checksum
+		d.Resumable = probe.SupportsRange // Forced single mode can still resume with Range
+		d.Headers = cfg.Headers           // Forward custom headers from browser extension
+		d.SupportsHTTP2 = probe.SupportsHTTP2
+		d.SupportsHTTP3 = probe.SupportsHTTP3
+		d.Hosts = cfg.Hosts
+		d.Bandwidth = cfg.Bandwidth
+		d.WorkingPath = workingPath
 		downloadErr = d.Download(ctx, cfg.URL, destPath, probe.FileSize, probe.Filename, cfg.Verbose)
 	}
 
@@ -263,6 +449,8 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			CompletedAt: time.Now().Unix(),
 			TimeTaken:   elapsed.Milliseconds(),
 			AvgSpeed:    avgSpeed,
+			Checksum:    cfg.Checksum.String(),
+			RetryCount:  cfg.RetryAttempt,
 		}); err != nil {
 			utils.Debug("Failed to persist completed download: %v", err)
 		}
@@ -276,17 +464,45 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 			}
 		}
 	} else if downloadErr != nil && !isPaused {
+		status := "error"
+		if types.NeedsNewURL(downloadErr) {
+			// The partial is kept; a new URL for the same file continues it.
+			status = "needs_url"
+		} else if types.IsDiskFull(downloadErr) {
+			// The partial is kept; the download waits for space to free up.
+			status = "disk_full"
+		}
+		var mismatch *types.ChecksumMismatchError
+		if errors.As(downloadErr, &mismatch) {
+			status = "checksum_mismatch"
+			if cfg.ProgressCh != nil {
+				cfg.ProgressCh <- events.DownloadChecksumMismatchMsg{
+					DownloadID: cfg.ID,
+					Filename:   finalFilename,
+					Algorithm:  mismatch.Algorithm,
+					Expected:   mismatch.Expected,
+					Actual:     mismatch.Actual,
+				}
+			}
+		}
+
 		// Persist error state
-		if err := state.AddToMasterList(types.DownloadEntry{
+		entry := types.DownloadEntry{
 			ID:         cfg.ID,
 			URL:        cfg.URL,
 			URLHash:    state.URLHash(cfg.URL),
 			DestPath:   destPath,
 			Filename:   finalFilename,
-			Status:     "error",
+			Status:     status,
 			TotalSize:  probe.FileSize,
 			Downloaded: cfg.State.Downloaded.Load(),
-		}); err != nil {
+			Mirrors:    cfg.Mirrors,
+			Checksum:   cfg.Checksum.String(),
+			Priority:   cfg.Priority,
+			RetryCount: cfg.RetryAttempt,
+		}
+		entry.SetError(downloadErr)
+		if err := state.AddToMasterList(entry); err != nil {
 			utils.Debug("Failed to persist error state: %v", err)
 		}
 	}
@@ -294,6 +510,28 @@ func CLIDownload(ctx context.Context, cfg *types.DownloadConfig) error {
 	return downloadErr
 }
 
+// VerifyReplacementURL checks that newURL serves the same file as the one
+// entry was downloading, so its partial can be continued from there. The
+// probe sends headers, the download's own, as resuming it would.
+func VerifyReplacementURL(ctx context.Context, entry *types.DownloadEntry, newURL string, headers map[string]string) error {
+	probe, err := engine.ProbeServer(ctx, newURL, "", headers)
+	if err != nil {
+		return err
+	}
+
+	saved, _ := state.LoadState(entry.URL, entry.DestPath)
+	if saved == nil {
+		saved = &types.DownloadState{TotalSize: entry.TotalSize}
+	}
+	if change := saved.RemoteChange(probe.FileSize, probe.ETag, probe.LastModified); change != "" {
+		return types.NewError(types.KindFileChanged, fmt.Errorf("new URL serves a different file: %s", change))
+	}
+	if entry.Downloaded > 0 && !probe.SupportsRange {
+		return fmt.Errorf("new URL does not support range requests, so the partial cannot be continued")
+	}
+	return nil
+}
+
 func Download(ctx context.Context, url, outPath string, verbose bool, progressCh chan<- any, id string) error {
 	cfg := types.DownloadConfig{
 		URL:        url,
@@ -2,10 +2,13 @@ package download
 
 import (
 	"context"
+	"errors"
 	"os"
 	"sync"
 	"time"
 
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/ratelimit"
 	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/events"
 	"concurrent_downloader/internal/state"
@@ -16,40 +19,132 @@ import (
 type activeDownload struct {
 	config types.DownloadConfig
 	cancel context.CancelFunc
+	retry  *pendingRetry // Set while a failed download waits to be retried
 }
 
+// pendingRetry is a failed download waiting for its automatic retry, or
+// for disk space when diskFull is set.
+type pendingRetry struct {
+	at       time.Time
+	err      error
+	timer    *time.Timer
+	diskFull bool
+}
+
+// diskSpaceCheckInterval is how often a download that ran out of disk
+// space checks whether the rest of it fits now.
+const diskSpaceCheckInterval = 30 * time.Second
+
 type WorkerPool struct {
-	taskChan     chan types.DownloadConfig
-	progressCh   chan<- any
-	downloads    map[string]*activeDownload      // Track active downloads for pause/resume
-	queued       map[string]types.DownloadConfig // Track queued downloads
-	mu           sync.RWMutex
-	wg           sync.WaitGroup // Ensures workers exit before shutdown completes
+	progressCh  chan<- any
+	downloads   map[string]*activeDownload      // Track active downloads for pause/resume
+	queued      map[string]types.DownloadConfig // Track queued downloads
+	order       []string                        // Queued IDs in the order they start
+	queueReady  *sync.Cond                      // Signalled on p.mu when order grows
+	mu          sync.RWMutex
+	wg          sync.WaitGroup       // Ensures workers exit before shutdown completes
+	connections *connlimit.Allocator // Connection budget shared by all running downloads
+	hosts       *connlimit.Hosts     // Per-origin connection limit shared by all running downloads
+	bandwidth   *ratelimit.Limiter   // Global speed cap; every download's limiter waits on it
+
+	// Concurrency limit, adjustable at runtime. A worker holds a slot while
+	// its download runs; more workers are started when the limit grows and
+	// surplus ones exit once their download is done.
+	slotMu       sync.Mutex
+	slotFree     *sync.Cond
 	maxDownloads int
+	workers      int
+	running      int
 }
 
 func NewWorkerPool(progressCh chan<- any, maxDownloads int) *WorkerPool {
-	if maxDownloads < 1 {
-		maxDownloads = 3 // Default to 3 if invalid
-	}
 	pool := &WorkerPool{
-		taskChan:     make(chan types.DownloadConfig, 100), // Buffered to avoid UI/CLI blocking on Add
-		progressCh:   progressCh,
-		downloads:    make(map[string]*activeDownload),
-		queued:       make(map[string]types.DownloadConfig),
-		maxDownloads: maxDownloads,
+		progressCh:  progressCh,
+		downloads:   make(map[string]*activeDownload),
+		queued:      make(map[string]types.DownloadConfig),
+		connections: connlimit.New(0),
+		hosts:       connlimit.NewHosts(0),
+		bandwidth:   ratelimit.New(0),
+	}
+	pool.slotFree = sync.NewCond(&pool.slotMu)
+	pool.queueReady = sync.NewCond(&pool.mu)
+	pool.SetMaxDownloads(maxDownloads)
+	return pool
+}
+
+// SetMaxDownloads changes how many downloads may run at once (values below 1
+// fall back to 3). Lowering it lets running downloads finish; queued ones
+// wait until the count drops under the new limit.
+func (p *WorkerPool) SetMaxDownloads(n int) {
+	if n < 1 {
+		n = 3 // Default to 3 if invalid
 	}
-	for i := 0; i < maxDownloads; i++ {
-		go pool.worker()
+
+	p.slotMu.Lock()
+	p.maxDownloads = n
+	for p.workers < n {
+		p.workers++
+		go p.worker()
 	}
-	return pool
+	p.slotFree.Broadcast()
+	p.slotMu.Unlock()
+
+	// Wake idle workers so surplus ones give up their slot.
+	p.mu.Lock()
+	p.queueReady.Broadcast()
+	p.mu.Unlock()
+}
+
+// MaxDownloads returns the current concurrency limit.
+func (p *WorkerPool) MaxDownloads() int {
+	p.slotMu.Lock()
+	defer p.slotMu.Unlock()
+	return p.maxDownloads
+}
+
+// acquireSlot blocks until fewer than maxDownloads downloads are running.
+// It returns false when the calling worker is surplus and should exit.
+func (p *WorkerPool) acquireSlot() bool {
+	p.slotMu.Lock()
+	defer p.slotMu.Unlock()
+	for p.running >= p.maxDownloads {
+		if p.workers > p.maxDownloads {
+			p.workers--
+			return false
+		}
+		p.slotFree.Wait()
+	}
+	p.running++
+	return true
+}
+
+// overLimit reports whether more slots are held than the current limit
+// allows, i.e. the limit was lowered while a worker sat idle.
+func (p *WorkerPool) overLimit() bool {
+	p.slotMu.Lock()
+	defer p.slotMu.Unlock()
+	return p.running > p.maxDownloads
+}
+
+func (p *WorkerPool) releaseSlot() {
+	p.slotMu.Lock()
+	defer p.slotMu.Unlock()
+	p.running--
+	p.slotFree.Signal()
 }
 
 // Add adds a new download task to the pool
 func (p *WorkerPool) Add(cfg types.DownloadConfig) {
+	// The limiter lives with the config so pause/resume keeps its rate.
+	if cfg.Bandwidth == nil {
+		cfg.Bandwidth = p.bandwidth.Child(cfg.RateLimit)
+	}
+
 	p.mu.Lock()
 	p.queued[cfg.ID] = cfg
+	p.insertQueued(cfg.ID, cfg.Priority)
 	p.mu.Unlock()
+	p.saveQueueOrder()
 
 	if p.progressCh != nil && !cfg.IsResume {
 		p.progressCh <- events.DownloadQueuedMsg{
@@ -57,8 +152,6 @@ func (p *WorkerPool) Add(cfg types.DownloadConfig) {
 			Filename:   cfg.Filename,
 		}
 	}
-
-	p.taskChan <- cfg
 }
 
 // HasDownload checks if a download with the given URL already exists
@@ -125,12 +218,98 @@ func (p *WorkerPool) GetAll() []types.DownloadConfig {
 		}
 		configs = append(configs, cfg)
 	}
-	for _, cfg := range p.queued {
-		configs = append(configs, cfg)
+	for _, id := range p.order {
+		// A resumed download is still tracked as active until it restarts.
+		if _, active := p.downloads[id]; !active {
+			configs = append(configs, p.queued[id])
+		}
 	}
 	return configs
 }
 
+// SetRateLimit changes the speed cap in bytes/sec (0 = unlimited) of a queued
+// or running download, or of all downloads together when downloadID is empty.
+// Running transfers apply it on their next read. Returns false if the
+// download is not in the pool.
+func (p *WorkerPool) SetRateLimit(downloadID string, bytesPerSec int64) bool {
+	if downloadID == "" {
+		p.bandwidth.SetRate(bytesPerSec)
+		return true
+	}
+
+	// The limiter is shared by every copy of the config, so updating it is enough.
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if ad, ok := p.downloads[downloadID]; ok {
+		ad.config.Bandwidth.SetRate(bytesPerSec)
+		return true
+	}
+	if cfg, ok := p.queued[downloadID]; ok {
+		cfg.Bandwidth.SetRate(bytesPerSec)
+		return true
+	}
+	return false
+}
+
+// Headers returns the custom headers (cookies, auth, etc.) a download in the
+// pool sends, or nil if it is not in the pool.
+func (p *WorkerPool) Headers(downloadID string) map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if ad, ok := p.downloads[downloadID]; ok {
+		return ad.config.Headers
+	}
+	if cfg, ok := p.queued[downloadID]; ok {
+		return cfg.Headers
+	}
+	return nil
+}
+
+// ReplaceURL points a paused, queued or retrying download at newURL. The old
+// URL is dropped from its mirrors. Returns false if the download is not in
+// the pool, and an error if it is running.
+func (p *WorkerPool) ReplaceURL(downloadID string, newURL string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	replace := func(cfg *types.DownloadConfig) {
+		mirrors := cfg.Mirrors[:0:0]
+		for _, m := range cfg.Mirrors {
+			if m != cfg.URL && m != newURL {
+				mirrors = append(mirrors, m)
+			}
+		}
+		cfg.Mirrors = mirrors
+		cfg.URL = newURL
+	}
+
+	if cfg, ok := p.queued[downloadID]; ok {
+		replace(&cfg)
+		p.queued[downloadID] = cfg
+		return true, nil
+	}
+	ad, ok := p.downloads[downloadID]
+	if !ok {
+		return false, nil
+	}
+	if ad.retry == nil && (ad.config.State == nil || !ad.config.State.IsPaused()) {
+		return true, errors.New("download is running; pause it before changing its URL")
+	}
+	oldURL := ad.config.URL
+	replace(&ad.config)
+	if ad.config.State != nil {
+		// Listings read the mirror set from the state until the download restarts.
+		ms := ad.config.State.GetMirrors()
+		for i := range ms {
+			if ms[i].URL == oldURL {
+				ms[i] = types.MirrorStatus{URL: newURL, Active: true}
+			}
+		}
+		ad.config.State.SetMirrors(ms)
+	}
+	return true, nil
+}
+
 // Pause pauses a specific download by ID. Returns true if found and pause initiated (or already paused), false otherwise.
 func (p *WorkerPool) Pause(downloadID string) bool {
 	p.mu.RLock()
@@ -141,6 +320,28 @@ func (p *WorkerPool) Pause(downloadID string) bool {
 		return false
 	}
 
+	if p.stopRetry(ad) {
+		// Nothing is running; park it as paused. A later resume is the
+		// user's call, so it starts with a fresh retry budget.
+		ad.config.RetryAttempt = 0
+		downloaded := int64(0)
+		if ad.config.State != nil {
+			ad.config.State.Pause()
+			downloaded = ad.config.State.VerifiedProgress.Load()
+		}
+		if err := state.UpdateStatus(downloadID, "paused"); err != nil {
+			utils.Debug("Failed to mark %s paused: %v", downloadID, err)
+		}
+		if p.progressCh != nil {
+			p.progressCh <- events.DownloadPausedMsg{
+				DownloadID: downloadID,
+				Filename:   ad.config.Filename,
+				Downloaded: downloaded,
+			}
+		}
+		return true
+	}
+
 	// Set paused flag and cancel context
 	if ad.config.State != nil {
 		// Idempotency: If already pausing or paused, do nothing.
@@ -171,8 +372,9 @@ func (p *WorkerPool) PauseAll() {
 	p.mu.RLock()
 	ids := make([]string, 0, len(p.downloads)) // This stores the uuids of the downloads to be paused
 	for id, ad := range p.downloads {
-		// Only pause downloads that are actually active (not already paused or done or pausing)
-		if ad != nil && ad.config.State != nil && !ad.config.State.IsPaused() && !ad.config.State.Done.Load() && !ad.config.State.IsPausing() {
+		// Only pause downloads that are actually active (not already paused or done or pausing).
+		// Pending retries stay "retrying" in the database and are re-armed on restart.
+		if ad != nil && ad.retry == nil && ad.config.State != nil && !ad.config.State.IsPaused() && !ad.config.State.Done.Load() && !ad.config.State.IsPausing() {
 			ids = append(ids, id)
 		}
 	}
@@ -190,11 +392,28 @@ func (p *WorkerPool) Cancel(downloadID string) {
 	if exists {
 		delete(p.downloads, downloadID)
 	}
+	qCfg, wasQueued := p.queued[downloadID]
+	if wasQueued && p.removeQueued(downloadID) {
+		delete(p.queued, downloadID)
+	} else {
+		wasQueued = false
+	}
 	p.mu.Unlock()
 
+	if wasQueued {
+		p.saveQueueOrder()
+		if !exists && p.progressCh != nil {
+			p.progressCh <- events.DownloadRemovedMsg{
+				DownloadID: downloadID,
+				Filename:   qCfg.Filename,
+			}
+		}
+	}
+
 	if !exists || ad == nil {
 		return
 	}
+	p.stopRetry(ad)
 
 	// Cancel the context to stop workers
 	if ad.cancel != nil {
@@ -208,8 +427,10 @@ func (p *WorkerPool) Cancel(downloadID string) {
 
 	// Best-effort cleanup of active partial file.
 	// This handles cancels before paused state is persisted in DB.
-	if ad.config.State != nil && ad.config.State.DestPath != "" {
-		_ = os.Remove(ad.config.State.DestPath + types.IncompleteSuffix)
+	if ad.config.State != nil {
+		if workingPath := ad.config.State.GetWorkingPath(); workingPath != "" {
+			_ = os.Remove(workingPath)
+		}
 	}
 
 	// Send removal message
@@ -231,6 +452,12 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 		return false
 	}
 
+	// Resuming a download that waits for its retry retries it now.
+	if ad.retry != nil {
+		p.retryNow(downloadID)
+		return true
+	}
+
 	// Prevent race: Don't resume if still pausing.
 	if ad.config.State != nil && ad.config.State.IsPausing() {
 		utils.Debug("Resume ignored: download %s is still pausing", downloadID)
@@ -260,6 +487,7 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 
 	// Re-queue the download
 	ad.config.IsResume = true
+	ad.config.RetryAttempt = 0
 	p.Add(ad.config)
 
 	// Send resume message
@@ -273,21 +501,46 @@ func (p *WorkerPool) Resume(downloadID string) bool {
 }
 
 func (p *WorkerPool) worker() {
-	for cfg := range p.taskChan {
-		p.wg.Add(1)
+	for {
+		// Take a slot before picking work so the highest-priority download
+		// is chosen when one can actually start.
+		if !p.acquireSlot() {
+			utils.Debug("WorkerPool: worker exiting, limit lowered to %d", p.MaxDownloads())
+			return
+		}
+
+		p.mu.Lock()
+		for len(p.order) == 0 && !p.overLimit() {
+			p.queueReady.Wait()
+		}
+		if p.overLimit() {
+			// Hand the slot back; acquireSlot retires this worker.
+			p.mu.Unlock()
+			p.releaseSlot()
+			continue
+		}
+
 		// Create cancellable context
 		ctx, cancel := context.WithCancel(context.Background())
 
 		// Register active download
-		ad := &activeDownload{
-			config: cfg,
-			cancel: cancel,
-		}
-		p.mu.Lock()
+		ad := &activeDownload{cancel: cancel}
+		cfg := p.queued[p.order[0]]
+		p.order = p.order[1:]
 		delete(p.queued, cfg.ID)
+		ad.config = cfg
 		p.downloads[cfg.ID] = ad
+		p.wg.Add(1)
 		p.mu.Unlock()
 
+		// Apply the latest connection limits; running downloads rebalance.
+		if cfg.Runtime != nil {
+			p.connections.SetLimit(cfg.Runtime.MaxGlobalConnections)
+			p.hosts.SetLimit(cfg.Runtime.GetMaxConnectionsPerHost())
+		}
+		ad.config.Connections = p.connections
+		ad.config.Hosts = p.hosts
+
 		err := CLIDownload(ctx, &ad.config)
 
 		// Logic:
@@ -304,21 +557,36 @@ func (p *WorkerPool) worker() {
 		if isPaused {
 			utils.Debug("WorkerPool: Download %s paused cleanly", cfg.ID)
 			// If paused, we keep it in downloads map for potential resume
+		} else if err != nil && p.waitForSpace(ad, err) {
+			// Kept in p.downloads until there is room for the rest of it.
+		} else if err != nil && p.scheduleRetry(ad, err) {
+			// Kept in p.downloads until the retry re-queues it.
 		} else if err != nil {
 			if cfg.State != nil {
 				cfg.State.SetError(err)
 			}
-			if p.progressCh != nil {
+			// Clean up errored download from tracking (don't save to .GoFetch),
+			// before telling anyone who might act on it right away.
+			p.mu.Lock()
+			delete(p.downloads, cfg.ID)
+			p.mu.Unlock()
+
+			if p.progressCh != nil && types.NeedsNewURL(err) {
+				de := types.Classify(err)
+				p.progressCh <- events.DownloadNeedsURLMsg{
+					DownloadID: cfg.ID,
+					Filename:   cfg.Filename,
+					URL:        cfg.URL,
+					StatusCode: de.StatusCode,
+					Reason:     de.Error(),
+				}
+			} else if p.progressCh != nil {
 				p.progressCh <- events.DownloadErrorMsg{
 					DownloadID: cfg.ID,
 					Filename:   cfg.Filename,
-					Err:        err,
+					Err:        types.Classify(err),
 				}
 			}
-			// Clean up errored download from tracking (don't save to .GoFetch).
-			p.mu.Lock()
-			delete(p.downloads, cfg.ID)
-			p.mu.Unlock()
 
 		} else if !isPaused {
 			// Only mark as done if not paused
@@ -333,10 +601,196 @@ func (p *WorkerPool) worker() {
 			p.mu.Unlock()
 		}
 		// If paused, we keep it in downloads map for potential resume
+		p.releaseSlot()
 		p.wg.Done()
 	}
 }
 
+// scheduleRetry arms an automatic retry for a download that failed with
+// err, if the retry policy allows another attempt. The download keeps its
+// resolved path, so the retry continues from the partial file.
+func (p *WorkerPool) scheduleRetry(ad *activeDownload, err error) bool {
+	attempt := ad.config.RetryAttempt + 1
+	delay, ok := ad.config.Runtime.RetryDelay(err, attempt)
+	if !ok {
+		return false
+	}
+
+	id := ad.config.ID
+	keepResolvedPath(&ad.config)
+
+	at := time.Now().Add(delay)
+	p.mu.Lock()
+	if p.downloads[id] != ad {
+		// Removed while it was failing.
+		p.mu.Unlock()
+		return false
+	}
+	ad.config.IsResume = true
+	ad.config.RetryAttempt = attempt
+	ad.retry = &pendingRetry{at: at, err: err}
+	ad.retry.timer = time.AfterFunc(delay, func() { p.retryNow(id) })
+	p.mu.Unlock()
+
+	utils.Debug("WorkerPool: Download %s failed (%v), retry %d/%d in %v", id, err, attempt, ad.config.Runtime.DownloadRetries, delay)
+	if err := state.UpdateRetry(id, attempt, at.Unix()); err != nil {
+		utils.Debug("Failed to persist retry of %s: %v", id, err)
+	}
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadRetryScheduledMsg{
+			DownloadID:  id,
+			Filename:    ad.config.Filename,
+			Attempt:     attempt,
+			MaxAttempts: ad.config.Runtime.DownloadRetries,
+			RetryAt:     at,
+			Kind:        types.Classify(err).Kind,
+			Reason:      err.Error(),
+		}
+	}
+	return true
+}
+
+// keepResolvedPath points cfg at the file its last run resolved, so a
+// re-queued download continues the partial it left.
+func keepResolvedPath(cfg *types.DownloadConfig) {
+	if cfg.State == nil {
+		return
+	}
+	if destPath := cfg.State.GetDestPath(); destPath != "" {
+		cfg.DestPath = destPath
+	}
+	if filename := cfg.State.GetFilename(); filename != "" {
+		cfg.Filename = filename
+	}
+}
+
+// waitForSpace parks a download that ran out of disk space until the rest
+// of it fits, checking every diskSpaceCheckInterval. Unlike a retry, the
+// wait uses no attempt and has no end.
+func (p *WorkerPool) waitForSpace(ad *activeDownload, err error) bool {
+	if !types.IsDiskFull(err) {
+		return false
+	}
+
+	id := ad.config.ID
+	keepResolvedPath(&ad.config)
+
+	p.mu.Lock()
+	if p.downloads[id] != ad {
+		// Removed while it was failing.
+		p.mu.Unlock()
+		return false
+	}
+	ad.config.IsResume = true
+	p.armSpaceCheck(ad, err)
+	p.mu.Unlock()
+
+	utils.Debug("WorkerPool: Download %s is waiting for disk space: %v", id, err)
+	if p.progressCh != nil {
+		p.progressCh <- events.DownloadDiskFullMsg{
+			DownloadID: id,
+			Filename:   ad.config.Filename,
+			DestPath:   ad.config.DestPath,
+			Reason:     err.Error(),
+		}
+	}
+	return true
+}
+
+// armSpaceCheck schedules the next disk space check of ad. Caller holds p.mu.
+func (p *WorkerPool) armSpaceCheck(ad *activeDownload, err error) {
+	wait := &pendingRetry{at: time.Now().Add(diskSpaceCheckInterval), err: err, diskFull: true}
+	wait.timer = time.AfterFunc(diskSpaceCheckInterval, func() { p.checkSpace(ad, wait) })
+	ad.retry = wait
+}
+
+// checkSpace re-queues a download waiting for disk space once the rest of
+// it fits, and waits another round otherwise.
+func (p *WorkerPool) checkSpace(ad *activeDownload, wait *pendingRetry) {
+	p.mu.RLock()
+	current := p.downloads[ad.config.ID] == ad && ad.retry == wait
+	cfg := ad.config
+	p.mu.RUnlock()
+	if !current {
+		// Paused, resumed or removed since.
+		return
+	}
+
+	var total int64
+	if cfg.State != nil {
+		_, total, _, _, _, _ = cfg.State.GetProgress()
+	}
+	workingPath := cfg.DestPath + types.IncompleteSuffix
+	if cfg.State != nil {
+		workingPath = cfg.State.GetWorkingPath()
+	}
+	if err := checkDiskSpace(workingPath, cfg.DestPath, total, cfg.Runtime.GetDiskReserve()); err != nil {
+		p.mu.Lock()
+		if p.downloads[cfg.ID] == ad && ad.retry == wait {
+			p.armSpaceCheck(ad, err)
+		}
+		p.mu.Unlock()
+		return
+	}
+	utils.Debug("WorkerPool: Disk space available again for %s", cfg.ID)
+	p.retryNow(cfg.ID)
+}
+
+// retryNow re-queues a download waiting for its retry.
+func (p *WorkerPool) retryNow(id string) {
+	p.mu.Lock()
+	ad, ok := p.downloads[id]
+	if !ok || ad.retry == nil {
+		p.mu.Unlock()
+		return
+	}
+	ad.retry.timer.Stop()
+	ad.retry = nil
+	cfg := ad.config
+	p.mu.Unlock()
+
+	if err := state.UpdateStatus(id, "queued"); err != nil {
+		utils.Debug("Failed to mark %s queued: %v", id, err)
+	}
+	p.Add(cfg)
+}
+
+// stopRetry cancels a pending retry. It reports whether one was pending.
+func (p *WorkerPool) stopRetry(ad *activeDownload) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ad.retry == nil {
+		return false
+	}
+	ad.retry.timer.Stop()
+	ad.retry = nil
+	return true
+}
+
+// Retrying returns the attempt number, due time and cause of a download
+// waiting for an automatic retry, or a zero attempt if it is not waiting.
+func (p *WorkerPool) Retrying(id string) (attempt int, at time.Time, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	ad, ok := p.downloads[id]
+	if !ok || ad.retry == nil || ad.retry.diskFull {
+		return 0, time.Time{}, nil
+	}
+	return ad.config.RetryAttempt, ad.retry.at, ad.retry.err
+}
+
+// WaitingForSpace returns the cause if a download is waiting for disk
+// space, or nil if it is not.
+func (p *WorkerPool) WaitingForSpace(id string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	ad, ok := p.downloads[id]
+	if !ok || ad.retry == nil || !ad.retry.diskFull {
+		return nil
+	}
+	return ad.retry.err
+}
+
 // GetStatus returns the status of an active download
 func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	p.mu.RLock()
@@ -356,6 +810,9 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 			Status:     "queued",
 			Downloaded: 0,
 			TotalSize:  0, // Metadata not yet fetched
+			RateLimit:  qCfg.Bandwidth.Rate(),
+			Priority:   qCfg.Priority,
+			QueuePos:   p.QueuePosition(id),
 		}
 	}
 
@@ -381,6 +838,16 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 		Downloaded: downloaded,
 		Status:     "downloading",
 	}
+	status.RateLimit = ad.config.Bandwidth.Rate()
+	status.Priority = ad.config.Priority
+	origins := append([]string{ad.config.URL}, ad.config.Mirrors...)
+	status.HostConnections = p.hosts.Usage(origins...)
+	if until := p.hosts.BackoffUntil(origins...); !until.IsZero() {
+		status.BackoffUntil = until.Unix()
+	}
+	if writes := state.GetWriteStats(); writes.Writes > 0 {
+		status.Writes = &writes
+	}
 
 	if ad.config.State.IsPausing() {
 		status.Status = "pausing"
@@ -389,10 +856,23 @@ func (p *WorkerPool) GetStatus(id string) *types.DownloadStatus {
 	} else if state.Done.Load() {
 		status.Status = "completed"
 	}
+	status.RetryCount = ad.config.RetryAttempt
+	if attempt, at, err := p.Retrying(id); attempt > 0 {
+		status.Status = "retrying"
+		status.NextRetryAt = at.Unix()
+		status.SetError(err)
+	}
+	if err := p.WaitingForSpace(id); err != nil {
+		status.Status = "disk_full"
+		status.SetError(err)
+	}
 
 	if err := state.GetError(); err != nil {
 		status.Status = "error"
-		status.Error = err.Error()
+		if errors.Is(err, types.ErrChecksumMismatch) {
+			status.Status = "checksum_mismatch"
+		}
+		status.SetError(err)
 	}
 
 	// Calculate progress
@@ -417,6 +897,15 @@ func (p *WorkerPool) GracefulShutdown() {
 	// ... existing implementation
 	p.PauseAll()
 
+	// Pending retries are re-armed from the database on the next start.
+	p.mu.Lock()
+	for _, ad := range p.downloads {
+		if ad.retry != nil {
+			ad.retry.timer.Stop()
+		}
+	}
+	p.mu.Unlock()
+
 	// Wait for any downloads in "Pausing" state to finish transitioning.
 	// This avoids exiting while a database write is pending.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,139 @@
+package download
+
+import (
+	"concurrent_downloader/internal/state"
+	"concurrent_downloader/internal/utils"
+)
+
+// The run queue is p.order: queued IDs sorted by priority (highest first) and
+// by arrival within a priority. Moving a download adopts its new neighbours'
+// priority, so the order stays sorted and can be rebuilt from priorities
+// alone after a restart. Callers hold p.mu unless noted.
+
+// insertQueued places id after every download with the same or a higher priority.
+func (p *WorkerPool) insertQueued(id string, priority int) {
+	p.removeQueued(id)
+	pos := len(p.order)
+	for i, qid := range p.order {
+		if p.queued[qid].Priority < priority {
+			pos = i
+			break
+		}
+	}
+	p.order = append(p.order, "")
+	copy(p.order[pos+1:], p.order[pos:])
+	p.order[pos] = id
+	p.queueReady.Signal()
+}
+
+// removeQueued drops id from the run order. It reports whether id was there.
+func (p *WorkerPool) removeQueued(id string) bool {
+	for i, qid := range p.order {
+		if qid == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *WorkerPool) setPriority(id string, priority int) {
+	cfg := p.queued[id]
+	cfg.Priority = priority
+	p.queued[id] = cfg
+}
+
+// MoveQueued moves a queued download to a 1-based position in the queue;
+// positions below 1 or past the end move it to the bottom. Returns false if
+// the download is not queued.
+func (p *WorkerPool) MoveQueued(id string, position int) bool {
+	p.mu.Lock()
+	if !p.removeQueued(id) {
+		p.mu.Unlock()
+		return false
+	}
+	if position < 1 || position > len(p.order)+1 {
+		position = len(p.order) + 1
+	}
+	pos := position - 1
+
+	// Adopt the neighbours' priority so the queue stays sorted.
+	priority := p.queued[id].Priority
+	if pos < len(p.order) {
+		priority = max(priority, p.queued[p.order[pos]].Priority)
+	}
+	if pos > 0 {
+		priority = min(priority, p.queued[p.order[pos-1]].Priority)
+	}
+	p.setPriority(id, priority)
+
+	p.order = append(p.order, "")
+	copy(p.order[pos+1:], p.order[pos:])
+	p.order[pos] = id
+	p.mu.Unlock()
+
+	p.persistPriority(id, priority)
+	p.saveQueueOrder()
+	return true
+}
+
+// SwapQueued exchanges the queue positions (and priorities) of two queued
+// downloads. Returns false unless both are queued.
+func (p *WorkerPool) SwapQueued(a, b string) bool {
+	p.mu.Lock()
+	ia, ib := -1, -1
+	for i, id := range p.order {
+		switch id {
+		case a:
+			ia = i
+		case b:
+			ib = i
+		}
+	}
+	if ia < 0 || ib < 0 {
+		p.mu.Unlock()
+		return false
+	}
+	p.order[ia], p.order[ib] = b, a
+	pa, pb := p.queued[a].Priority, p.queued[b].Priority
+	p.setPriority(a, pb)
+	p.setPriority(b, pa)
+	p.mu.Unlock()
+
+	p.persistPriority(a, pb)
+	p.persistPriority(b, pa)
+	p.saveQueueOrder()
+	return true
+}
+
+// QueuePosition returns id's 1-based place in the queue, or 0 if it is not queued.
+func (p *WorkerPool) QueuePosition(id string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i, qid := range p.order {
+		if qid == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// QueueOrder returns the queued download IDs, next to start first.
+func (p *WorkerPool) QueueOrder() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.order...)
+}
+
+// saveQueueOrder persists the queue so restarts keep it. Must not hold p.mu.
+func (p *WorkerPool) saveQueueOrder() {
+	if err := state.SaveQueueOrder(p.QueueOrder()); err != nil {
+		utils.Debug("Failed to save queue order: %v", err)
+	}
+}
+
+func (p *WorkerPool) persistPriority(id string, priority int) {
+	if err := state.UpdatePriority(id, priority); err != nil {
+		utils.Debug("Failed to save priority of %s: %v", id, err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package ratelimit throttles download speed with token buckets that can be
+// chained (a download's limit under the global one) and retuned while
+// transfers are running.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// burstWindow is how much transfer time the bucket may save up. It also
+// bounds a single read, so one wait never blocks for long.
+const burstWindow = 100 * time.Millisecond
+
+// minRead keeps very low rates from degenerating into tiny reads.
+const minRead = 1024
+
+// Limiter is a token bucket measured in bytes per second. Readers take tokens
+// before reading; a read that overdraws the bucket waits until it is paid off.
+//
+// All methods are safe to call on a nil *Limiter, which means "no limit".
+type Limiter struct {
+	parent *Limiter
+
+	mu      sync.Mutex
+	rate    float64 // Bytes per second, 0 = unlimited
+	tokens  float64 // May go negative while a reader waits
+	last    time.Time
+	changed chan struct{} // Closed and replaced when the rate changes
+}
+
+// New returns a limiter for bytesPerSec; bytesPerSec <= 0 means unlimited.
+func New(bytesPerSec int64) *Limiter {
+	l := &Limiter{changed: make(chan struct{})}
+	l.SetRate(bytesPerSec)
+	return l
+}
+
+// Child returns a limiter for bytesPerSec whose readers also wait on l.
+func (l *Limiter) Child(bytesPerSec int64) *Limiter {
+	c := New(bytesPerSec)
+	c.parent = l
+	return c
+}
+
+// SetRate changes the limit. Readers already waiting pick it up immediately.
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	if l == nil {
+		return
+	}
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = float64(bytesPerSec)
+	if l.rate == 0 {
+		l.tokens = 0
+	} else if burst := l.burst(); l.tokens > burst {
+		l.tokens = burst
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Rate returns the limiter's own limit in bytes per second (0 = unlimited).
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int64(l.rate)
+}
+
+// Limited reports whether l or any limiter above it has a limit.
+func (l *Limiter) Limited() bool {
+	for ; l != nil; l = l.parent {
+		if l.Rate() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadSize caps n to what the tightest limit in the chain lets through in one
+// burst window.
+func (l *Limiter) ReadSize(n int) int {
+	for ; l != nil; l = l.parent {
+		l.mu.Lock()
+		if l.rate > 0 {
+			if b := int(l.burst()); b < n {
+				n = max(b, minRead)
+			}
+		}
+		l.mu.Unlock()
+	}
+	return n
+}
+
+// WaitN takes n bytes worth of tokens from l and every limiter above it,
+// blocking until each can pay for them or ctx is done.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	for ; l != nil; l = l.parent {
+		if err := l.wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.refill(time.Now())
+	l.tokens -= float64(n)
+
+	for {
+		if l.rate == 0 || l.tokens >= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		l.mu.Lock()
+		l.refill(time.Now())
+	}
+}
+
+// refill adds the tokens earned since the last call. Callers hold l.mu.
+func (l *Limiter) refill(now time.Time) {
+	if !l.last.IsZero() && l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if burst := l.burst(); l.tokens > burst {
+			l.tokens = burst
+		}
+	}
+	l.last = now
+}
+
+// burst is the most the bucket holds. Callers hold l.mu.
+func (l *Limiter) burst() float64 {
+	return l.rate * burstWindow.Seconds()
+}
+
+// ParseRate parses a rate such as "500K", "2M", "1.5MB/s" or a plain number of
+// bytes per second. Suffixes are binary (K = 1024). "0" means unlimited.
+func ParseRate(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	v := strings.ToUpper(raw)
+	v = strings.TrimSuffix(v, "/S")
+	v = strings.TrimSuffix(v, "B")
+
+	multiplier := 1.0
+	if v != "" {
+		switch v[len(v)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			v = v[:len(v)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid rate %q (use e.g. 500K, 2M or 0 for unlimited)", raw)
+	}
+	return int64(n * multiplier), nil
+}
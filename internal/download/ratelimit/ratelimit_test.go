@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1000", 1000, false},
+		{"500K", 500 << 10, false},
+		{"500k", 500 << 10, false},
+		{"2M", 2 << 20, false},
+		{"1.5MB/s", 3 << 19, false},
+		{" 1G ", 1 << 30, false},
+		{"64KB", 64 << 10, false},
+		{"10B", 10, false},
+		{"", 0, true},
+		{"K", 0, true},
+		{"-1M", 0, true},
+		{"fast", 0, true},
+		{"Inf", 0, true},
+		{"NaN", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestReadSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent int64
+		child  int64
+		n      int
+		want   int
+	}{
+		{"unlimited", 0, 0, 32 << 10, 32 << 10},
+		{"capped to burst", 0, 100 << 10, 32 << 10, 10 << 10},
+		{"below burst", 0, 100 << 10, 4 << 10, 4 << 10},
+		{"parent is tighter", 50 << 10, 100 << 10, 32 << 10, 5 << 10},
+		{"minimum read", 0, 1000, 32 << 10, minRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.parent).Child(tt.child)
+			if got := l.ReadSize(tt.n); got != tt.want {
+				t.Errorf("ReadSize(%d) = %d, want %d", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitN(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		limiter func() *Limiter // Built in the subtest so its bucket starts empty
+		min     time.Duration
+	}{
+		{"nil", func() *Limiter { return nil }, 0},
+		{"unlimited", func() *Limiter { return New(0) }, 0},
+		{"own limit", func() *Limiter { return New(100 << 10) }, 80 * time.Millisecond},
+		{"parent limit", func() *Limiter { return New(100 << 10).Child(0) }, 80 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.limiter()
+			start := time.Now()
+			// 10K at 100K/s from an empty bucket takes about 100ms.
+			if err := l.WaitN(ctx, 10<<10); err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || (tt.min == 0 && elapsed > 50*time.Millisecond) {
+				t.Errorf("WaitN took %v, want about %v", elapsed, tt.min)
+			}
+		})
+	}
+}
+
+func TestWaitNCancel(t *testing.T) {
+	l := New(1 << 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 1<<20); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitN error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSetRateWakesWaiters(t *testing.T) {
+	l := New(1 << 10)
+	done := make(chan error)
+	go func() { done <- l.WaitN(context.Background(), 1<<20) }()
+
+	time.Sleep(10 * time.Millisecond)
+	l.SetRate(0)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WaitN = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitN still blocked after the limit was lifted")
+	}
+}
+
+func TestLimited(t *testing.T) {
+	var nilLimiter *Limiter
+	if nilLimiter.Limited() || nilLimiter.Rate() != 0 {
+		t.Error("nil limiter is limited")
+	}
+	nilLimiter.SetRate(10) // No-op
+
+	parent := New(0)
+	child := parent.Child(0)
+	if child.Limited() {
+		t.Error("unlimited chain reports a limit")
+	}
+	parent.SetRate(1 << 20)
+	if !child.Limited() || child.Rate() != 0 {
+		t.Errorf("child Limited = %v, Rate = %d; want limited through the parent with no own rate", child.Limited(), child.Rate())
+	}
+	child.SetRate(-5)
+	if child.Rate() != 0 {
+		t.Errorf("negative rate stored as %d, want 0", child.Rate())
+	}
+}
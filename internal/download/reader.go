@@ -0,0 +1,231 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/state"
+)
+
+// followPoll bounds how long a follow reader waits before looking again at
+// a download that may have stopped or finished without writing more.
+const followPoll = 500 * time.Millisecond
+
+// Open returns a reader of download id's file that follows the download: it
+// reads the bytes as they reach disk and blocks until the ones at its offset
+// do. Seeking is allowed anywhere; a chunked download fetches the bytes a
+// reader waits for first. A completed download reads the finished file.
+// Reading fails once the download fails or is removed.
+func (p *WorkerPool) Open(id string) (io.ReadSeekCloser, error) {
+	if p.progressState(id) == nil {
+		if entry, err := state.GetDownload(id); err != nil || entry == nil {
+			return nil, fmt.Errorf("download %s not found", id)
+		}
+	}
+	return &followReader{pool: p, id: id, closed: make(chan struct{})}, nil
+}
+
+// progressState returns the live state of a queued or running download.
+func (p *WorkerPool) progressState(id string) *types.ProgressState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if ad, ok := p.downloads[id]; ok {
+		return ad.config.State
+	}
+	if cfg, ok := p.queued[id]; ok {
+		return cfg.State
+	}
+	return nil
+}
+
+// followReader reads a download's working file behind its workers, and the
+// finished file once it completes.
+type followReader struct {
+	pool *WorkerPool
+	id   string
+
+	mu     sync.Mutex // Protects offset, file and path against Close
+	offset int64
+	file   *os.File
+	path   string
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// followView is what a follow reader can read right now.
+type followView struct {
+	avail int64           // Bytes on disk from the read offset on
+	size  int64           // File size, 0 while unknown
+	path  string          // File holding them
+	wake  <-chan struct{} // Closed once more may be on disk; nil to poll
+}
+
+func (r *followReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for {
+		v, err := r.wait(func(v followView) bool {
+			return v.avail > 0 || (v.size > 0 && r.getOffset() >= v.size)
+		})
+		if err != nil {
+			return 0, err
+		}
+		if v.avail == 0 {
+			return 0, io.EOF
+		}
+
+		n, err := r.readAt(b[:min(int64(len(b)), v.avail)], v.path)
+		// A partial moved into place since the check is read from its new
+		// path on the next round.
+		if os.IsNotExist(err) {
+			continue
+		}
+		return n, err
+	}
+}
+
+// Seek sets the read offset. Seeking from the end waits until the size of
+// the file is known.
+func (r *followReader) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = r.getOffset()
+	case io.SeekEnd:
+		v, err := r.wait(func(v followView) bool { return v.size > 0 })
+		if err != nil {
+			return 0, err
+		}
+		base = v.size
+	default:
+		return 0, errors.New("seek: invalid whence")
+	}
+	if base+offset < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.offset = base + offset
+	return r.offset, nil
+}
+
+// wait looks at the download until ready accepts what it sees, it fails or
+// the reader is closed.
+func (r *followReader) wait(ready func(followView) bool) (followView, error) {
+	for {
+		select {
+		case <-r.closed:
+			return followView{}, os.ErrClosed
+		default:
+		}
+
+		v, err := r.available()
+		if err != nil || ready(v) {
+			return v, err
+		}
+
+		timer := time.NewTimer(followPoll)
+		select {
+		case <-v.wake:
+		case <-timer.C:
+		case <-r.closed:
+		}
+		timer.Stop()
+	}
+}
+
+// available reports what can be read from the read offset now. While the
+// download runs, it also tells the download where this reader waits.
+func (r *followReader) available() (followView, error) {
+	offset := r.getOffset()
+	if ps := r.pool.progressState(r.id); ps != nil && !ps.Done.Load() {
+		if err := ps.GetError(); err != nil {
+			attempt, _, _ := r.pool.Retrying(r.id)
+			if attempt == 0 && r.pool.WaitingForSpace(r.id) == nil {
+				return followView{}, err
+			}
+		}
+		ps.SetReadPosition(r, offset)
+		n, wake := ps.WrittenAt(offset)
+		_, size, _, _, _, _ := ps.GetProgress()
+		return followView{avail: n, size: size, path: ps.GetWorkingPath(), wake: wake}, nil
+	}
+
+	entry, err := state.GetDownload(r.id)
+	if err != nil || entry == nil {
+		return followView{}, fmt.Errorf("download %s was removed", r.id)
+	}
+	switch entry.Status {
+	case "completed":
+		info, err := os.Stat(entry.DestPath)
+		if err != nil {
+			return followView{}, err
+		}
+		return followView{avail: max(info.Size()-offset, 0), size: info.Size(), path: entry.DestPath}, nil
+	case "error", "checksum_mismatch":
+		return followView{}, fmt.Errorf("download %s failed: %s", r.id, entry.Error)
+	}
+	// Paused or waiting to run again.
+	return followView{size: entry.TotalSize}, nil
+}
+
+// readAt reads b at the read offset from path and moves the offset on.
+func (r *followReader) readAt(b []byte, path string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return 0, os.ErrClosed
+	default:
+	}
+	if r.file == nil || r.path != path {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		if r.file != nil {
+			_ = r.file.Close()
+		}
+		r.file, r.path = f, path
+	}
+
+	n, err := r.file.ReadAt(b, r.offset)
+	r.offset += int64(n)
+	if n > 0 && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *followReader) getOffset() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offset
+}
+
+// Close ends the reader, waking a Read or Seek that waits.
+func (r *followReader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	if ps := r.pool.progressState(r.id); ps != nil {
+		ps.ClearReadPosition(r)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
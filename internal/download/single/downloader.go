@@ -2,31 +2,50 @@ package single
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/httpclient"
+	"concurrent_downloader/internal/download/integrity"
+	"concurrent_downloader/internal/download/ratelimit"
 	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/events"
+	"concurrent_downloader/internal/state"
 	"concurrent_downloader/internal/utils"
 )
 
-// SingleDownloader handles single-threaded downloads for servers that don't support range requests.
-// NOTE: Pause/resume is NOT supported because this downloader is only used when
-// the server doesn't support Range headers. If interrupted, the download must restart.
+// SingleDownloader handles single-connection downloads. It is used for servers
+// that don't support range requests, and when single mode is forced.
+// When the server does honour ranges (Resumable), the partial file survives a
+// pause or a dropped connection and the next attempt continues from its offset
+// with Range and If-Range; otherwise an interrupted download restarts.
 type SingleDownloader struct {
-	Client       *http.Client
 	ProgressChan chan<- any           // Channel for events (start/complete/error)
 	ID           string               // Download ID
 	State        *types.ProgressState // Shared state for TUI polling
 	Runtime      *types.RuntimeConfig
+	Checksum     *types.Checksum    // Expected digest, verified before the final rename
+	Resumable    bool               // Server supports Range requests, so partials can be resumed
+	Headers      map[string]string  // Custom HTTP headers from browser (cookies, auth, etc.)
+	Hosts        *connlimit.Hosts   // Per-origin connection limit shared with other downloads (nil = unlimited)
+	Bandwidth    *ratelimit.Limiter // Speed cap for this download (nil = unlimited)
+	WorkingPath  string             // Partial file, moved to destPath on completion (default: destPath + IncompleteSuffix)
+
+	// Protocols the server advertised during the probe
+	SupportsHTTP2 bool
+	SupportsHTTP3 bool
 }
 
 // NewSingleDownloader creates a new single-threaded downloader with all required parameters
 func NewSingleDownloader(id string, progressCh chan<- any, state *types.ProgressState, runtime *types.RuntimeConfig) *SingleDownloader {
 	return &SingleDownloader{
-		Client:       &http.Client{Timeout: 0},
 		ProgressChan: progressCh,
 		ID:           id,
 		State:        state,
@@ -35,103 +54,291 @@ func NewSingleDownloader(id string, progressCh chan<- any, state *types.Progress
 }
 
 // Download downloads a file using a single connection.
-// This is used for servers that don't support Range requests.
-// If interrupted, the download cannot be resumed and must restart from the beginning.
+// If the download is Resumable and a partial from an earlier attempt exists,
+// it continues from the saved offset.
 func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string, fileSize int64, filename string, verbose bool) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
-	if err != nil {
-		return err
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if d.State != nil {
+		d.State.SetCancelFunc(cancel)
 	}
 
-	req.Header.Set("User-Agent", d.Runtime.GetUserAgent())
+	resumable := d.Resumable && fileSize > 0
 
-	resp, err := d.Client.Do(req)
-	if err != nil {
-		return err
+	// Without a shared limiter, still keep the origin's backoff state between attempts.
+	if d.Hosts == nil {
+		d.Hosts = connlimit.NewHosts(0)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	// Same proxy, protocol and redirect handling as the concurrent path, with one connection.
+	clients := httpclient.NewSet(d.Runtime, 1, d.SupportsHTTP2, d.SupportsHTTP3)
+	defer clients.Close()
 
 	// Use .GoFetch extension for incomplete file to keep partials discoverable.
-	workingPath := destPath + types.IncompleteSuffix
-	outFile, err := os.Create(workingPath)
+	workingPath := d.WorkingPath
+	if workingPath == "" {
+		workingPath = destPath + types.IncompleteSuffix
+	}
+
+	// Pick up where an earlier attempt stopped.
+	var saved *types.DownloadState
+	var offset int64
+	if resumable {
+		saved, offset = d.loadPartial(rawurl, destPath, workingPath, fileSize)
+	}
+
+	outFile, err := os.OpenFile(workingPath, os.O_CREATE|os.O_RDWR, 0o644)
 	if err != nil {
 		return err
 	}
 
 	// Track whether we completed successfully for cleanup.
+	// Resumable partials are kept so the next attempt can continue.
 	success := false
+	keepPartial := false
 	defer func() {
 		outFile.Close()
-		if !success {
+		if !success && !keepPartial {
 			os.Remove(workingPath)
 		}
 	}()
 
-	start := time.Now()
+	// Hash bytes as they stream in so verification needs no second read.
+	hasher, err := integrity.NewPrefixHasher(d.Checksum)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if err := hasher.Restore(saved.HashState, saved.HashedBytes); err != nil {
+			utils.Debug("Rehashing partial instead: %v", err)
+		}
+		if hasher.Hashed() != offset {
+			if hasher, err = integrity.NewPrefixHasher(d.Checksum); err != nil {
+				return err
+			}
+			if err := hasher.CatchUp(outFile, offset); err != nil {
+				return err
+			}
+		}
+	}
 
-	// Copy response body to file with context cancellation support.
-	var written int64
+	start := time.Now()
+	written := offset
+	if d.State != nil {
+		d.State.Downloaded.Store(written)
+		d.State.VerifiedProgress.Store(written)
+		d.State.SyncSessionStart()
+		d.State.SetWritten([]types.Task{{Offset: 0, Length: written}})
+	}
 	buf := make([]byte, d.Runtime.GetWorkerBufferSize())
 
-	for {
-		// Check for context cancellation (allows clean shutdown)
-		select {
-		case <-ctx.Done():
-			// Can't resume - server doesn't support Range requests
-			return ctx.Err()
+	// setWritten moves the write position, e.g. back to 0 when starting over.
+	setWritten := func(n int64) error {
+		if n == 0 {
+			if err := outFile.Truncate(0); err != nil {
+				return fmt.Errorf("failed to truncate partial: %w", err)
+			}
+			if hasher, err = integrity.NewPrefixHasher(d.Checksum); err != nil {
+				return err
+			}
+		}
+		if _, err := outFile.Seek(n, io.SeekStart); err != nil {
+			return fmt.Errorf("seek error: %w", err)
+		}
+		written = n
+		if d.State != nil {
+			d.State.Downloaded.Store(n)
+			d.State.VerifiedProgress.Store(n)
+			d.State.SetWritten([]types.Task{{Offset: 0, Length: n}})
+		}
+		return nil
+	}
+
+	// transfer makes one request and streams the body to disk from written.
+	// retry reports whether the failure was a dropped or refused connection
+	// that is worth reconnecting for.
+	transfer := func() (retry bool, err error) {
+		release, err := d.Hosts.Acquire(ctx, rawurl)
+		if err != nil {
+			return false, err
+		}
+		defer release()
+
+		resp, err := d.request(ctx, clients, rawurl, written, saved.IfRange())
+		if err != nil {
+			return true, err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case written > 0 && resp.StatusCode == http.StatusPartialContent:
+			if first, err := contentRangeStart(resp.Header.Get("Content-Range"), fileSize); err != nil || first != written {
+				return false, types.NewError(types.KindFileChanged,
+					fmt.Errorf("unexpected Content-Range %q for resume at %d", resp.Header.Get("Content-Range"), written))
+			}
+			utils.Debug("Resuming single download at offset %d", written)
+		case resp.StatusCode == http.StatusOK:
+			if written > 0 {
+				if saved.IfRange() != "" && d.Runtime.GetOnRemoteChange() == types.RemoteChangeFail {
+					return false, types.NewError(types.KindFileChanged,
+						fmt.Errorf("remote file changed since the partial was saved (If-Range %s failed)", saved.IfRange()))
+				}
+				// If-Range failed (the file changed) or the range was ignored: start over.
+				utils.Debug("Server sent the full file, restarting from 0")
+			}
+			if err := setWritten(0); err != nil {
+				return false, err
+			}
+			saved = &types.DownloadState{
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+			}
 		default:
+			if throttled := types.ThrottledResponse(resp); throttled != nil {
+				d.backOff(rawurl, destPath, throttled)
+				return true, throttled
+			}
+			err := types.StatusError(resp.StatusCode)
+			return err.Retryable(), err
 		}
+		d.Hosts.Succeeded(rawurl)
+
+		// Copy response body to file with context cancellation support.
+		for {
+			// Check for context cancellation (allows clean shutdown and pause)
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
 
-		nr, readErr := resp.Body.Read(buf)
-		if nr > 0 {
-			nw, writeErr := outFile.Write(buf[0:nr])
-			if nw > 0 {
-				written += int64(nw)
-				if d.State != nil {
-					d.State.Downloaded.Store(written)
+			// Charge the limiter for what arrived: a read often returns less
+			// than the chunk.
+			nr, readErr := resp.Body.Read(buf[:d.Bandwidth.ReadSize(len(buf))])
+			if nr > 0 {
+				if err := d.Bandwidth.WaitN(ctx, nr); err != nil {
+					return false, err
+				}
+				nw, writeErr := outFile.Write(buf[0:nr])
+				if nw > 0 {
+					hasher.Write(buf[0:nw])
+					written += int64(nw)
+					if d.State != nil {
+						d.State.Downloaded.Store(written)
+						d.State.VerifiedProgress.Store(written)
+						d.State.MarkWritten(written-int64(nw), int64(nw))
+					}
+				}
+				if writeErr != nil {
+					return false, fmt.Errorf("write error: %w", writeErr)
+				}
+				if nr != nw {
+					return false, io.ErrShortWrite
 				}
 			}
-			if writeErr != nil {
-				return fmt.Errorf("write error: %w", writeErr)
+			if readErr != nil {
+				if readErr == io.EOF {
+					break // Done reading
+				}
+				return true, fmt.Errorf("read error: %w", readErr)
 			}
-			if nr != nw {
-				return io.ErrShortWrite
+		}
+
+		if fileSize > 0 && written != fileSize {
+			return true, fmt.Errorf("read error: got %d of %d bytes: %w", written, fileSize, io.ErrUnexpectedEOF)
+		}
+		return false, nil
+	}
+
+	// interrupted keeps the partial for resume when the server supports it.
+	interrupted := func(cause error) error {
+		if !resumable || written == 0 {
+			return cause
+		}
+		if err := outFile.Sync(); err != nil {
+			return fmt.Errorf("sync error: %w", err)
+		}
+		keepPartial = true
+		d.saveState(rawurl, destPath, workingPath, fileSize, written, saved, hasher, start)
+		if d.State != nil && d.State.IsPaused() {
+			return types.ErrPaused
+		}
+		return cause
+	}
+
+	// Reconnect with exponential backoff when the stream drops.
+	maxRetries := d.Runtime.GetMaxTaskRetries()
+	for attempt := 0; ; attempt++ {
+		retry, err := transfer()
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return interrupted(ctx.Err())
+		}
+		if !retry || attempt >= maxRetries {
+			return interrupted(err)
+		}
+		if errors.Is(err, types.ErrThrottled) {
+			// Hosts.Acquire waits out the backoff before the next request.
+			// Like in the concurrent worker, the attempt still counts, so a
+			// host that never stops throttling ends the download.
+			continue
+		}
+
+		delay := time.Duration(1<<attempt) * d.Runtime.GetRetryBaseDelay()
+		utils.Debug("Single download %s dropped at %d: %v (reconnect %d/%d in %v)", d.ID, written, err, attempt+1, maxRetries, delay)
+		if !resumable {
+			// Without Range support the only way back is from the start.
+			if err := setWritten(0); err != nil {
+				return err
 			}
 		}
-		if readErr != nil {
-			if readErr == io.EOF {
-				break // Done reading
+		if d.ProgressChan != nil {
+			d.ProgressChan <- events.DownloadRetryMsg{
+				DownloadID:  d.ID,
+				Filename:    filepath.Base(destPath),
+				Attempt:     attempt + 1,
+				MaxAttempts: maxRetries,
+				Delay:       delay,
+				Offset:      written,
+				Reason:      err.Error(),
 			}
-			return fmt.Errorf("read error: %w", readErr)
+		}
+
+		select {
+		case <-ctx.Done():
+			return interrupted(ctx.Err())
+		case <-time.After(delay):
 		}
 	}
 
 	if err := outFile.Sync(); err != nil {
 		return fmt.Errorf("sync error: %w", err)
 	}
+
+	// Verify content before exposing it under the final name.
+	// On failure the deferred cleanup removes the partial.
+	if err := hasher.Finish(outFile, written); err != nil {
+		return err
+	}
+
 	if err := outFile.Close(); err != nil {
 		return fmt.Errorf("close error: %w", err)
 	}
 
-	// Rename .GoFetch file to final destination.
-	if err := os.Rename(workingPath, destPath); err != nil {
-		// Fallback: copy if rename fails (cross-device).
-		if copyErr := copyFile(workingPath, destPath); copyErr != nil {
-			return fmt.Errorf("failed to finalize file: %w", copyErr)
-		}
-		os.Remove(workingPath)
+	// Move .GoFetch file to final destination.
+	if err := utils.MoveFile(workingPath, destPath, d.reportMove(destPath)); err != nil {
+		return fmt.Errorf("failed to finalize file: %w", err)
 	}
 
 	success = true // Mark successful so defer doesn't clean up
+	if resumable {
+		_ = state.DeleteState(d.ID, rawurl, destPath)
+	}
 
 	// Only print stats in verbose mode
 	if verbose {
 		elapsed := time.Since(start)
-		speed := float64(written) / elapsed.Seconds()
+		speed := float64(written-offset) / elapsed.Seconds()
 		fmt.Fprintf(os.Stderr, "\nDownloaded %s in %s (%s/s)\n",
 			destPath,
 			elapsed.Round(time.Second),
@@ -142,22 +349,125 @@ func (d *SingleDownloader) Download(ctx context.Context, rawurl, destPath string
 	return nil
 }
 
-// copyFile copies a file from src to dst (fallback when rename fails)
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
+// request issues the GET, asking for bytes from offset onwards when resuming.
+func (d *SingleDownloader) request(ctx context.Context, clients *httpclient.Set, rawurl string, offset int64, ifRange string) (*http.Response, error) {
+	return clients.Do(func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		httpclient.ApplyHeaders(req, d.Headers, d.Runtime)
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			if ifRange != "" {
+				req.Header.Set("If-Range", ifRange)
+			}
+		}
+		return req, nil
+	})
+}
+
+// loadPartial returns the saved state and resume offset of an earlier
+// attempt, or a zero offset when there is nothing usable on disk.
+func (d *SingleDownloader) loadPartial(rawurl, destPath, workingPath string, fileSize int64) (*types.DownloadState, int64) {
+	saved, err := state.LoadState(rawurl, destPath)
+	if err != nil || saved == nil || saved.TotalSize != fileSize || len(saved.Tasks) != 1 {
+		return nil, 0
+	}
+	// A single remaining task that runs to EOF is the shape saveState writes.
+	task := saved.Tasks[0]
+	if task.Offset <= 0 || task.Offset+task.Length != fileSize {
+		return nil, 0
+	}
+	info, err := os.Stat(workingPath)
+	if err != nil || info.Size() < task.Offset {
+		utils.Debug("Partial file missing or short, restarting single download")
+		return nil, 0
 	}
-	defer in.Close()
 
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
+	if d.State != nil {
+		d.State.SetSavedElapsed(time.Duration(saved.Elapsed))
 	}
-	defer out.Close()
+	return saved, task.Offset
+}
 
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+// saveState persists the resume offset, validators and hash progress.
+func (d *SingleDownloader) saveState(rawurl, destPath, workingPath string, fileSize, written int64, saved *types.DownloadState, hasher *integrity.PrefixHasher, start time.Time) {
+	elapsed := time.Since(start)
+	if d.State != nil {
+		elapsed += d.State.GetSavedElapsed()
+		d.State.FinalizePause(written, elapsed)
+	}
+	hashState, hashedBytes := hasher.Snapshot()
+
+	s := &types.DownloadState{
+		URL:          rawurl,
+		ID:           d.ID,
+		DestPath:     destPath,
+		TotalSize:    fileSize,
+		Downloaded:   written,
+		Tasks:        []types.Task{{Offset: written, Length: fileSize - written}},
+		Filename:     filepath.Base(destPath),
+		Elapsed:      elapsed.Nanoseconds(),
+		Checksum:     d.Checksum.String(),
+		HashState:    hashState,
+		HashedBytes:  hashedBytes,
+		ETag:         saved.ETag,
+		LastModified: saved.LastModified,
+		WorkingPath:  workingPath,
+	}
+	if err := state.SaveState(rawurl, destPath, s); err != nil {
+		utils.Debug("Failed to save single download state: %v", err)
+		return
+	}
+	utils.Debug("Single download state saved at offset %d", written)
+}
+
+// contentRangeStart parses "bytes start-end/total" and checks total when known.
+func contentRangeStart(header string, fileSize int64) (int64, error) {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(header, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return 0, err
+	}
+	if total != "*" {
+		if n, err := strconv.ParseInt(total, 10, 64); err != nil || n != fileSize {
+			return 0, fmt.Errorf("total size %s does not match %d", total, fileSize)
+		}
+	}
+	return start, nil
+}
+
+// reportMove returns a MoveFile progress callback that announces the copy
+// of a finished file to destPath.
+func (d *SingleDownloader) reportMove(destPath string) func(copied, total int64) {
+	return func(copied, total int64) {
+		if d.ProgressChan != nil {
+			d.ProgressChan <- events.DownloadMovingMsg{
+				DownloadID: d.ID,
+				Filename:   filepath.Base(destPath),
+				Moved:      copied,
+				Total:      total,
+			}
+		}
+	}
+}
+
+// backOff starts the origin's shared backoff and announces it.
+func (d *SingleDownloader) backOff(rawurl, destPath string, throttled *types.ThrottledError) {
+	until, extended := d.Hosts.Throttle(rawurl, throttled.RetryAfter)
+	if !extended {
+		return
+	}
+	utils.Debug("Host %s throttled (%d), backing off until %s", connlimit.Origin(rawurl), throttled.StatusCode, until.Format(time.TimeOnly))
+	if d.ProgressChan != nil {
+		d.ProgressChan <- events.DownloadBackoffMsg{
+			DownloadID: d.ID,
+			Filename:   filepath.Base(destPath),
+			Host:       connlimit.Origin(rawurl),
+			StatusCode: throttled.StatusCode,
+			Until:      until,
+		}
 	}
-	return out.Sync()
 }
@@ -0,0 +1,159 @@
+package download
+
+import (
+	engine "concurrent_downloader/internal"
+	"concurrent_downloader/internal/download/concurrent"
+	"concurrent_downloader/internal/download/httpclient"
+	"concurrent_downloader/internal/download/integrity"
+	"concurrent_downloader/internal/download/stream"
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// StreamDownload downloads cfg.URL and writes it to w in order, without
+// staging it in a file. When the server supports ranges, workers fetch over
+// several connections up to stream.DefaultWindow ahead of what w has taken;
+// otherwise the body is copied through one connection. A checksum, given or
+// advertised by the server, is checked once the last byte is written, so w
+// has already seen the data when a mismatch is reported.
+func StreamDownload(ctx context.Context, cfg *types.DownloadConfig, w io.Writer) error {
+	probe, err := engine.ProbeServer(ctx, cfg.URL, "", cfg.Headers)
+	if err != nil {
+		return err
+	}
+	if cfg.Size > 0 && probe.FileSize > 0 && probe.FileSize != cfg.Size {
+		return types.NewError(types.KindFileChanged,
+			fmt.Errorf("size mismatch: expected %d bytes, server reports %d", cfg.Size, probe.FileSize))
+	}
+	checksum := cfg.Checksum
+	if checksum == nil {
+		checksum = probe.Checksum
+	}
+
+	// Small chunks taken lowest offset first keep every connection close to
+	// the reader.
+	runtime := types.RuntimeConfig{}
+	if cfg.Runtime != nil {
+		runtime = *cfg.Runtime
+	}
+	runtime.SequentialDownload = true
+
+	if runtime.ForceSingle || !probe.SupportsRange || probe.FileSize <= 0 {
+		utils.Debug("StreamDownload: streaming %s over one connection", cfg.URL)
+		return streamBody(ctx, cfg, &runtime, probe, checksum, w)
+	}
+
+	window, err := stream.New(probe.FileSize, stream.DefaultWindow, checksum)
+	if err != nil {
+		return err
+	}
+	progress := cfg.State
+	if progress == nil {
+		progress = types.NewProgressState(cfg.ID, probe.FileSize)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Workers waiting for room must not outlive a cancelled download.
+	stop := window.CloseOnDone(ctx)
+	defer stop()
+
+	d := concurrent.NewConcurrentDownloader(cfg.ID, cfg.ProgressCh, progress, &runtime)
+	d.Headers = cfg.Headers
+	d.Connections = cfg.Connections
+	d.Hosts = cfg.Hosts
+	d.Bandwidth = cfg.Bandwidth
+	d.ETag = probe.ETag
+	d.LastModified = probe.LastModified
+	d.Stream = window
+
+	done := make(chan error, 1)
+	go func() {
+		err := d.Download(ctx, cfg.URL, cfg.Mirrors, probeMirrors(ctx, cfg), "", probe.FileSize, probe.SupportsHTTP2, probe.SupportsHTTP3)
+		window.CloseWithError(err)
+		done <- err
+	}()
+
+	// The window reports a failed download, or a checksum mismatch, to the
+	// copy; a failed write stops the workers.
+	_, err = io.Copy(w, window)
+	if err != nil {
+		cancel()
+		_ = window.Close()
+	}
+	if downloadErr := <-done; err == nil {
+		err = downloadErr
+	}
+	return err
+}
+
+// streamBody copies the whole body of one GET to w, for servers that do
+// not support ranges or when a single connection is forced.
+func streamBody(ctx context.Context, cfg *types.DownloadConfig, runtime *types.RuntimeConfig, probe *engine.ProbeResult, checksum *types.Checksum, w io.Writer) error {
+	clients := httpclient.NewSet(runtime, 1, probe.SupportsHTTP2, probe.SupportsHTTP3)
+	defer clients.Close()
+
+	resp, err := clients.Do(func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpclient.ApplyHeaders(req, cfg.Headers, runtime)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if throttled := types.ThrottledResponse(resp); throttled != nil {
+			return throttled
+		}
+		return types.StatusError(resp.StatusCode)
+	}
+
+	var h hash.Hash
+	if checksum != nil {
+		if h, err = integrity.NewHash(checksum.Algorithm); err != nil {
+			return err
+		}
+	}
+
+	var n int64
+	buf := make([]byte, runtime.GetWorkerBufferSize())
+	for {
+		chunk := buf[:cfg.Bandwidth.ReadSize(len(buf))]
+		read, readErr := resp.Body.Read(chunk)
+		if read > 0 {
+			// Charge the limiter for what arrived, not for the whole chunk.
+			if err := cfg.Bandwidth.WaitN(ctx, read); err != nil {
+				return err
+			}
+			if h != nil {
+				_, _ = h.Write(chunk[:read])
+			}
+			if _, err := w.Write(chunk[:read]); err != nil {
+				return err
+			}
+			n += int64(read)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if probe.FileSize > 0 && n != probe.FileSize {
+		return fmt.Errorf("stream ended after %d of %d bytes: %w", n, probe.FileSize, io.ErrUnexpectedEOF)
+	}
+	if h != nil {
+		return integrity.Compare(checksum, h.Sum(nil))
+	}
+	return nil
+}
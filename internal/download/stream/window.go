@@ -0,0 +1,267 @@
+// Package stream hands a download to a reader in order while workers fetch
+// its ranges out of order, holding what arrives early in a bounded window of
+// memory instead of a file.
+package stream
+
+import (
+	"context"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+
+	"concurrent_downloader/internal/download/integrity"
+	"concurrent_downloader/internal/download/types"
+)
+
+// DefaultWindow is how far a stream downloads ahead of its reader.
+const DefaultWindow = 64 * types.MB
+
+// Window is the in-order view of a download that workers fill out of order.
+// It keeps the bytes from the read cursor on in a ring buffer; workers wait
+// with WaitRoom before fetching beyond it, so memory stays bounded however
+// slowly the reader takes them. Written bytes reach the reader only once
+// committed, so a range can still be dropped and fetched again until then.
+type Window struct {
+	size int64
+	buf  []byte
+
+	mu      sync.Mutex
+	changed chan struct{} // Closed and replaced whenever the window changes
+	cursor  int64         // Next byte the reader gets
+	spans   []types.Task  // Committed ranges beyond the cursor, sorted and merged
+	done    bool          // The download ended
+	err     error         // Why it ended; nil when it completed
+	closed  bool          // The reader is gone
+	eofErr  error         // Result of the checksum once the reader reached the end
+
+	hash     hash.Hash
+	expected *types.Checksum
+	stats    types.WriteStats
+}
+
+// New returns a window for a download of size bytes that holds up to
+// window bytes ahead of the reader. With a checksum, the reader gets an
+// error instead of io.EOF if the streamed bytes do not match it.
+func New(size, window int64, checksum *types.Checksum) (*Window, error) {
+	if size <= 0 {
+		return nil, errors.New("stream needs a known size")
+	}
+	if window <= 0 || window > size {
+		window = size
+	}
+	w := &Window{
+		size:     size,
+		buf:      make([]byte, window),
+		changed:  make(chan struct{}),
+		expected: checksum,
+	}
+	if checksum != nil {
+		h, err := integrity.NewHash(checksum.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		w.hash = h
+	}
+	return w, nil
+}
+
+// Fits reports whether the bytes up to end can be held right now.
+func (w *Window) Fits(end int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return end <= w.cursor+int64(len(w.buf))
+}
+
+// WaitRoom blocks until the bytes up to end can be held, the reader goes
+// away, the download side is closed or ctx ends.
+func (w *Window) WaitRoom(ctx context.Context, end int64) error {
+	for {
+		w.mu.Lock()
+		if err := w.stopped(); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		if end <= w.cursor+int64(len(w.buf)) {
+			w.mu.Unlock()
+			return nil
+		}
+		changed := w.changed
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// WriteAt stages p at off; the reader gets it once Commit covers it. Bytes
+// the reader already has are dropped, as from a hedged duplicate; a write
+// beyond the window waits for room, so commit earlier writes first. It
+// fails once the reader goes away or the download side is closed, so a
+// stalled reader cannot hold workers forever; see CloseOnDone.
+func (w *Window) WriteAt(p []byte, off int64) (int, error) {
+	n := len(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stats.Writes++
+	w.stats.Bytes += int64(n)
+
+	for {
+		if err := w.stopped(); err != nil {
+			return 0, err
+		}
+		if off < w.cursor {
+			skip := w.cursor - off
+			if skip >= int64(len(p)) {
+				return n, nil
+			}
+			p, off = p[skip:], w.cursor
+		}
+		if off+int64(len(p)) <= w.cursor+int64(len(w.buf)) {
+			break
+		}
+		changed := w.changed
+		w.mu.Unlock()
+		<-changed
+		w.mu.Lock()
+	}
+
+	start := off % int64(len(w.buf))
+	copied := copy(w.buf[start:], p)
+	copy(w.buf, p[copied:])
+	return n, nil
+}
+
+// Commit hands the staged bytes of [off, off+length) to the reader, then
+// calls fn: a window holds no file to wait for.
+func (w *Window) Commit(off, length int64, fn func()) {
+	w.mu.Lock()
+	if end := off + length; !w.closed && end > w.cursor {
+		off = max(off, w.cursor)
+		w.spans = types.InsertRange(w.spans, types.Task{Offset: off, Length: end - off})
+		w.notify()
+	}
+	w.mu.Unlock()
+	fn()
+}
+
+// Flush does nothing; written bytes are readable at once.
+func (w *Window) Flush() error {
+	return nil
+}
+
+// Pending returns nothing; a stream is not resumed.
+func (w *Window) Pending() []types.Task {
+	return nil
+}
+
+// Stats returns the writes workers made to the window.
+func (w *Window) Stats() types.WriteStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// CloseWithError ends the download side. The reader gets the bytes already
+// in order, then err, or io.EOF when err is nil and all bytes arrived.
+func (w *Window) CloseWithError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	w.done = true
+	w.err = err
+	w.notify()
+}
+
+// CloseOnDone closes the download side with ctx's error once ctx ends,
+// waking workers that wait for room. The returned func stops watching ctx.
+func (w *Window) CloseOnDone(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() { w.CloseWithError(ctx.Err()) })
+}
+
+// Read reads the next bytes in order, blocking until they arrive.
+func (w *Window) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		if w.closed {
+			return 0, io.ErrClosedPipe
+		}
+		if len(w.spans) > 0 && w.spans[0].Offset == w.cursor {
+			n := min(int64(len(p)), w.spans[0].Length)
+			start := w.cursor % int64(len(w.buf))
+			copied := copy(p[:n], w.buf[start:])
+			copy(p[copied:n], w.buf)
+			if w.hash != nil {
+				_, _ = w.hash.Write(p[:n])
+			}
+
+			w.cursor += n
+			w.spans[0].Offset += n
+			w.spans[0].Length -= n
+			if w.spans[0].Length == 0 {
+				w.spans = w.spans[1:]
+			}
+			w.notify()
+			return int(n), nil
+		}
+		if w.cursor >= w.size {
+			if w.eofErr == nil {
+				w.eofErr = io.EOF
+				if w.hash != nil {
+					if err := integrity.Compare(w.expected, w.hash.Sum(nil)); err != nil {
+						w.eofErr = err
+					}
+				}
+			}
+			return 0, w.eofErr
+		}
+		if w.done {
+			if w.err != nil {
+				return 0, w.err
+			}
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		changed := w.changed
+		w.mu.Unlock()
+		<-changed
+		w.mu.Lock()
+	}
+}
+
+// Close ends the reader side. Workers waiting for room or writing get
+// io.ErrClosedPipe.
+func (w *Window) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.notify()
+	return nil
+}
+
+// stopped returns why workers may no longer write, or nil while they may.
+// Call it with mu held.
+func (w *Window) stopped() error {
+	switch {
+	case w.closed:
+		return io.ErrClosedPipe
+	case w.done && w.err != nil:
+		return w.err
+	case w.done:
+		return io.ErrClosedPipe
+	}
+	return nil
+}
+
+// notify wakes everyone waiting on the window. Call it with mu held.
+func (w *Window) notify() {
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+// write stages and commits data[off:off+n].
+func write(t *testing.T, w *Window, data []byte, off, n int64) {
+	t.Helper()
+	if _, err := w.WriteAt(data[off:off+n], off); err != nil {
+		t.Fatalf("WriteAt(%d, %d): %v", off, n, err)
+	}
+	w.Commit(off, n, func() {})
+}
+
+func TestWindowInOrder(t *testing.T) {
+	data := testData(100)
+	sum := sha256.Sum256(data)
+	w, err := New(int64(len(data)), 40, &types.Checksum{Algorithm: types.ChecksumSHA256, Value: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		got, _ := io.ReadAll(w)
+		done <- got
+	}()
+
+	// Ranges arrive out of order, wrap the ring and overlap what was read.
+	write(t, w, data, 20, 20)
+	write(t, w, data, 0, 20)
+	if err := w.WaitRoom(context.Background(), 80); err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, data, 60, 20)
+	write(t, w, data, 30, 30)
+	if err := w.WaitRoom(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, data, 80, 20)
+	w.CloseWithError(nil)
+
+	if got := <-done; !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes that differ from the download", len(got))
+	}
+}
+
+func TestWindowHoldsUncommitted(t *testing.T) {
+	data := testData(10)
+	w, _ := New(10, 0, nil)
+	if _, err := w.WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	read := make(chan int)
+	go func() {
+		n, _ := w.Read(make([]byte, 10))
+		read <- n
+	}()
+	select {
+	case n := <-read:
+		t.Fatalf("Read returned %d staged bytes before Commit", n)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	w.Commit(0, 10, func() {})
+	if n := <-read; n != 10 {
+		t.Errorf("Read = %d bytes after Commit, want 10", n)
+	}
+}
+
+func TestWindowChecksumMismatch(t *testing.T) {
+	data := testData(10)
+	w, _ := New(10, 0, &types.Checksum{Algorithm: types.ChecksumMD5, Value: "900150983cd24fb0d6963f7d28e17f72"})
+	write(t, w, data, 0, 10)
+
+	_, err := io.ReadAll(w)
+	if !errors.Is(err, types.ErrChecksumMismatch) {
+		t.Errorf("ReadAll error = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestWindowEnds(t *testing.T) {
+	failed := errors.New("server went away")
+	tests := []struct {
+		name string
+		end  func(w *Window)
+		want error // From Read once the committed bytes are gone
+	}{
+		{"failed", func(w *Window) { w.CloseWithError(failed) }, failed},
+		{"short", func(w *Window) { w.CloseWithError(nil) }, io.ErrUnexpectedEOF},
+		{"reader closed", func(w *Window) { _ = w.Close() }, io.ErrClosedPipe},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := New(10, 0, nil)
+			write(t, w, testData(10), 0, 4)
+			tt.end(w)
+
+			_, err := io.ReadFull(w, make([]byte, 10))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Read error = %v, want %v", err, tt.want)
+			}
+			if _, err := w.WriteAt([]byte{1}, 5); err == nil {
+				t.Error("WriteAt succeeded after the window ended")
+			}
+		})
+	}
+}
+
+func TestWindowCloseOnDone(t *testing.T) {
+	w, _ := New(100, 10, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer w.CloseOnDone(ctx)()
+
+	errc := make(chan error)
+	go func() {
+		// Beyond the window: waits for room until ctx ends.
+		_, err := w.WriteAt(make([]byte, 5), 50)
+		errc <- err
+	}()
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("WriteAt error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WriteAt still waiting after cancel")
+	}
+}
@@ -0,0 +1,125 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Supported checksum algorithms.
+const (
+	ChecksumSHA512 = "sha512"
+	ChecksumSHA256 = "sha256"
+	ChecksumSHA1   = "sha1"
+	ChecksumMD5    = "md5"
+	ChecksumCRC32C = "crc32c"
+	ChecksumCRC32  = "crc32"
+)
+
+// Checksum is an expected digest for a download, written as "algo:hex".
+type Checksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"` // Lowercase hex digest
+}
+
+// checksumLengths maps each algorithm to its digest size in bytes.
+var checksumLengths = map[string]int{
+	ChecksumSHA512: 64,
+	ChecksumSHA256: 32,
+	ChecksumSHA1:   20,
+	ChecksumMD5:    16,
+	ChecksumCRC32C: 4,
+	ChecksumCRC32:  4,
+}
+
+// normalizeChecksumAlgorithm accepts common spellings such as "SHA-256".
+func normalizeChecksumAlgorithm(algo string) string {
+	algo = strings.ToLower(strings.TrimSpace(algo))
+	return strings.ReplaceAll(algo, "-", "")
+}
+
+// ParseChecksum parses "algo:hexdigest" (e.g. "sha256:9f86d0...").
+func ParseChecksum(raw string) (*Checksum, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	algo, value, ok := strings.Cut(raw, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid checksum %q: expected <algorithm>:<hex digest>", raw)
+	}
+	return NewChecksum(algo, value)
+}
+
+// NewChecksum validates an algorithm/hex pair and returns it normalized.
+func NewChecksum(algo string, value string) (*Checksum, error) {
+	algo = normalizeChecksumAlgorithm(algo)
+	size, ok := checksumLengths[algo]
+	if !ok {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q (supported: sha512, sha256, sha1, md5, crc32c, crc32)", algo)
+	}
+
+	value = strings.ToLower(strings.TrimSpace(value))
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != size {
+		return nil, fmt.Errorf("invalid %s digest %q: expected %d hex characters", algo, value, size*2)
+	}
+
+	return &Checksum{Algorithm: algo, Value: value}, nil
+}
+
+// String returns the "algo:hex" form used by the CLI, API and state DB.
+func (c *Checksum) String() string {
+	if c == nil || c.Algorithm == "" {
+		return ""
+	}
+	return c.Algorithm + ":" + c.Value
+}
+
+// PieceHashes lists expected digests for fixed-size pieces of a file,
+// as published in metalinks. The last piece may be shorter than Length.
+type PieceHashes struct {
+	Algorithm string   `json:"algorithm"`
+	Length    int64    `json:"length"`
+	Hashes    []string `json:"hashes"` // Lowercase hex digests in file order
+}
+
+// NewPieceHashes validates piece digests and returns them normalized.
+func NewPieceHashes(algo string, length int64, hashes []string) (*PieceHashes, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("invalid piece length %d", length)
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no piece hashes given")
+	}
+
+	p := &PieceHashes{Length: length, Hashes: make([]string, len(hashes))}
+	for i, h := range hashes {
+		c, err := NewChecksum(algo, h)
+		if err != nil {
+			return nil, fmt.Errorf("piece %d: %w", i, err)
+		}
+		p.Algorithm = c.Algorithm
+		p.Hashes[i] = c.Value
+	}
+	return p, nil
+}
+
+// Piece returns the byte range and expected digest of piece i.
+func (p *PieceHashes) Piece(i int, fileSize int64) (Task, *Checksum) {
+	offset := int64(i) * p.Length
+	length := p.Length
+	if offset+length > fileSize {
+		length = fileSize - offset
+	}
+	return Task{Offset: offset, Length: length}, &Checksum{Algorithm: p.Algorithm, Value: p.Hashes[i]}
+}
+
+// Matches reports whether the piece list covers exactly fileSize bytes.
+func (p *PieceHashes) Matches(fileSize int64) bool {
+	if p == nil || p.Length <= 0 || fileSize <= 0 {
+		return false
+	}
+	return int64(len(p.Hashes)) == (fileSize+p.Length-1)/p.Length
+}
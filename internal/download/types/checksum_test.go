@@ -0,0 +1,117 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sha256Hex = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    *Checksum
+		wantErr string
+	}{
+		{"empty", "  ", nil, ""},
+		{"sha256", "sha256:" + sha256Hex, &Checksum{ChecksumSHA256, sha256Hex}, ""},
+		{"spelling and case", " SHA-256:" + strings.ToUpper(sha256Hex) + " ", &Checksum{ChecksumSHA256, sha256Hex}, ""},
+		{"crc32c", "crc32c:364b3fb7", &Checksum{ChecksumCRC32C, "364b3fb7"}, ""},
+		{"no algorithm", sha256Hex, nil, "expected <algorithm>:<hex digest>"},
+		{"unknown algorithm", "sha3:" + sha256Hex, nil, "unsupported checksum algorithm"},
+		{"wrong length", "md5:" + sha256Hex, nil, "expected 32 hex characters"},
+		{"not hex", "crc32:zzzzzzzz", nil, "invalid crc32 digest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksum(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseChecksum(%q) error = %v, want one containing %q", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseChecksum(%q): %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChecksum(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksumString(t *testing.T) {
+	var nilChecksum *Checksum
+	if got := nilChecksum.String(); got != "" {
+		t.Errorf("nil String = %q, want empty", got)
+	}
+	c := &Checksum{Algorithm: ChecksumMD5, Value: "900150983cd24fb0d6963f7d28e17f72"}
+	if got := c.String(); got != "md5:900150983cd24fb0d6963f7d28e17f72" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestNewPieceHashes(t *testing.T) {
+	if _, err := NewPieceHashes("md5", 0, []string{"900150983cd24fb0d6963f7d28e17f72"}); err == nil {
+		t.Error("zero piece length accepted")
+	}
+	if _, err := NewPieceHashes("md5", 4, nil); err == nil {
+		t.Error("empty piece list accepted")
+	}
+	if _, err := NewPieceHashes("md5", 4, []string{"900150983cd24fb0d6963f7d28e17f72", "bad"}); err == nil || !strings.Contains(err.Error(), "piece 1") {
+		t.Errorf("bad piece error = %v, want one naming piece 1", err)
+	}
+
+	p, err := NewPieceHashes("SHA-1", 4, []string{strings.ToUpper("a9993e364706816aba3e25717850c26c9cd0d89d")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Algorithm != ChecksumSHA1 || p.Hashes[0] != "a9993e364706816aba3e25717850c26c9cd0d89d" {
+		t.Errorf("NewPieceHashes = %+v, want normalized sha1", p)
+	}
+}
+
+func TestPieceHashes(t *testing.T) {
+	p := &PieceHashes{Algorithm: ChecksumCRC32, Length: 4, Hashes: []string{"00000000", "11111111", "22222222"}}
+
+	matches := []struct {
+		size int64
+		want bool
+	}{
+		{0, false},
+		{8, false},
+		{9, true},
+		{12, true},
+		{13, false},
+	}
+	for _, tt := range matches {
+		if got := p.Matches(tt.size); got != tt.want {
+			t.Errorf("Matches(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+	var nilPieces *PieceHashes
+	if nilPieces.Matches(10) {
+		t.Error("nil PieceHashes matches")
+	}
+
+	pieces := []struct {
+		i    int
+		want Task
+	}{
+		{0, Task{Offset: 0, Length: 4}},
+		{1, Task{Offset: 4, Length: 4}},
+		{2, Task{Offset: 8, Length: 2}}, // Last piece is short
+	}
+	for _, tt := range pieces {
+		got, sum := p.Piece(tt.i, 10)
+		if got != tt.want {
+			t.Errorf("Piece(%d) = %+v, want %+v", tt.i, got, tt.want)
+		}
+		if sum.Algorithm != ChecksumCRC32 || sum.Value != p.Hashes[tt.i] {
+			t.Errorf("Piece(%d) checksum = %v", tt.i, sum)
+		}
+	}
+}
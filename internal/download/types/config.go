@@ -1,8 +1,16 @@
 package types
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+
+	"concurrent_downloader/internal/download/connlimit"
+	"concurrent_downloader/internal/download/ratelimit"
 )
 
 // Size constants
@@ -18,6 +26,23 @@ const (
 	IncompleteSuffix = ".GoFetch"
 )
 
+// PartialPath returns where the partial of a download finishing at destPath
+// is written: next to it, or in tempDir when one is set. In a temp dir the
+// ID keeps partials of downloads with the same filename apart.
+func PartialPath(id, destPath, tempDir string) string {
+	if tempDir == "" {
+		return destPath + IncompleteSuffix
+	}
+	name := filepath.Base(destPath)
+	if id != "" {
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		name += "." + id
+	}
+	return filepath.Join(tempDir, name+IncompleteSuffix)
+}
+
 // Chunk size constants for concurrent downloads
 const (
 	MinChunk     = 2 * MB // Minimum chunk size
@@ -54,26 +79,102 @@ const (
 // DownloadConfig contains all parameters needed to start a download.
 // It is passed across layers so worker and UI state stay aligned.
 type DownloadConfig struct {
-	URL        string
-	OutputPath string
-	DestPath   string // Full destination path (for resume state lookup)
-	ID         string
-	Filename   string
-	Verbose    bool
-	IsResume   bool // True if this is explicitly a resume, not a fresh download
-	ProgressCh chan<- any
-	State      *ProgressState
-	SavedState *DownloadState    // Pre-loaded state for resume optimization
-	Runtime    *RuntimeConfig    // Dynamic settings from user config
-	Mirrors    []string          // List of mirror URLs (including primary)
-	Headers    map[string]string // Custom HTTP headers from browser (cookies, auth, etc.)
-
+	URL          string
+	OutputPath   string
+	DestPath     string // Full destination path (for resume state lookup)
+	ID           string
+	Filename     string
+	Verbose      bool
+	IsResume     bool // True if this is explicitly a resume, not a fresh download
+	ProgressCh   chan<- any
+	State        *ProgressState
+	SavedState   *DownloadState       // Pre-loaded state for resume optimization
+	Runtime      *RuntimeConfig       // Dynamic settings from user config
+	Mirrors      []string             // List of mirror URLs (including primary)
+	Headers      map[string]string    // Custom HTTP headers from browser (cookies, auth, etc.)
+	Checksum     *Checksum            // Expected digest verified before the final rename
+	Pieces       *PieceHashes         // Per-piece digests checked as ranges complete
+	Size         int64                // Expected file size (0 = unknown), checked against the probe
+	Connections  *connlimit.Allocator // Connection budget shared across downloads (set by the WorkerPool)
+	Hosts        *connlimit.Hosts     // Per-origin connection limit shared across downloads (set by the WorkerPool)
+	RateLimit    int64                // Requested speed cap in bytes/sec (0 = unlimited)
+	Bandwidth    *ratelimit.Limiter   // Live speed cap, chained to the global one (set by the WorkerPool)
+	Priority     int                  // Queued downloads with higher priority start first
+	RetryAttempt int                  // Automatic retries of the whole download so far
 }
 
 // AddOptions provides per-request overrides for download behavior.
 type AddOptions struct {
 	ForceSingle bool
 	ChunkCount  int
+	Checksum    *Checksum
+	Pieces      *PieceHashes
+	Size        int64
+	RateLimit   int64     // Bytes per second, 0 = unlimited
+	StartAt     time.Time // Keep the download scheduled until then (zero = start now)
+	PauseAt     time.Time // Pause the download at this time (zero = never)
+	Priority    int       // Higher starts first; see PriorityHigh/PriorityLow
+	TempDir     string    // Write the partial here instead of the temp_dir setting
+}
+
+// Named priorities accepted by ParsePriority. Any integer works; these are
+// spaced apart so downloads can be slotted in between.
+const (
+	PriorityLow    = -10
+	PriorityNormal = 0
+	PriorityHigh   = 10
+)
+
+// ParsePriority parses "high", "normal", "low" or an integer.
+func ParsePriority(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	case "low":
+		return PriorityLow, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid priority %q (use high, normal, low or a number)", s)
+	}
+	return n, nil
+}
+
+// ValidateSchedule checks start-at and pause-at times for a new download.
+// Either may be zero; a start time in the past means "start now".
+func ValidateSchedule(startAt, pauseAt, now time.Time) error {
+	if pauseAt.IsZero() {
+		return nil
+	}
+	if !pauseAt.After(now) {
+		return fmt.Errorf("pause time %s is in the past", pauseAt.Format("2006-01-02 15:04"))
+	}
+	if !startAt.IsZero() && !pauseAt.After(startAt) {
+		return fmt.Errorf("pause time must be after the start time")
+	}
+	return nil
+}
+
+// ParseScheduleTime parses a start or pause time: "HH:MM" for its next
+// occurrence after now, "YYYY-MM-DD HH:MM" in local time, or RFC 3339.
+func ParseScheduleTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use HH:MM, YYYY-MM-DD HH:MM or RFC 3339)", s)
 }
 
 type RuntimeConfig struct {
@@ -89,10 +190,26 @@ type RuntimeConfig struct {
 
 	WorkerBufferSize      int
 	MaxTaskRetries        int
+	RetryBaseDelay        time.Duration
 	SlowWorkerThreshold   float64
 	SlowWorkerGracePeriod time.Duration
 	StallTimeout          time.Duration
 	SpeedEmaAlpha         float64
+
+	DownloadRetries       int           // Automatic retries of a failed download, 0 = off
+	DownloadRetryDelay    time.Duration // Wait before the first retry, doubled for each one after
+	DownloadRetryMaxDelay time.Duration
+	RetryOn               []ErrorKind // Error kinds that qualify; nil means ErrorKind.Retryable
+
+	OnRemoteChange string // RemoteChangeRestart or RemoteChangeFail
+
+	DiskReserve int64 // Bytes to keep free on the destination filesystem
+	Preallocate bool  // Reserve the file's blocks up front instead of a sparse file
+
+	TempDir string // Directory for partial files; empty keeps them next to the destination
+
+	WriteBuffer    int64 // Bytes of writes to gather and issue in file order, 0 = write directly
+	WriteDropCache bool  // Write flushed ranges out and drop them from the page cache
 }
 
 const (
@@ -102,6 +219,12 @@ const (
 	ProtocolHTTP3 = "http3"
 )
 
+// What to do with a partial when the file on the server has changed.
+const (
+	RemoteChangeRestart = "restart" // Discard the partial and download the new version
+	RemoteChangeFail    = "fail"    // Stop with a file_changed error and keep the partial
+)
+
 // GetUserAgent returns the configured user agent or the default
 func (r *RuntimeConfig) GetUserAgent() string {
 	if r == nil || r.UserAgent == "" {
@@ -157,6 +280,53 @@ func (r *RuntimeConfig) GetProtocolPreference() string {
 	}
 }
 
+// GetOnRemoteChange returns a normalized remote change policy.
+func (r *RuntimeConfig) GetOnRemoteChange() string {
+	if r == nil {
+		return RemoteChangeRestart
+	}
+	if strings.ToLower(strings.TrimSpace(r.OnRemoteChange)) == RemoteChangeFail {
+		return RemoteChangeFail
+	}
+	return RemoteChangeRestart
+}
+
+// GetDiskReserve returns the bytes to keep free on the destination.
+func (r *RuntimeConfig) GetDiskReserve() int64 {
+	if r == nil || r.DiskReserve < 0 {
+		return 0
+	}
+	return r.DiskReserve
+}
+
+// GetTempDir returns the directory partial files are written to, or "" to
+// write them next to their destination.
+func (r *RuntimeConfig) GetTempDir() string {
+	if r == nil {
+		return ""
+	}
+	return strings.TrimSpace(r.TempDir)
+}
+
+// GetPreallocate reports whether files are preallocated.
+func (r *RuntimeConfig) GetPreallocate() bool {
+	return r != nil && r.Preallocate
+}
+
+// GetWriteBuffer returns the write-back budget in bytes, 0 if writes go
+// straight to the file.
+func (r *RuntimeConfig) GetWriteBuffer() int64 {
+	if r == nil || r.WriteBuffer <= 0 {
+		return 0
+	}
+	return r.WriteBuffer
+}
+
+// GetWriteDropCache reports whether written ranges are dropped from the page cache.
+func (r *RuntimeConfig) GetWriteDropCache() bool {
+	return r != nil && r.WriteDropCache
+}
+
 const (
 	MaxTaskRetries = 3
 	RetryBaseDelay = 200 * time.Millisecond
@@ -167,6 +337,16 @@ const (
 	SlowWorkerGrace     = 5 * time.Second // Grace period before checking speed
 	StallTimeout        = 5 * time.Second // Restart if no data for x seconds
 	SpeedEMAAlpha       = 0.3             // EMA smoothing factor
+
+	// Adaptive connection scaling
+	ScaleInterval     = 2 * time.Second // How often throughput is sampled to add or retire connections
+	ScaleMinGain      = 0.10            // Keep an added connection only if throughput rose by x
+	ScaleHoldSamples  = 5               // Samples to wait after a plateau before probing again
+	ScaleThrottleStep = 4               // Drop 1/x of the connections on 429/503
+
+	// Whole-download retries
+	DownloadRetryDelay    = 30 * time.Second
+	DownloadRetryMaxDelay = 10 * time.Minute
 )
 
 // GetMaxTaskRetries returns configured value or default
@@ -177,6 +357,14 @@ func (r *RuntimeConfig) GetMaxTaskRetries() int {
 	return r.MaxTaskRetries
 }
 
+// GetRetryBaseDelay returns configured value or default
+func (r *RuntimeConfig) GetRetryBaseDelay() time.Duration {
+	if r == nil || r.RetryBaseDelay <= 0 {
+		return RetryBaseDelay
+	}
+	return r.RetryBaseDelay
+}
+
 // GetSlowWorkerThreshold returns configured value or default
 func (r *RuntimeConfig) GetSlowWorkerThreshold() float64 {
 	if r == nil || r.SlowWorkerThreshold <= 0 {
@@ -208,3 +396,57 @@ func (r *RuntimeConfig) GetSpeedEmaAlpha() float64 {
 	}
 	return r.SpeedEmaAlpha
 }
+
+// GetDownloadRetryDelay returns configured value or default
+func (r *RuntimeConfig) GetDownloadRetryDelay() time.Duration {
+	if r == nil || r.DownloadRetryDelay <= 0 {
+		return DownloadRetryDelay
+	}
+	return r.DownloadRetryDelay
+}
+
+// GetDownloadRetryMaxDelay returns configured value or default
+func (r *RuntimeConfig) GetDownloadRetryMaxDelay() time.Duration {
+	if r == nil || r.DownloadRetryMaxDelay <= 0 {
+		return DownloadRetryMaxDelay
+	}
+	return r.DownloadRetryMaxDelay
+}
+
+// RetryDelay reports whether a download that failed with err should be
+// retried automatically, and how long to wait first. attempt is the retry
+// about to be made, counting from 1. The delay doubles with each attempt up
+// to the maximum, and a longer Retry-After from the server wins.
+func (r *RuntimeConfig) RetryDelay(err error, attempt int) (time.Duration, bool) {
+	if r == nil || attempt > r.DownloadRetries {
+		return 0, false
+	}
+	de := Classify(err)
+	if de.Kind == KindCancelled {
+		return 0, false
+	}
+	if de.Kind == KindFileChanged && r.GetOnRemoteChange() == RemoteChangeFail {
+		// Asked to stop at a changed file; a retry would only stop again.
+		return 0, false
+	}
+	qualifies := de.Retryable()
+	if r.RetryOn != nil {
+		// A listed http_status still only covers statuses that can pass (5xx, 408, 429).
+		qualifies = slices.Contains(r.RetryOn, de.Kind) &&
+			(de.Kind != KindHTTPStatus || de.Retryable())
+	}
+	if !qualifies {
+		return 0, false
+	}
+
+	maxDelay := r.GetDownloadRetryMaxDelay()
+	delay := maxDelay
+	if attempt <= 16 {
+		delay = min(r.GetDownloadRetryDelay()<<(attempt-1), maxDelay)
+	}
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) && throttled.RetryAfter > delay {
+		delay = throttled.RetryAfter
+	}
+	return delay, true
+}
@@ -6,6 +6,7 @@ import "concurrent_downloader/internal/config"
 func ConvertRuntimeConfig(rc *config.RuntimeConfig) *RuntimeConfig {
 	return &RuntimeConfig{
 		MaxConnectionsPerHost: rc.MaxConnectionsPerHost,
+		MaxGlobalConnections:  rc.MaxGlobalConnections,
 		UserAgent:             rc.UserAgent,
 		ProxyURL:              rc.ProxyURL,
 		SequentialDownload:    rc.SequentialDownload,
@@ -14,9 +15,20 @@ func ConvertRuntimeConfig(rc *config.RuntimeConfig) *RuntimeConfig {
 		ForceSingle:           rc.ForceSingle,
 		ProtocolPreference:    rc.ProtocolPreference,
 		MaxTaskRetries:        rc.MaxTaskRetries,
+		RetryBaseDelay:        rc.RetryBaseDelay,
 		SlowWorkerThreshold:   rc.SlowWorkerThreshold,
 		SlowWorkerGracePeriod: rc.SlowWorkerGracePeriod,
 		StallTimeout:          rc.StallTimeout,
 		SpeedEmaAlpha:         rc.SpeedEmaAlpha,
+		DownloadRetries:       rc.DownloadRetries,
+		DownloadRetryDelay:    rc.DownloadRetryDelay,
+		DownloadRetryMaxDelay: rc.DownloadRetryMaxDelay,
+		RetryOn:               ParseErrorKinds(rc.RetryOn),
+		OnRemoteChange:        rc.OnRemoteChange,
+		DiskReserve:           rc.DiskReserve,
+		Preallocate:           rc.Preallocate,
+		TempDir:               rc.TempDir,
+		WriteBuffer:           rc.WriteBuffer,
+		WriteDropCache:        rc.WriteDropCache,
 	}
 }
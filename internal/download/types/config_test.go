@@ -0,0 +1,62 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	base := RuntimeConfig{
+		DownloadRetries:       5,
+		DownloadRetryDelay:    time.Second,
+		DownloadRetryMaxDelay: 10 * time.Second,
+	}
+	network := NewError(KindNetwork, errors.New("connection reset"))
+
+	tests := []struct {
+		name    string
+		mod     func(*RuntimeConfig)
+		err     error
+		attempt int
+		want    time.Duration
+		retry   bool
+	}{
+		{"first attempt", nil, network, 1, time.Second, true},
+		{"doubles", nil, network, 3, 4 * time.Second, true},
+		{"capped", nil, network, 5, 10 * time.Second, true},
+		{"out of retries", nil, network, 6, 0, false},
+		{"retries off", func(r *RuntimeConfig) { r.DownloadRetries = 0 }, network, 1, 0, false},
+		{"huge attempt does not overflow", func(r *RuntimeConfig) { r.DownloadRetries = 100 }, network, 80, 10 * time.Second, true},
+		{"default delays", func(r *RuntimeConfig) { r.DownloadRetryDelay, r.DownloadRetryMaxDelay = 0, 0 }, network, 2, 2 * DownloadRetryDelay, true},
+		{"server error", nil, StatusError(http.StatusBadGateway), 1, time.Second, true},
+		{"not found", nil, StatusError(http.StatusNotFound), 1, 0, false},
+		{"cancelled", nil, context.Canceled, 1, 0, false},
+		{"longer retry-after wins", nil, &ThrottledError{StatusCode: 429, RetryAfter: time.Minute}, 1, time.Minute, true},
+		{"shorter retry-after ignored", nil, &ThrottledError{StatusCode: 429, RetryAfter: time.Millisecond}, 2, 2 * time.Second, true},
+		{"file changed, restart", nil, NewError(KindFileChanged, ErrFileChanged), 1, time.Second, true},
+		{"file changed, fail", func(r *RuntimeConfig) { r.OnRemoteChange = "FAIL" }, NewError(KindFileChanged, ErrFileChanged), 1, 0, false},
+		{"retry-on excludes kind", func(r *RuntimeConfig) { r.RetryOn = []ErrorKind{KindHTTPStatus} }, network, 1, 0, false},
+		{"retry-on adds kind", func(r *RuntimeConfig) { r.RetryOn = []ErrorKind{KindChecksum} }, NewError(KindChecksum, ErrChecksumMismatch), 1, time.Second, true},
+		{"retry-on keeps status rule", func(r *RuntimeConfig) { r.RetryOn = []ErrorKind{KindHTTPStatus} }, StatusError(http.StatusNotFound), 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := base
+			if tt.mod != nil {
+				tt.mod(&r)
+			}
+			got, retry := r.RetryDelay(tt.err, tt.attempt)
+			if got != tt.want || retry != tt.retry {
+				t.Errorf("RetryDelay(%v, %d) = %v, %v; want %v, %v", tt.err, tt.attempt, got, retry, tt.want, tt.retry)
+			}
+		})
+	}
+
+	var nilConfig *RuntimeConfig
+	if _, retry := nilConfig.RetryDelay(network, 1); retry {
+		t.Error("nil config retries")
+	}
+}
@@ -1,8 +1,259 @@
 package types
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
 
 // Common errors
 var (
-	ErrPaused = errors.New("download paused")
-)
\ No newline at end of file
+	ErrPaused           = errors.New("download paused")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrThrottled        = errors.New("server throttled the request")
+	ErrFileChanged      = errors.New("file changed on the server")
+)
+
+// ErrorKind classifies why a download failed, so callers can decide whether
+// to retry without matching on error text.
+type ErrorKind string
+
+const (
+	KindUnknown      ErrorKind = "unknown"
+	KindNetwork      ErrorKind = "network"       // Connection failed or dropped
+	KindHTTPStatus   ErrorKind = "http_status"   // Server answered with an unexpected status
+	KindDiskFull     ErrorKind = "disk_full"     // No space left on the destination
+	KindPermission   ErrorKind = "permission"    // Destination is not writable
+	KindChecksum     ErrorKind = "checksum"      // Content did not match the expected digest
+	KindAuthRequired ErrorKind = "auth_required" // Server wants credentials (401, 403, 407)
+	KindFileChanged  ErrorKind = "file_changed"  // Remote file no longer matches the partial
+	KindCancelled    ErrorKind = "cancelled"     // Stopped by the user or shutdown
+)
+
+// Retryable reports whether running the download again may succeed without
+// anything else changing. statusCode only matters for KindHTTPStatus.
+func (k ErrorKind) Retryable(statusCode int) bool {
+	switch k {
+	case KindNetwork, KindFileChanged:
+		return true
+	case KindHTTPStatus:
+		return statusCode >= 500 || statusCode == http.StatusRequestTimeout ||
+			statusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// ParseErrorKinds parses a comma-separated list of error kinds, e.g.
+// "network,http_status". An empty list yields nil.
+func ParseErrorKinds(s string) []ErrorKind {
+	var kinds []ErrorKind
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			kinds = append(kinds, ErrorKind(k))
+		}
+	}
+	return kinds
+}
+
+// DownloadError is a classified download failure. Error returns the message
+// of the wrapped error, so wrapping does not change what users see.
+type DownloadError struct {
+	Kind       ErrorKind
+	StatusCode int // HTTP status for KindHTTPStatus and KindAuthRequired, else 0
+	Err        error
+}
+
+// NewError wraps err with a kind.
+func NewError(kind ErrorKind, err error) *DownloadError {
+	return &DownloadError{Kind: kind, Err: err}
+}
+
+// StatusError returns the error for an unexpected HTTP status.
+func StatusError(statusCode int) *DownloadError {
+	kind := KindHTTPStatus
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired:
+		kind = KindAuthRequired
+	}
+	return &DownloadError{
+		Kind:       kind,
+		StatusCode: statusCode,
+		Err:        fmt.Errorf("unexpected status code: %d", statusCode),
+	}
+}
+
+// NeedsNewURL reports whether err means the URL itself stopped working
+// (401, 403 or 410), as when a presigned link expires. Only a new URL for
+// the same file lets the download continue.
+func NeedsNewURL(err error) bool {
+	var de *DownloadError
+	if !errors.As(err, &de) {
+		return false
+	}
+	switch de.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// IsDiskFull reports whether err means the destination ran out of space.
+// The download can continue from its partial once space frees up.
+func IsDiskFull(err error) bool {
+	de := Classify(err)
+	return de != nil && de.Kind == KindDiskFull
+}
+
+func (e *DownloadError) Error() string {
+	if e.Err == nil {
+		return string(e.Kind)
+	}
+	return e.Err.Error()
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether running the download again may succeed.
+func (e *DownloadError) Retryable() bool {
+	return e.Kind.Retryable(e.StatusCode)
+}
+
+// Is lets callers match a kind with errors.Is(err, ErrFileChanged) and the
+// like, even after the error crossed the HTTP API as text.
+func (e *DownloadError) Is(target error) bool {
+	switch target {
+	case ErrFileChanged:
+		return e.Kind == KindFileChanged
+	case ErrChecksumMismatch:
+		return e.Kind == KindChecksum
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+	case context.Canceled:
+		return e.Kind == KindCancelled
+	}
+	return false
+}
+
+// Classify returns err as a *DownloadError, inferring the kind from the
+// errors it wraps when it is not one already. It returns nil for nil.
+func Classify(err error) *DownloadError {
+	if err == nil {
+		return nil
+	}
+	var de *DownloadError
+	if errors.As(err, &de) {
+		if de == err {
+			return de
+		}
+		// Keep the outer message with the inner classification.
+		return &DownloadError{Kind: de.Kind, StatusCode: de.StatusCode, Err: err}
+	}
+
+	e := &DownloadError{Kind: KindUnknown, Err: err}
+	var throttled *ThrottledError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrPaused):
+		e.Kind = KindCancelled
+	case errors.Is(err, ErrChecksumMismatch):
+		e.Kind = KindChecksum
+	case errors.As(err, &throttled):
+		e.Kind = KindHTTPStatus
+		e.StatusCode = throttled.StatusCode
+	case errors.Is(err, syscall.ENOSPC):
+		e.Kind = KindDiskFull
+	case errors.Is(err, os.ErrPermission):
+		e.Kind = KindPermission
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, context.DeadlineExceeded):
+		e.Kind = KindNetwork
+	}
+	return e
+}
+
+// ChecksumMismatchError reports the digest that was expected and the one computed.
+type ChecksumMismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %s:%s, got %s:%s", e.Algorithm, e.Expected, e.Algorithm, e.Actual)
+}
+
+// Is lets callers match with errors.Is(err, ErrChecksumMismatch).
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// ThrottledError reports a 429 Too Many Requests or 503 Service Unavailable
+// response. RetryAfter is the server's Retry-After hint, 0 when it sent none.
+type ThrottledError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	msg := fmt.Sprintf("server throttled the request (%d)", e.StatusCode)
+	if e.StatusCode == http.StatusTooManyRequests {
+		msg = "rate limited (429)"
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	}
+	return msg
+}
+
+// Is lets callers match with errors.Is(err, ErrThrottled).
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+// ThrottledResponse returns a *ThrottledError for a 429 or 503 response and
+// nil for any other status.
+func ThrottledResponse(resp *http.Response) *ThrottledError {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	return &ThrottledError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// maxRetryAfter caps absurd Retry-After values.
+const maxRetryAfter = 24 * time.Hour
+
+// ParseRetryAfter reads a Retry-After header in either of its forms, delay
+// seconds or an HTTP date. It returns 0 for a missing, invalid or past value.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		d = maxRetryAfter
+		if secs < int64(maxRetryAfter/time.Second) {
+			d = time.Duration(secs) * time.Second
+		}
+	} else if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		d = min(t.Sub(now), maxRetryAfter)
+	}
+	return d
+}
@@ -0,0 +1,180 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"padded", " 5 ", 5 * time.Second},
+		{"zero", "0", 0},
+		{"negative", "-3", 0},
+		{"capped", "999999999999", maxRetryAfter},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"far date capped", now.Add(48 * time.Hour).Format(http.TimeFormat), maxRetryAfter},
+		{"garbage", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThrottledResponse(t *testing.T) {
+	tests := []struct {
+		status int
+		header string
+		want   *ThrottledError
+	}{
+		{http.StatusTooManyRequests, "7", &ThrottledError{StatusCode: 429, RetryAfter: 7 * time.Second}},
+		{http.StatusServiceUnavailable, "", &ThrottledError{StatusCode: 503}},
+		{http.StatusInternalServerError, "7", nil},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got := ThrottledResponse(resp)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("ThrottledResponse(%d) = %+v, want %+v", tt.status, got, tt.want)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		kind   ErrorKind
+		status int
+	}{
+		{"cancelled", context.Canceled, KindCancelled, 0},
+		{"paused", fmt.Errorf("worker: %w", ErrPaused), KindCancelled, 0},
+		{"checksum", &ChecksumMismatchError{Algorithm: "md5"}, KindChecksum, 0},
+		{"throttled", &ThrottledError{StatusCode: 429}, KindHTTPStatus, 429},
+		{"disk full", &os.PathError{Op: "write", Path: "f", Err: syscall.ENOSPC}, KindDiskFull, 0},
+		{"permission", &os.PathError{Op: "open", Path: "f", Err: os.ErrPermission}, KindPermission, 0},
+		{"net error", timeoutError{}, KindNetwork, 0},
+		{"unexpected eof", io.ErrUnexpectedEOF, KindNetwork, 0},
+		{"connection reset", syscall.ECONNRESET, KindNetwork, 0},
+		{"deadline", context.DeadlineExceeded, KindNetwork, 0},
+		{"status", StatusError(http.StatusBadGateway), KindHTTPStatus, 502},
+		{"auth status", StatusError(http.StatusForbidden), KindAuthRequired, 403},
+		{"wrapped kind", fmt.Errorf("probe: %w", NewError(KindFileChanged, ErrFileChanged)), KindFileChanged, 0},
+		{"unknown", errors.New("boom"), KindUnknown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if got.Kind != tt.kind || got.StatusCode != tt.status {
+				t.Errorf("Classify(%v) = %s/%d, want %s/%d", tt.err, got.Kind, got.StatusCode, tt.kind, tt.status)
+			}
+			if got.Error() != tt.err.Error() {
+				t.Errorf("Classify changed the message to %q", got.Error())
+			}
+		})
+	}
+	if Classify(nil) != nil {
+		t.Error("Classify(nil) is not nil")
+	}
+}
+
+func TestErrorKindRetryable(t *testing.T) {
+	tests := []struct {
+		kind   ErrorKind
+		status int
+		want   bool
+	}{
+		{KindNetwork, 0, true},
+		{KindFileChanged, 0, true},
+		{KindHTTPStatus, 500, true},
+		{KindHTTPStatus, 408, true},
+		{KindHTTPStatus, 429, true},
+		{KindHTTPStatus, 404, false},
+		{KindAuthRequired, 401, false},
+		{KindDiskFull, 0, false},
+		{KindChecksum, 0, false},
+		{KindCancelled, 0, false},
+		{KindUnknown, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.Retryable(tt.status); got != tt.want {
+			t.Errorf("%s.Retryable(%d) = %v, want %v", tt.kind, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadErrorIs(t *testing.T) {
+	tests := []struct {
+		err    *DownloadError
+		target error
+		want   bool
+	}{
+		{NewError(KindFileChanged, errors.New("etag changed")), ErrFileChanged, true},
+		{NewError(KindChecksum, errors.New("bad")), ErrChecksumMismatch, true},
+		{StatusError(http.StatusTooManyRequests), ErrThrottled, true},
+		{StatusError(http.StatusServiceUnavailable), ErrThrottled, true},
+		{StatusError(http.StatusInternalServerError), ErrThrottled, false},
+		{NewError(KindCancelled, nil), context.Canceled, true},
+		{NewError(KindNetwork, nil), ErrFileChanged, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(tt.err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%s/%d, %v) = %v, want %v", tt.err.Kind, tt.err.StatusCode, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestNeedsNewURL(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{StatusError(http.StatusUnauthorized), true},
+		{StatusError(http.StatusForbidden), true},
+		{fmt.Errorf("range: %w", StatusError(http.StatusGone)), true},
+		{StatusError(http.StatusNotFound), false},
+		{errors.New("unexpected status code: 403"), false},
+	}
+	for _, tt := range tests {
+		if got := NeedsNewURL(tt.err); got != tt.want {
+			t.Errorf("NeedsNewURL(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrorKinds(t *testing.T) {
+	got := ParseErrorKinds(" Network, ,http_status ")
+	if len(got) != 2 || got[0] != KindNetwork || got[1] != KindHTTPStatus {
+		t.Errorf("ParseErrorKinds = %v", got)
+	}
+	if got := ParseErrorKinds(""); got != nil {
+		t.Errorf("ParseErrorKinds(\"\") = %v, want nil", got)
+	}
+}
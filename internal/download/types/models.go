@@ -1,10 +1,36 @@
 package types
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type Task struct {
 	Offset int64 `json:"offset"`
 	Length int64 `json:"length"`
 }
 
+// InsertRange adds r to a list of ranges sorted by offset, merging it with
+// the ranges it overlaps or touches.
+func InsertRange(ranges []Task, r Task) []Task {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].Offset > r.Offset })
+	ranges = append(ranges, Task{})
+	copy(ranges[i+1:], ranges[i:])
+	ranges[i] = r
+
+	merged := ranges[:1]
+	for _, next := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if next.Offset <= last.Offset+last.Length {
+			last.Length = max(last.Length, next.Offset+next.Length-last.Offset)
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
 // DownloadState persists a paused download so it can be resumed later.
 type DownloadState struct {
 	ID         string   `json:"id"`       // Unique ID of the download
@@ -26,6 +52,59 @@ type DownloadState struct {
 
 	// Integrity verification
 	FileHash string `json:"file_hash,omitempty"` // SHA-256 hash of the .GoFetch file at pause time
+	Checksum string `json:"checksum,omitempty"`  // Expected digest ("algo:hex") checked on completion
+
+	// Incremental hash progress so resume does not re-read the verified prefix
+	HashState   []byte `json:"hash_state,omitempty"`   // Serialized hash.Hash state
+	HashedBytes int64  `json:"hashed_bytes,omitempty"` // Length of the prefix covered by HashState
+
+	Pieces *PieceHashes `json:"pieces,omitempty"` // Per-piece digests from a metalink
+
+	RateLimit int64 `json:"rate_limit,omitempty"` // Speed cap in bytes/sec, 0 = unlimited
+
+	// Validators of the partial content, sent back in If-Range on resume
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	WorkingPath string `json:"working_path,omitempty"` // Partial file, moved to DestPath on completion
+}
+
+// IfRange returns the validator to send in If-Range when resuming, or "" if
+// there is none. Weak ETags are not allowed in If-Range (RFC 9110 13.1.5).
+func (s *DownloadState) IfRange() string {
+	if s == nil {
+		return ""
+	}
+	return IfRange(s.ETag, s.LastModified)
+}
+
+// IfRange picks the If-Range validator from a response's ETag and
+// Last-Modified, preferring a strong ETag.
+func IfRange(etag, lastModified string) string {
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return lastModified
+}
+
+// RemoteChange describes how the version the server reports now differs from
+// the one the partial was downloaded from, or returns "" when nothing shows a
+// change. Validators missing on either side are not evidence of one.
+func (s *DownloadState) RemoteChange(size int64, etag, lastModified string) string {
+	if s == nil {
+		return ""
+	}
+	switch {
+	case size > 0 && s.TotalSize > 0 && size != s.TotalSize:
+		return fmt.Sprintf("size changed from %d to %d bytes", s.TotalSize, size)
+	case etag != "" && s.ETag != "":
+		if etag != s.ETag {
+			return fmt.Sprintf("ETag changed from %s to %s", s.ETag, etag)
+		}
+	case lastModified != "" && s.LastModified != "" && lastModified != s.LastModified:
+		return fmt.Sprintf("Last-Modified changed from %s to %s", s.LastModified, lastModified)
+	}
+	return ""
 }
 
 type DownloadEntry struct {
@@ -33,14 +112,37 @@ type DownloadEntry struct {
 	URLHash     string   `json:"url_hash"` // Hash of URL only (backward compatibility)
 	URL         string   `json:"url"`
 	DestPath    string   `json:"dest_path"`
+	WorkingPath string   `json:"working_path,omitempty"` // Partial file while unfinished
 	Filename    string   `json:"filename"`
-	Status      string   `json:"status"`       // "paused", "completed", "error"
+	Status      string   `json:"status"`       // "scheduled", "queued", "paused", "completed", "retrying", "needs_url", "disk_full", "error", "checksum_mismatch"
 	TotalSize   int64    `json:"total_size"`   // File size in bytes
 	Downloaded  int64    `json:"downloaded"`   // Bytes downloaded
 	CompletedAt int64    `json:"completed_at"` // Unix timestamp when completed
 	TimeTaken   int64    `json:"time_taken"`   // Duration in milliseconds (for completed)
 	AvgSpeed    float64  `json:"avg_speed"`    // Average speed in bytes/sec (for completed)
 	Mirrors     []string `json:"mirrors,omitempty"`
+	Checksum    string   `json:"checksum,omitempty"`       // Expected digest ("algo:hex"), if any
+	StartAt     int64    `json:"start_at,omitempty"`       // Unix timestamp a scheduled download is queued at
+	PauseAt     int64    `json:"pause_at,omitempty"`       // Unix timestamp the download is paused at
+	Priority    int      `json:"priority,omitempty"`       // Higher runs first
+	QueuePos    int      `json:"queue_position,omitempty"` // 1-based place in the run queue when last saved
+	RateLimit   int64    `json:"rate_limit,omitempty"`     // Speed cap in bytes/sec, 0 = unlimited
+
+	// Why the download failed (status "error" or "checksum_mismatch" only)
+	Error       string    `json:"error,omitempty"`
+	ErrorKind   ErrorKind `json:"error_kind,omitempty"`
+	ErrorStatus int       `json:"error_status,omitempty"` // HTTP status code, if the server caused it
+
+	RetryCount  int   `json:"retry_count,omitempty"`   // Automatic retries made so far
+	NextRetryAt int64 `json:"next_retry_at,omitempty"` // Unix timestamp of the next automatic retry (status "retrying")
+}
+
+// SetError records a failed download's error in classified form.
+func (e *DownloadEntry) SetError(err error) {
+	de := Classify(err)
+	e.Error = de.Error()
+	e.ErrorKind = de.Kind
+	e.ErrorStatus = de.StatusCode
 }
 
 type MasterList struct {
@@ -57,11 +159,71 @@ type DownloadStatus struct {
 	Downloaded  int64   `json:"downloaded"`
 	Progress    float64 `json:"progress"` // Percentage 0-100
 	Speed       float64 `json:"speed"`    // MB/s
-	Status      string  `json:"status"`   // "queued", "paused", "downloading", "completed", "error"
+	Status      string  `json:"status"`   // "scheduled", "queued", "paused", "downloading", "completed", "retrying", "needs_url", "disk_full", "error", "checksum_mismatch"
 	Error       string  `json:"error,omitempty"`
 	ETA         int64   `json:"eta"`         // Estimated seconds remaining
 	Connections int     `json:"connections"` // Active connections
 	AddedAt     int64   `json:"added_at"`    // Unix timestamp when added
 	TimeTaken   int64   `json:"time_taken"`  // Duration in milliseconds (completed only)
 	AvgSpeed    float64 `json:"avg_speed"`   // Average speed in bytes/sec (completed only)
+
+	RateLimit int64 `json:"rate_limit,omitempty"`     // Speed cap in bytes/sec (active only)
+	StartAt   int64 `json:"start_at,omitempty"`       // Unix timestamp a scheduled download is queued at
+	PauseAt   int64 `json:"pause_at,omitempty"`       // Unix timestamp the download is paused at
+	Priority  int   `json:"priority,omitempty"`       // Higher runs first
+	QueuePos  int   `json:"queue_position,omitempty"` // 1-based place in the run queue (queued only)
+
+	// Connections open to each of the download's origins, counted across all downloads (active only)
+	HostConnections map[string]int `json:"host_connections,omitempty"`
+	// Unix timestamp a throttled origin of the download backs off until (active only)
+	BackoffUntil int64 `json:"backoff_until,omitempty"`
+
+	// Classification of Error, so clients can decide whether to retry
+	ErrorKind   ErrorKind `json:"error_kind,omitempty"`
+	ErrorStatus int       `json:"error_status,omitempty"` // HTTP status code, if the server caused it
+	Retryable   bool      `json:"retryable,omitempty"`
+
+	RetryCount  int   `json:"retry_count,omitempty"`   // Automatic retries made so far
+	NextRetryAt int64 `json:"next_retry_at,omitempty"` // Unix timestamp of the next automatic retry (status "retrying")
+
+	// How the download's writes reached the disk (active chunked downloads only)
+	Writes *WriteStats `json:"writes,omitempty"`
+}
+
+// WriteStats counts the writes of a chunked download: those its workers
+// made and those the write-back buffer issued to the file after merging
+// neighbours. DiskBytes above the file size is write amplification, e.g.
+// from hedged or re-fetched ranges.
+type WriteStats struct {
+	Writes     int64 `json:"writes"`      // Writes made by workers
+	Bytes      int64 `json:"bytes"`       // Bytes written by workers
+	DiskWrites int64 `json:"disk_writes"` // Writes issued to the file
+	DiskBytes  int64 `json:"disk_bytes"`  // Bytes issued to the file
+}
+
+// SetError fills the error fields from a failed download's error.
+func (s *DownloadStatus) SetError(err error) {
+	de := Classify(err)
+	s.Error = de.Error()
+	s.ErrorKind = de.Kind
+	s.ErrorStatus = de.StatusCode
+	s.Retryable = de.Retryable()
+}
+
+// SetEntryError copies the error and retry state saved with a download entry.
+func (s *DownloadStatus) SetEntryError(e DownloadEntry) {
+	s.Error = e.Error
+	s.ErrorKind = e.ErrorKind
+	s.ErrorStatus = e.ErrorStatus
+	s.Retryable = e.ErrorKind.Retryable(e.ErrorStatus)
+	s.RetryCount = e.RetryCount
+	s.NextRetryAt = e.NextRetryAt
+}
+
+// ScheduleStatus describes the limits the bandwidth schedule currently applies.
+type ScheduleStatus struct {
+	Enabled                bool   `json:"enabled"`
+	Profile                string `json:"profile,omitempty"` // Empty outside every scheduled window
+	RateLimit              int64  `json:"rate_limit"`        // Global speed cap in bytes/sec, 0 = unlimited
+	MaxConcurrentDownloads int    `json:"max_concurrent_downloads"`
 }
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInsertRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []Task
+		add    Task
+		want   []Task
+	}{
+		{"into empty", nil, Task{5, 5}, []Task{{5, 5}}},
+		{"before", []Task{{10, 10}}, Task{0, 5}, []Task{{0, 5}, {10, 10}}},
+		{"after", []Task{{10, 10}}, Task{25, 5}, []Task{{10, 10}, {25, 5}}},
+		{"touching below", []Task{{10, 10}}, Task{5, 5}, []Task{{5, 15}}},
+		{"touching above", []Task{{10, 10}}, Task{20, 10}, []Task{{10, 20}}},
+		{"overlapping", []Task{{10, 10}}, Task{15, 10}, []Task{{10, 15}}},
+		{"contained", []Task{{10, 10}}, Task{12, 3}, []Task{{10, 10}}},
+		{"same offset, longer", []Task{{10, 10}}, Task{10, 15}, []Task{{10, 15}}},
+		{"bridging", []Task{{0, 5}, {10, 5}, {20, 5}}, Task{5, 15}, []Task{{0, 25}}},
+		{"gap remains", []Task{{0, 5}, {10, 5}}, Task{6, 3}, []Task{{0, 5}, {6, 3}, {10, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InsertRange(append([]Task(nil), tt.ranges...), tt.add)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InsertRange(%v, %v) = %v, want %v", tt.ranges, tt.add, got, tt.want)
+			}
+		})
+	}
+}
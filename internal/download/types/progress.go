@@ -2,6 +2,7 @@ package types
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +17,7 @@ type ProgressState struct {
 	Downloaded    atomic.Int64
 	TotalSize     int64
 	DestPath      string // Initial destination path
+	WorkingPath   string // Partial file while downloading, once known
 	Filename      string // Initial filename
 	StartTime     time.Time
 	ActiveWorkers atomic.Int32
@@ -37,7 +39,14 @@ type ProgressState struct {
 	ActualChunkSize int64   // Size of each actual chunk in bytes
 	BitmapWidth     int     // Number of chunks tracked
 
-	mu sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes, SavedElapsed, Mirrors
+	Writes WriteStats // Write-back counters of a chunked download
+
+	written     []Task        // Ranges of the working file on disk, sorted and merged
+	writtenWake chan struct{} // Closed and replaced when written grows
+	readers     map[any]int64 // Where each reader of the working file reads next
+	writtenMu   sync.Mutex    // Protects written, writtenWake and readers
+
+	mu sync.Mutex // Protects TotalSize, StartTime, SessionStartBytes, SavedElapsed, Mirrors, Writes
 }
 
 type MirrorStatus struct {
@@ -58,6 +67,123 @@ func (ps *ProgressState) GetDestPath() string {
 	return ps.DestPath
 }
 
+func (ps *ProgressState) SetWorkingPath(path string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.WorkingPath = path
+}
+
+// GetWorkingPath returns the partial file, or the default next to the
+// destination before the download has started.
+func (ps *ProgressState) GetWorkingPath() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.WorkingPath != "" {
+		return ps.WorkingPath
+	}
+	if ps.DestPath == "" {
+		return ""
+	}
+	return ps.DestPath + IncompleteSuffix
+}
+
+func (ps *ProgressState) SetWriteStats(stats WriteStats) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.Writes = stats
+}
+
+func (ps *ProgressState) GetWriteStats() WriteStats {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.Writes
+}
+
+// SetWritten replaces the ranges of the working file known to be on disk,
+// e.g. with what a resumed partial already holds.
+func (ps *ProgressState) SetWritten(ranges []Task) {
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	ps.written = nil
+	for _, r := range ranges {
+		if r.Length > 0 {
+			ps.written = InsertRange(ps.written, r)
+		}
+	}
+	ps.wakeWritten()
+}
+
+// MarkWritten records that [offset, offset+length) of the working file is
+// on disk, waking readers that follow the download.
+func (ps *ProgressState) MarkWritten(offset, length int64) {
+	if length <= 0 {
+		return
+	}
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	ps.written = InsertRange(ps.written, Task{Offset: offset, Length: length})
+	ps.wakeWritten()
+}
+
+// WrittenAt returns how many bytes from offset on are on disk in one piece,
+// and a channel that is closed once more may be.
+func (ps *ProgressState) WrittenAt(offset int64) (int64, <-chan struct{}) {
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	if ps.writtenWake == nil {
+		ps.writtenWake = make(chan struct{})
+	}
+	i := sort.Search(len(ps.written), func(i int) bool {
+		return ps.written[i].Offset+ps.written[i].Length > offset
+	})
+	if i < len(ps.written) && ps.written[i].Offset <= offset {
+		return ps.written[i].Offset + ps.written[i].Length - offset, ps.writtenWake
+	}
+	return 0, ps.writtenWake
+}
+
+// SetReadPosition records where the reader identified by key reads the
+// working file next, so the download can fetch the bytes there first.
+func (ps *ProgressState) SetReadPosition(key any, offset int64) {
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	if ps.readers == nil {
+		ps.readers = make(map[any]int64)
+	}
+	ps.readers[key] = offset
+}
+
+// ClearReadPosition forgets the reader identified by key.
+func (ps *ProgressState) ClearReadPosition(key any) {
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	delete(ps.readers, key)
+}
+
+// ReadPositions returns where the readers of the working file read next,
+// lowest first.
+func (ps *ProgressState) ReadPositions() []int64 {
+	ps.writtenMu.Lock()
+	defer ps.writtenMu.Unlock()
+	if len(ps.readers) == 0 {
+		return nil
+	}
+	positions := make([]int64, 0, len(ps.readers))
+	for _, offset := range ps.readers {
+		positions = append(positions, offset)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	return positions
+}
+
+// wakeWritten wakes readers waiting in WrittenAt. Call it with writtenMu held.
+func (ps *ProgressState) wakeWritten() {
+	if ps.writtenWake != nil {
+		close(ps.writtenWake)
+		ps.writtenWake = nil
+	}
+}
+
 func (ps *ProgressState) SetFilename(filename string) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
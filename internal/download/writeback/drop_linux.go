@@ -0,0 +1,20 @@
+//go:build linux
+
+package writeback
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropPages writes [off, off+length) of f to disk and drops it from the
+// page cache, so written data does not pile up as dirty pages.
+func dropPages(f *os.File, off, length int64) error {
+	fd := int(f.Fd())
+	flags := unix.SYNC_FILE_RANGE_WAIT_BEFORE | unix.SYNC_FILE_RANGE_WRITE | unix.SYNC_FILE_RANGE_WAIT_AFTER
+	if err := unix.SyncFileRange(fd, off, length, flags); err != nil {
+		return err
+	}
+	return unix.Fadvise(fd, off, length, unix.FADV_DONTNEED)
+}
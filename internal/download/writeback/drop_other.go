@@ -0,0 +1,11 @@
+//go:build !linux
+
+package writeback
+
+import "os"
+
+// dropPages leaves the page cache alone on platforms without
+// sync_file_range and fadvise.
+func dropPages(f *os.File, off, length int64) error {
+	return nil
+}
@@ -0,0 +1,220 @@
+// Package writeback gathers the scattered writes of a download's workers in
+// memory and writes them to the file in offset order, merging neighbouring
+// ranges, so disks that seek slowly see fewer and larger writes.
+package writeback
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// FlushInterval bounds how long a write waits in the buffer when the
+// budget is not reached.
+const FlushInterval = time.Second
+
+// Writer buffers writes to a file up to a budget. Once buffered data
+// exceeds it, everything is written out in offset order. With no budget
+// every write goes straight to the file.
+//
+// Data that is still buffered is not in the file yet, so anything reading
+// the file back waits for Commit.
+type Writer struct {
+	file      *os.File
+	budget    int64
+	dropCache bool
+
+	mu       sync.Mutex
+	extents  []extent // Buffered ranges, sorted by offset, not overlapping
+	buffered int64
+	commits  []commit // Commits waiting for their range to be written
+	failed   bool     // The last flush failed
+	stats    types.WriteStats
+}
+
+type extent struct {
+	offset int64
+	data   []byte
+}
+
+func (e *extent) end() int64 { return e.offset + int64(len(e.data)) }
+
+type commit struct {
+	offset, length int64
+	fn             func()
+}
+
+// New returns a Writer for file that buffers up to budget bytes. With
+// dropCache, written ranges are flushed to disk and dropped from the page
+// cache where the platform supports it.
+func New(file *os.File, budget int64, dropCache bool) *Writer {
+	return &Writer{file: file, budget: budget, dropCache: dropCache}
+}
+
+// WriteAt buffers p at off, writing the buffer out first if p overlaps it,
+// so a later write always wins. A write that cannot be buffered goes to the
+// file directly. An error from an earlier flush is reported here, to the
+// next worker that writes, unless writing the buffer now succeeds.
+func (w *Writer) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	w.stats.Writes++
+	w.stats.Bytes += int64(len(p))
+
+	if w.failed || w.overlaps(off, int64(len(p))) {
+		if err := w.flushLocked(); err != nil {
+			w.mu.Unlock()
+			return 0, err
+		}
+	}
+
+	n, err := len(p), error(nil)
+	if int64(len(p)) >= w.budget {
+		n, err = w.writeLocked(p, off)
+	} else {
+		w.insert(p, off)
+		if w.buffered >= w.budget {
+			err = w.flushLocked()
+		}
+	}
+	ready := w.readyCommits()
+	w.mu.Unlock()
+
+	runCommits(ready)
+	return n, err
+}
+
+// Commit calls fn once [off, off+length) is in the file: right away if
+// none of it is buffered, otherwise after the flush that writes it.
+func (w *Writer) Commit(off, length int64, fn func()) {
+	w.mu.Lock()
+	if w.overlaps(off, length) {
+		w.commits = append(w.commits, commit{offset: off, length: length, fn: fn})
+		w.mu.Unlock()
+		return
+	}
+	w.mu.Unlock()
+	fn()
+}
+
+// Flush writes all buffered data to the file in offset order. On error the
+// ranges not written stay buffered.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	err := w.flushLocked()
+	ready := w.readyCommits()
+	w.mu.Unlock()
+
+	runCommits(ready)
+	return err
+}
+
+// Pending returns the buffered ranges, which are not in the file. After a
+// failed final Flush they have to be downloaded again.
+func (w *Writer) Pending() []types.Task {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tasks := make([]types.Task, 0, len(w.extents))
+	for _, e := range w.extents {
+		tasks = append(tasks, types.Task{Offset: e.offset, Length: int64(len(e.data))})
+	}
+	return tasks
+}
+
+// Stats returns the writes made so far.
+func (w *Writer) Stats() types.WriteStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+// overlaps reports whether any buffered byte lies in [off, off+length).
+func (w *Writer) overlaps(off, length int64) bool {
+	i := sort.Search(len(w.extents), func(i int) bool { return w.extents[i].end() > off })
+	return i < len(w.extents) && w.extents[i].offset < off+length
+}
+
+// insert copies p into the buffer at off, which must not overlap it, and
+// merges it with the ranges it touches.
+func (w *Writer) insert(p []byte, off int64) {
+	w.buffered += int64(len(p))
+	i := sort.Search(len(w.extents), func(i int) bool { return w.extents[i].offset >= off })
+
+	// Workers write their ranges front to back, so most writes extend the
+	// range before them.
+	if i > 0 && w.extents[i-1].end() == off {
+		prev := &w.extents[i-1]
+		prev.data = append(prev.data, p...)
+		if i < len(w.extents) && w.extents[i].offset == prev.end() {
+			prev.data = append(prev.data, w.extents[i].data...)
+			w.extents = append(w.extents[:i], w.extents[i+1:]...)
+		}
+		return
+	}
+
+	data := append([]byte(nil), p...)
+	if i < len(w.extents) && w.extents[i].offset == off+int64(len(p)) {
+		w.extents[i].data = append(data, w.extents[i].data...)
+		w.extents[i].offset = off
+		return
+	}
+	w.extents = append(w.extents, extent{})
+	copy(w.extents[i+1:], w.extents[i:])
+	w.extents[i] = extent{offset: off, data: data}
+}
+
+// flushLocked writes the buffered ranges in offset order. Ranges written
+// before an error leave the buffer; the rest stay.
+func (w *Writer) flushLocked() error {
+	for len(w.extents) > 0 {
+		e := w.extents[0]
+		if _, err := w.writeLocked(e.data, e.offset); err != nil {
+			w.failed = true
+			return err
+		}
+		w.buffered -= int64(len(e.data))
+		w.extents = w.extents[1:]
+	}
+	w.extents = nil
+	w.failed = false
+	return nil
+}
+
+// writeLocked writes p to the file at off and counts it.
+func (w *Writer) writeLocked(p []byte, off int64) (int, error) {
+	n, err := w.file.WriteAt(p, off)
+	w.stats.DiskWrites++
+	w.stats.DiskBytes += int64(n)
+	if err == nil && w.dropCache {
+		if err := dropPages(w.file, off, int64(n)); err != nil {
+			utils.Debug("Failed to drop written pages of %s: %v", w.file.Name(), err)
+		}
+	}
+	return n, err
+}
+
+// readyCommits removes and returns the commits whose range is written.
+func (w *Writer) readyCommits() []commit {
+	var ready []commit
+	waiting := w.commits[:0]
+	for _, c := range w.commits {
+		if w.overlaps(c.offset, c.length) {
+			waiting = append(waiting, c)
+		} else {
+			ready = append(ready, c)
+		}
+	}
+	clear(w.commits[len(waiting):])
+	w.commits = waiting
+	return ready
+}
+
+// runCommits runs commits outside the lock, since they read the file.
+func runCommits(commits []commit) {
+	for _, c := range commits {
+		c.fn()
+	}
+}
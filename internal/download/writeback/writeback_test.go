@@ -0,0 +1,155 @@
+package writeback
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+// ext builds an extent of n bytes, each set to its offset's low byte.
+func ext(off, n int64) extent {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(off + int64(i))
+	}
+	return extent{offset: off, data: data}
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name    string
+		extents []extent
+		add     extent
+		want    []extent
+	}{
+		{"into empty", nil, ext(10, 5), []extent{ext(10, 5)}},
+		{"before", []extent{ext(10, 5)}, ext(0, 5), []extent{ext(0, 5), ext(10, 5)}},
+		{"after", []extent{ext(10, 5)}, ext(20, 5), []extent{ext(10, 5), ext(20, 5)}},
+		{"extends previous", []extent{ext(10, 5)}, ext(15, 5), []extent{ext(10, 10)}},
+		{"prepends to next", []extent{ext(10, 5)}, ext(5, 5), []extent{ext(5, 10)}},
+		{"fills a gap", []extent{ext(0, 5), ext(10, 5)}, ext(5, 5), []extent{ext(0, 15)}},
+		{"one short of a gap", []extent{ext(0, 5), ext(10, 5)}, ext(5, 4), []extent{ext(0, 9), ext(10, 5)}},
+		{"between", []extent{ext(0, 5), ext(20, 5)}, ext(10, 5), []extent{ext(0, 5), ext(10, 5), ext(20, 5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Writer{}
+			for _, e := range tt.extents {
+				w.insert(e.data, e.offset)
+			}
+			w.insert(tt.add.data, tt.add.offset)
+
+			if !reflect.DeepEqual(w.extents, tt.want) {
+				t.Errorf("extents = %v, want %v", spans(w.extents), spans(tt.want))
+			}
+			var total int64
+			for _, e := range tt.want {
+				total += int64(len(e.data))
+			}
+			if w.buffered != total {
+				t.Errorf("buffered = %d, want %d", w.buffered, total)
+			}
+		})
+	}
+}
+
+func TestInsertCopies(t *testing.T) {
+	w := &Writer{}
+	p := []byte{1, 2, 3}
+	w.insert(p, 0)
+	p[0] = 9
+	if w.extents[0].data[0] != 1 {
+		t.Error("insert kept the caller's buffer")
+	}
+}
+
+func TestReadyCommits(t *testing.T) {
+	w := &Writer{extents: []extent{ext(10, 10), ext(40, 10)}}
+	var ran []int64
+	for _, off := range []int64{0, 5, 15, 20, 30, 45, 50} {
+		w.commits = append(w.commits, commit{offset: off, length: 10, fn: func() { ran = append(ran, off) }})
+	}
+
+	runCommits(w.readyCommits())
+	// Commits that touch a buffered byte keep waiting.
+	if want := []int64{0, 20, 30, 50}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if len(w.commits) != 3 {
+		t.Errorf("%d commits waiting, want 3", len(w.commits))
+	}
+}
+
+func TestWriterFlushesInOrder(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := New(f, 40, false)
+
+	want := ext(0, 40).data
+	var committed []int64
+	// Out of order, in small pieces; the last one reaches the budget.
+	for _, off := range []int64{20, 30, 0} {
+		if _, err := w.WriteAt(want[off:off+10], off); err != nil {
+			t.Fatal(err)
+		}
+		w.Commit(off, 10, func() { committed = append(committed, off) })
+	}
+	if got := w.Pending(); !reflect.DeepEqual(got, []types.Task{{Offset: 0, Length: 10}, {Offset: 20, Length: 20}}) {
+		t.Errorf("Pending = %v", got)
+	}
+	if len(committed) != 0 {
+		t.Errorf("committed %v before the flush", committed)
+	}
+	if _, err := w.WriteAt(want[10:20], 10); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(f.Name())
+	if !bytes.Equal(got, want) {
+		t.Errorf("file = %v, want %v", got, want)
+	}
+	if stats := w.Stats(); stats.Writes != 4 || stats.DiskWrites != 1 || stats.DiskBytes != 40 {
+		t.Errorf("Stats = %+v, want 4 writes merged into 1", stats)
+	}
+	if len(committed) != 3 || len(w.Pending()) != 0 {
+		t.Errorf("committed %v with %v pending, want all after the flush", committed, w.Pending())
+	}
+}
+
+func TestWriterLaterWriteWins(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := New(f, 1<<20, false)
+
+	if _, err := w.WriteAt([]byte("aaaa"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// Overlaps the buffer, which goes out first.
+	if _, err := w.WriteAt([]byte("bb"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(f.Name()); string(got) != "abba" {
+		t.Errorf("file = %q, want %q", got, "abba")
+	}
+}
+
+func spans(extents []extent) []types.Task {
+	var tasks []types.Task
+	for _, e := range extents {
+		tasks = append(tasks, types.Task{Offset: e.offset, Length: int64(len(e.data))})
+	}
+	return tasks
+}
@@ -31,7 +31,9 @@ type DownloadCompleteMsg struct {
 	AvgSpeed   float64 // Average download speed in bytes/sec
 }
 
-// DownloadErrorMsg signals that an error occurred
+// DownloadErrorMsg signals that an error occurred. Err is a
+// *types.DownloadError when sent by the worker pool, and is rebuilt as one
+// when decoded, so errors.Is/As work on both sides of the HTTP API.
 type DownloadErrorMsg struct {
 	DownloadID string
 	Filename   string
@@ -41,9 +43,12 @@ type DownloadErrorMsg struct {
 func (m DownloadErrorMsg) MarshalJSON() ([]byte, error) {
 	// Ensure errors serialize as strings for client compatibility.
 	type encoded struct {
-		DownloadID string `json:"DownloadID"`
-		Filename   string `json:"Filename,omitempty"`
-		Err        string `json:"Err,omitempty"`
+		DownloadID string          `json:"DownloadID"`
+		Filename   string          `json:"Filename,omitempty"`
+		Err        string          `json:"Err,omitempty"`
+		Kind       types.ErrorKind `json:"Kind,omitempty"`
+		StatusCode int             `json:"StatusCode,omitempty"`
+		Retryable  bool            `json:"Retryable,omitempty"`
 	}
 
 	out := encoded{
@@ -51,7 +56,11 @@ func (m DownloadErrorMsg) MarshalJSON() ([]byte, error) {
 		Filename:   m.Filename,
 	}
 	if m.Err != nil {
-		out.Err = m.Err.Error()
+		de := types.Classify(m.Err)
+		out.Err = de.Error()
+		out.Kind = de.Kind
+		out.StatusCode = de.StatusCode
+		out.Retryable = de.Retryable()
 	}
 
 	return json.Marshal(out)
@@ -62,6 +71,8 @@ func (m *DownloadErrorMsg) UnmarshalJSON(data []byte) error {
 		DownloadID string          `json:"DownloadID"`
 		Filename   string          `json:"Filename"`
 		Err        json.RawMessage `json:"Err"`
+		Kind       types.ErrorKind `json:"Kind"`
+		StatusCode int             `json:"StatusCode"`
 	}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
@@ -76,22 +87,114 @@ func (m *DownloadErrorMsg) UnmarshalJSON(data []byte) error {
 	}
 
 	// Most common case: server sends Err as a string.
+	var cause error
 	var errStr string
 	if err := json.Unmarshal(aux.Err, &errStr); err == nil {
 		if errStr != "" {
-			m.Err = errors.New(errStr)
+			cause = errors.New(errStr)
 		}
+	} else if raw := string(aux.Err); raw != "" && raw != "null" {
+		// Backward/forward compatibility: accept non-string payloads (e.g. {}).
+		cause = errors.New(raw)
+	}
+	if cause == nil {
 		return nil
 	}
 
-	// Backward/forward compatibility: accept non-string payloads (e.g. {}).
-	raw := string(aux.Err)
-	if raw != "" && raw != "null" {
-		m.Err = errors.New(raw)
+	if aux.Kind == "" {
+		// Older servers send no classification.
+		aux.Kind = types.KindUnknown
 	}
+	m.Err = &types.DownloadError{Kind: aux.Kind, StatusCode: aux.StatusCode, Err: cause}
 	return nil
 }
 
+// DownloadChecksumMismatchMsg signals that the downloaded content did not match
+// the expected digest. It is followed by a DownloadErrorMsg for the same download.
+type DownloadChecksumMismatchMsg struct {
+	DownloadID string
+	Filename   string
+	Algorithm  string
+	Expected   string
+	Actual     string
+}
+
+// DownloadRetryMsg signals that a dropped connection is being re-established.
+// Attempt counts from 1 up to MaxAttempts.
+type DownloadRetryMsg struct {
+	DownloadID  string
+	Filename    string
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration // Backoff before this attempt
+	Offset      int64         // Byte offset the download continues from (0 = restart)
+	Reason      string
+}
+
+// DownloadRetryScheduledMsg is sent when a failed download will be retried
+// automatically at RetryAt, continuing from its partial file. Attempt counts
+// from 1 up to MaxAttempts. It takes the place of a DownloadErrorMsg.
+type DownloadRetryScheduledMsg struct {
+	DownloadID  string
+	Filename    string
+	Attempt     int
+	MaxAttempts int
+	RetryAt     time.Time
+	Kind        types.ErrorKind
+	Reason      string
+}
+
+// DownloadBackoffMsg is sent when a server throttles a download (429 or 503)
+// and its host backs off until Until. Other downloads from the same host
+// wait as well.
+type DownloadBackoffMsg struct {
+	DownloadID string
+	Filename   string
+	Host       string // Origin, e.g. https://example.com:443
+	StatusCode int
+	Until      time.Time
+}
+
+// DownloadNeedsURLMsg is sent when a download stops because its URL no
+// longer works (401, 403 or 410), typically an expired presigned link. The
+// partial is kept until a new URL is supplied with RefreshURL. It takes the
+// place of a DownloadErrorMsg.
+type DownloadNeedsURLMsg struct {
+	DownloadID string
+	Filename   string
+	URL        string
+	StatusCode int
+	Reason     string
+}
+
+// DownloadURLRefreshedMsg is sent when a download's URL has been replaced
+// by one serving the same file.
+type DownloadURLRefreshedMsg struct {
+	DownloadID string
+	Filename   string
+	URL        string
+}
+
+// DownloadDiskFullMsg is sent when a download stops because the disk holding
+// DestPath is full. The partial is kept and the download continues by
+// itself once the rest of it fits. It takes the place of a DownloadErrorMsg.
+type DownloadDiskFullMsg struct {
+	DownloadID string
+	Filename   string
+	DestPath   string
+	Reason     string
+}
+
+// DownloadMovingMsg reports progress while a finished download is copied
+// from a temp dir on another filesystem to its destination. Moves that are
+// a plain rename send none.
+type DownloadMovingMsg struct {
+	DownloadID string
+	Filename   string
+	Moved      int64
+	Total      int64
+}
+
 // DownloadStartedMsg is sent when a download actually starts (after metadata fetch)
 type DownloadStartedMsg struct {
 	DownloadID string
@@ -118,11 +221,30 @@ type DownloadQueuedMsg struct {
 	Filename   string
 }
 
+// DownloadScheduledMsg is sent when a download is held until StartAt.
+// PauseAt is zero when no pause time was requested.
+type DownloadScheduledMsg struct {
+	DownloadID string
+	Filename   string
+	StartAt    time.Time
+	PauseAt    time.Time
+}
+
 type DownloadRemovedMsg struct {
 	DownloadID string
 	Filename   string
 }
 
+// ScheduleProfileChangedMsg is sent when the bandwidth schedule switches
+// profiles. Profile is empty when no scheduled window applies and the
+// configured defaults are back in effect.
+type ScheduleProfileChangedMsg struct {
+	Profile                string
+	Previous               string
+	RateLimit              int64 // Global speed cap in bytes/sec, 0 = unlimited
+	MaxConcurrentDownloads int
+}
+
 // BatchProgressMsg represents a batch of progress updates to reduce TUI render calls
 type BatchProgressMsg []ProgressMsg
 
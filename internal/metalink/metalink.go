@@ -0,0 +1,269 @@
+// Package metalink reads Metalink 4.0 (RFC 5854) documents and turns each
+// described file into a download with mirrors, expected size and hashes.
+package metalink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"concurrent_downloader/internal/download/integrity"
+	"concurrent_downloader/internal/download/types"
+	"concurrent_downloader/internal/utils"
+)
+
+// Namespace is the XML namespace of Metalink 4.0 documents.
+const Namespace = "urn:ietf:params:xml:ns:metalink"
+
+// maxDocumentSize bounds how much of a metalink we read.
+const maxDocumentSize = 16 * types.MB
+
+// File is one file described by a metalink.
+type File struct {
+	Name     string             // Base filename (directories are stripped)
+	Size     int64              // Expected size in bytes, 0 if not given
+	Checksum *types.Checksum    // Strongest supported whole-file hash
+	Pieces   *types.PieceHashes // Strongest supported piece hashes
+	URLs     []URL
+}
+
+// URL is a mirror for a File.
+type URL struct {
+	URL      string
+	Priority int    // 1 is the most preferred; 0 means unspecified
+	Location string // ISO 3166-1 alpha-2 country code, lowercase
+}
+
+type xmlMetalink struct {
+	XMLName xml.Name  `xml:"metalink"`
+	Files   []xmlFile `xml:"file"`
+}
+
+type xmlFile struct {
+	Name   string      `xml:"name,attr"`
+	Size   int64       `xml:"size"`
+	Hashes []xmlHash   `xml:"hash"`
+	Pieces []xmlPieces `xml:"pieces"`
+	URLs   []xmlURL    `xml:"url"`
+}
+
+type xmlHash struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlPieces struct {
+	Type   string   `xml:"type,attr"`
+	Length int64    `xml:"length,attr"`
+	Hashes []string `xml:"hash"`
+}
+
+type xmlURL struct {
+	Location string `xml:"location,attr"`
+	Priority int    `xml:"priority,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// IsMetalinkFile reports whether arg names an existing local .meta4 or .metalink file.
+func IsMetalinkFile(arg string) bool {
+	ext := strings.ToLower(filepath.Ext(arg))
+	if ext != ".meta4" && ext != ".metalink" {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// Load reads a metalink from a local path or an http(s) URL.
+func Load(src string) ([]File, error) {
+	if u, err := url.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		client := &http.Client{Timeout: types.ProbeTimeout}
+		resp, err := client.Get(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch metalink: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch metalink: unexpected status %d", resp.StatusCode)
+		}
+		return Parse(resp.Body)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metalink: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return Parse(f)
+}
+
+// Parse decodes a Metalink 4.0 document.
+func Parse(r io.Reader) ([]File, error) {
+	var doc xmlMetalink
+	if err := xml.NewDecoder(io.LimitReader(r, maxDocumentSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid metalink: %w", err)
+	}
+	if doc.XMLName.Space != Namespace {
+		return nil, fmt.Errorf("unsupported metalink namespace %q (only Metalink 4.0 is supported)", doc.XMLName.Space)
+	}
+
+	var files []File
+	for _, xf := range doc.Files {
+		f := File{
+			Name: sanitizeName(xf.Name),
+			Size: xf.Size,
+		}
+
+		var hashes []*types.Checksum
+		for _, h := range xf.Hashes {
+			if c, err := types.NewChecksum(h.Type, h.Value); err == nil {
+				hashes = append(hashes, c)
+			} else {
+				utils.Debug("metalink: skipping hash for %s: %v", xf.Name, err)
+			}
+		}
+		f.Checksum = integrity.Strongest(hashes)
+		f.Pieces = strongestPieces(xf.Pieces, xf.Size)
+
+		for _, u := range xf.URLs {
+			raw := strings.TrimSpace(u.Value)
+			if parsed, err := url.Parse(raw); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				// FTP and other schemes are not supported by the downloaders.
+				continue
+			}
+			f.URLs = append(f.URLs, URL{
+				URL:      raw,
+				Priority: u.Priority,
+				Location: strings.ToLower(strings.TrimSpace(u.Location)),
+			})
+		}
+
+		files = append(files, f)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("metalink describes no files")
+	}
+	return files, nil
+}
+
+// Mirrors returns the file's URLs ordered for download: preferred locations
+// first, then by priority, keeping document order for ties.
+func (f File) Mirrors(locations []string) []string {
+	rank := make(map[string]int, len(locations))
+	for i, loc := range locations {
+		rank[loc] = i
+	}
+	locationRank := func(u URL) int {
+		if r, ok := rank[u.Location]; ok {
+			return r
+		}
+		return len(locations)
+	}
+	priority := func(u URL) int {
+		if u.Priority <= 0 {
+			return 1 << 30 // Unspecified sorts after every explicit priority
+		}
+		return u.Priority
+	}
+
+	urls := make([]URL, len(f.URLs))
+	copy(urls, f.URLs)
+	sort.SliceStable(urls, func(i, j int) bool {
+		if li, lj := locationRank(urls[i]), locationRank(urls[j]); li != lj {
+			return li < lj
+		}
+		return priority(urls[i]) < priority(urls[j])
+	})
+
+	mirrors := make([]string, 0, len(urls))
+	for _, u := range urls {
+		mirrors = append(mirrors, u.URL)
+	}
+	return mirrors
+}
+
+// AddOptions layers the file's size and hashes on top of base. A checksum in
+// base is replaced, since it cannot describe every file of the metalink.
+func (f File) AddOptions(base *types.AddOptions) *types.AddOptions {
+	opts := &types.AddOptions{}
+	if base != nil {
+		*opts = *base
+	}
+	opts.Checksum = f.Checksum
+	opts.Pieces = f.Pieces
+	opts.Size = f.Size
+	return opts
+}
+
+// ParseLocations splits a comma-separated list of country codes.
+func ParseLocations(raw string) []string {
+	var locations []string
+	for _, loc := range strings.Split(raw, ",") {
+		if loc = strings.ToLower(strings.TrimSpace(loc)); loc != "" {
+			locations = append(locations, loc)
+		}
+	}
+	return locations
+}
+
+// strongestPieces picks the best supported piece list that matches size.
+func strongestPieces(candidates []xmlPieces, size int64) *types.PieceHashes {
+	var best *types.PieceHashes
+	bestRank := -1
+	for _, xp := range candidates {
+		p, err := types.NewPieceHashes(xp.Type, xp.Length, trimAll(xp.Hashes))
+		if err != nil {
+			utils.Debug("metalink: skipping pieces: %v", err)
+			continue
+		}
+		if size > 0 && !p.Matches(size) {
+			utils.Debug("metalink: skipping %s pieces that do not cover %d bytes", p.Algorithm, size)
+			continue
+		}
+		if r := pieceRank(p.Algorithm); r > bestRank {
+			best, bestRank = p, r
+		}
+	}
+	return best
+}
+
+// pieceRank scores piece algorithms so stronger ones win.
+func pieceRank(algo string) int {
+	switch algo {
+	case types.ChecksumSHA512:
+		return 5
+	case types.ChecksumSHA256:
+		return 4
+	case types.ChecksumSHA1:
+		return 3
+	case types.ChecksumMD5:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func trimAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+// sanitizeName keeps only the last path element so documents can't escape the output dir.
+func sanitizeName(name string) string {
+	base := path.Base(path.Clean("/" + strings.ReplaceAll(name, "\\", "/")))
+	if base == "/" || base == "." {
+		return ""
+	}
+	return base
+}
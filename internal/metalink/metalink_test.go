@@ -0,0 +1,154 @@
+package metalink
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"concurrent_downloader/internal/download/types"
+)
+
+const (
+	sha1Hex   = "a9993e364706816aba3e25717850c26c9cd0d89d"
+	sha256Hex = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	md5Hex    = "900150983cd24fb0d6963f7d28e17f72"
+)
+
+func TestParse(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<metalink xmlns="urn:ietf:params:xml:ns:metalink">
+  <file name="../../etc/example.iso">
+    <size>10</size>
+    <hash type="md5">` + md5Hex + `</hash>
+    <hash type="sha-256">` + strings.ToUpper(sha256Hex) + `</hash>
+    <hash type="sha-1">not-hex</hash>
+    <pieces type="sha-1" length="4">
+      <hash>` + sha1Hex + `</hash>
+      <hash>` + sha1Hex + `</hash>
+    </pieces>
+    <pieces type="md5" length="4">
+      <hash>` + md5Hex + `</hash>
+      <hash>` + md5Hex + `</hash>
+      <hash>` + md5Hex + `</hash>
+    </pieces>
+    <url location="DE" priority="2"> https://de.example.com/example.iso </url>
+    <url priority="1">ftp://ftp.example.com/example.iso</url>
+    <url>http://plain.example.com/example.iso</url>
+  </file>
+  <file name="second">
+    <url>https://example.com/second</url>
+  </file>
+</metalink>`
+
+	files, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+
+	f := files[0]
+	if f.Name != "example.iso" {
+		t.Errorf("Name = %q, want the base name only", f.Name)
+	}
+	if f.Size != 10 {
+		t.Errorf("Size = %d, want 10", f.Size)
+	}
+	want := &types.Checksum{Algorithm: types.ChecksumSHA256, Value: sha256Hex}
+	if !reflect.DeepEqual(f.Checksum, want) {
+		t.Errorf("Checksum = %v, want the strongest valid hash %v", f.Checksum, want)
+	}
+	// The sha-1 pieces cover 8 of 10 bytes, so the weaker md5 list is used.
+	if f.Pieces == nil || f.Pieces.Algorithm != types.ChecksumMD5 || len(f.Pieces.Hashes) != 3 {
+		t.Errorf("Pieces = %+v, want the md5 list that covers the file", f.Pieces)
+	}
+	wantURLs := []URL{
+		{URL: "https://de.example.com/example.iso", Priority: 2, Location: "de"},
+		{URL: "http://plain.example.com/example.iso"},
+	}
+	if !reflect.DeepEqual(f.URLs, wantURLs) {
+		t.Errorf("URLs = %+v, want %+v", f.URLs, wantURLs)
+	}
+
+	if files[1].Checksum != nil || files[1].Pieces != nil || files[1].Size != 0 {
+		t.Errorf("second file = %+v, want no size or hashes", files[1])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"not xml", "hello", "invalid metalink"},
+		{"metalink 3", `<metalink xmlns="http://www.metalinker.org/"><file name="a"/></metalink>`, "unsupported metalink namespace"},
+		{"no files", `<metalink xmlns="urn:ietf:params:xml:ns:metalink"></metalink>`, "describes no files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	f := File{URLs: []URL{
+		{URL: "a", Priority: 0, Location: "us"},
+		{URL: "b", Priority: 3, Location: "de"},
+		{URL: "c", Priority: 1},
+		{URL: "d", Priority: 2, Location: "fr"},
+		{URL: "e", Priority: 1, Location: "de"},
+		{URL: "f", Priority: 0},
+	}}
+
+	tests := []struct {
+		name      string
+		locations []string
+		want      []string
+	}{
+		{"priority only", nil, []string{"c", "e", "d", "b", "a", "f"}},
+		{"preferred location first", []string{"de"}, []string{"e", "b", "c", "d", "a", "f"}},
+		{"locations in given order", []string{"fr", "us"}, []string{"d", "a", "c", "e", "b", "f"}},
+		{"unknown location", []string{"jp"}, []string{"c", "e", "d", "b", "a", "f"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.Mirrors(tt.locations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mirrors(%v) = %v, want %v", tt.locations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	got := ParseLocations(" DE, ,fr,US ")
+	want := []string{"de", "fr", "us"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLocations = %v, want %v", got, want)
+	}
+	if got := ParseLocations(""); got != nil {
+		t.Errorf("ParseLocations(\"\") = %v, want nil", got)
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"file.iso":             "file.iso",
+		"dir/file.iso":         "file.iso",
+		"../../etc/passwd":     "passwd",
+		`..\..\windows\system`: "system",
+		"":                     "",
+		"/":                    "",
+		"..":                   "",
+	}
+	for in, want := range tests {
+		if got := sanitizeName(in); got != want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"concurrent_downloader/internal/download/httpclient"
+	"concurrent_downloader/internal/download/integrity"
 	"concurrent_downloader/internal/download/types"
 	"concurrent_downloader/internal/utils"
 )
@@ -21,6 +23,9 @@ var ua = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) " +
 	"AppleWebKit/537.36 (KHTML, like Gecko) " +
 	"Chrome/120.0.0.0 Safari/537.36"
 
+// wantReprDigest states our digest preferences to servers that support RFC 9530.
+const wantReprDigest = "sha-512=3, sha-256=10"
+
 // ProbeResult contains all metadata from server probe.
 type ProbeResult struct {
 	FileSize      int64
@@ -29,6 +34,11 @@ type ProbeResult struct {
 	ContentType   string
 	SupportsHTTP2 bool
 	SupportsHTTP3 bool
+	Checksum      *types.Checksum // Whole-file digest advertised by the server, if any
+
+	// Validators of the current version, used to detect a changed file on resume
+	ETag         string
+	LastModified string
 }
 
 // ProbeServer sends GET with Range: bytes=0-0 to determine server capabilities.
@@ -41,22 +51,8 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 
 	// Create a client that preserves headers on redirects (for authenticated downloads).
 	client := &http.Client{
-		Timeout: types.ProbeTimeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("stopped after 10 redirects")
-			}
-			// Copy headers from original request to redirect request
-			if len(via) > 0 {
-				for key, vals := range via[0].Header {
-					if key == "Range" {
-						continue
-					}
-					req.Header[key] = vals
-				}
-			}
-			return nil
-		},
+		Timeout:       types.ProbeTimeout,
+		CheckRedirect: httpclient.PreserveHeadersOnRedirect,
 	}
 
 	// Retry logic for probe request to handle flaky networks.
@@ -83,6 +79,8 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 		}
 
 		req.Header.Set("Range", "bytes=0-0")
+		// Ask RFC 9530 servers to include a whole-file digest.
+		req.Header.Set("Want-Repr-Digest", wantReprDigest)
 		// Set User-Agent only if not provided in custom headers
 		if req.Header.Get("User-Agent") == "" {
 			req.Header.Set("User-Agent", ua)
@@ -108,6 +106,7 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 					reqNoRange.Header.Set(key, val)
 				}
 			}
+			reqNoRange.Header.Set("Want-Repr-Digest", wantReprDigest)
 			if reqNoRange.Header.Get("User-Agent") == "" {
 				reqNoRange.Header.Set("User-Agent", ua)
 			}
@@ -160,7 +159,10 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 		utils.Debug("Range NOT supported (got 200), file size: %d", result.FileSize)
 
 	default:
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if throttled := types.ThrottledResponse(resp); throttled != nil {
+			return nil, throttled
+		}
+		return nil, types.StatusError(resp.StatusCode)
 	}
 
 	// Determine filename using strengthened logic.
@@ -177,6 +179,14 @@ func ProbeServer(ctx context.Context, rawurl string, filenameHint string, header
 	}
 
 	result.ContentType = resp.Header.Get("Content-Type")
+	result.ETag = resp.Header.Get("ETag")
+	result.LastModified = resp.Header.Get("Last-Modified")
+
+	// Capture any advertised whole-file digest so completion can verify against it.
+	result.Checksum = integrity.RepresentationDigest(resp.Header, resp.StatusCode == http.StatusPartialContent)
+	if result.Checksum != nil {
+		utils.Debug("Server advertised checksum %s", result.Checksum)
+	}
 
 	parsedURL, parseErr := url.Parse(rawurl)
 	if parseErr == nil && strings.EqualFold(parsedURL.Scheme, "https") {
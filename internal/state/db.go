@@ -65,7 +65,23 @@ func initDB() error {
 		time_taken INTEGER,
 		mirrors TEXT,
 		chunk_bitmap BLOB,
-		actual_chunk_size INTEGER
+		actual_chunk_size INTEGER,
+		checksum TEXT,
+		hash_state BLOB,
+		hashed_bytes INTEGER,
+		pieces TEXT,
+		etag TEXT,
+		last_modified TEXT,
+		start_at INTEGER,
+		pause_at INTEGER,
+		priority INTEGER,
+		queue_position INTEGER,
+		error TEXT,
+		error_kind TEXT,
+		error_status INTEGER,
+		retry_count INTEGER,
+		next_retry_at INTEGER,
+		working_path TEXT
 	);
 
 	CREATE TABLE IF NOT EXISTS tasks (
@@ -81,6 +97,67 @@ func initDB() error {
 		return fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	if err := migrateColumns(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// downloadColumns lists columns added after the initial schema so databases
+// created by older versions are upgraded in place.
+var downloadColumns = []struct {
+	name string
+	decl string
+}{
+	{"checksum", "TEXT"},
+	{"hash_state", "BLOB"},
+	{"hashed_bytes", "INTEGER"},
+	{"pieces", "TEXT"},
+	{"etag", "TEXT"},
+	{"last_modified", "TEXT"},
+	{"start_at", "INTEGER"},
+	{"pause_at", "INTEGER"},
+	{"priority", "INTEGER"},
+	{"queue_position", "INTEGER"},
+	{"error", "TEXT"},
+	{"error_kind", "TEXT"},
+	{"error_status", "INTEGER"},
+	{"retry_count", "INTEGER"},
+	{"next_retry_at", "INTEGER"},
+	{"working_path", "TEXT"},
+	{"rate_limit", "INTEGER"},
+}
+
+// migrateColumns adds any missing columns to the downloads table.
+func migrateColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(downloads)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan schema: %w", err)
+		}
+		existing[name] = true
+	}
+	_ = rows.Close()
+
+	for _, col := range downloadColumns {
+		if existing[col.name] {
+			continue
+		}
+		utils.Debug("Migrating state DB: adding downloads.%s", col.name)
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE downloads ADD COLUMN %s %s", col.name, col.decl)); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
 	return nil
 }
 
@@ -6,9 +6,11 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -21,6 +23,48 @@ func URLHash(url string) string {
 	return hex.EncodeToString(h[:8]) // 16 chars
 }
 
+// encodePieces stores piece hashes as JSON text (NULL when absent).
+func encodePieces(p *types.PieceHashes) any {
+	if p == nil {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// partialPath returns the stored partial file of a download, or the
+// default next to destPath for rows saved before partials could move.
+func partialPath(raw sql.NullString, destPath string) string {
+	if raw.String != "" {
+		return raw.String
+	}
+	return destPath + types.IncompleteSuffix
+}
+
+// decodePieces reverses encodePieces, ignoring malformed rows.
+func decodePieces(raw sql.NullString) *types.PieceHashes {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+	var p types.PieceHashes
+	if err := json.Unmarshal([]byte(raw.String), &p); err != nil {
+		utils.Debug("Ignoring malformed piece hashes: %v", err)
+		return nil
+	}
+	return &p
+}
+
 // SaveState saves download state to SQLite for pause/resume support.
 func SaveState(url string, destPath string, state *types.DownloadState) error {
 	// Ensure ID is set
@@ -41,8 +85,8 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 		// 1. Upsert into downloads table for quick lookup.
 		_, err := tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, checksum, hash_state, hashed_bytes, pieces, etag, last_modified, working_path
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -55,8 +99,19 @@ func SaveState(url string, destPath string, state *types.DownloadState) error {
 				time_taken=excluded.time_taken,
 				mirrors=excluded.mirrors,
 				chunk_bitmap=excluded.chunk_bitmap,
-				actual_chunk_size=excluded.actual_chunk_size
-		`, state.ID, state.URL, state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize)
+				actual_chunk_size=excluded.actual_chunk_size,
+				checksum=excluded.checksum,
+				hash_state=excluded.hash_state,
+				hashed_bytes=excluded.hashed_bytes,
+				pieces=excluded.pieces,
+				etag=excluded.etag,
+				last_modified=excluded.last_modified,
+				working_path=excluded.working_path,
+				error=NULL,
+				error_kind=NULL,
+				error_status=NULL,
+				next_retry_at=NULL
+		`, state.ID, state.URL, state.DestPath, state.Filename, "paused", state.TotalSize, state.Downloaded, state.URLHash, state.CreatedAt, state.PausedAt, state.Elapsed/1e6, strings.Join(state.Mirrors, ","), state.ChunkBitmap, state.ActualChunkSize, state.Checksum, state.HashState, state.HashedBytes, encodePieces(state.Pieces), state.ETag, state.LastModified, nullString(state.WorkingPath))
 
 		if err != nil {
 			return fmt.Errorf("failed to upsert download: %w", err)
@@ -96,12 +151,12 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 	utils.Debug("Loading state for URL: %s, destPath: %s", url, destPath)
 
 	var state types.DownloadState
-	var timeTaken, createdAt, pausedAt, actualChunkSize sql.NullInt64 // handle null
-	var mirrors sql.NullString                                        // handle null mirrors
-	var chunkBitmap []byte
+	var timeTaken, createdAt, pausedAt, actualChunkSize, hashedBytes, rateLimit sql.NullInt64 // handle null
+	var mirrors, checksum, pieces, etag, lastModified, workingPath sql.NullString             // handle null text columns
+	var chunkBitmap, hashState []byte
 
 	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size
+		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, checksum, hash_state, hashed_bytes, pieces, etag, last_modified, working_path, rate_limit
 		FROM downloads 
 		WHERE url = ? AND dest_path = ? AND status != 'completed'
 		ORDER BY paused_at DESC LIMIT 1
@@ -110,7 +165,7 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 	err := row.Scan(
 		&state.ID, &state.URL, &state.DestPath, &state.Filename,
 		&state.TotalSize, &state.Downloaded, &state.URLHash,
-		&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize,
+		&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize, &checksum, &hashState, &hashedBytes, &pieces, &etag, &lastModified, &workingPath, &rateLimit,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -137,6 +192,18 @@ func LoadState(url string, destPath string) (*types.DownloadState, error) {
 		state.ActualChunkSize = actualChunkSize.Int64
 	}
 	state.ChunkBitmap = chunkBitmap
+	if checksum.Valid {
+		state.Checksum = checksum.String
+	}
+	if hashedBytes.Valid {
+		state.HashedBytes = hashedBytes.Int64
+	}
+	state.HashState = hashState
+	state.Pieces = decodePieces(pieces)
+	state.ETag = etag.String
+	state.LastModified = lastModified.String
+	state.WorkingPath = partialPath(workingPath, state.DestPath)
+	state.RateLimit = rateLimit.Int64
 
 	rows, err := db.Query("SELECT offset, length FROM tasks WHERE download_id = ?", state.ID)
 	if err != nil {
@@ -211,7 +278,7 @@ func LoadMasterList() (*types.MasterList, error) {
 	}
 
 	rows, err := db.Query(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors 
+		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, checksum, start_at, pause_at, priority, queue_position, error, error_kind, error_status, retry_count, next_retry_at, working_path, rate_limit
 		FROM downloads
 	`)
 	if err != nil {
@@ -223,12 +290,13 @@ func LoadMasterList() (*types.MasterList, error) {
 	var list types.MasterList
 	for rows.Next() {
 		var e types.DownloadEntry
-		var completedAt, timeTaken sql.NullInt64      // handle nulls
-		var filename, urlHash, mirrors sql.NullString // handle nulls
+		var completedAt, timeTaken, startAt, pauseAt, priority, queuePos, errStatus, retries, nextRetry, rateLimit sql.NullInt64 // handle nulls
+		var filename, urlHash, mirrors, checksum, errMsg, errKind, workingPath sql.NullString                                    // handle nulls
 
 		if err := rows.Scan(
 			&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-			&completedAt, &timeTaken, &urlHash, &mirrors,
+			&completedAt, &timeTaken, &urlHash, &mirrors, &checksum, &startAt, &pauseAt, &priority, &queuePos,
+			&errMsg, &errKind, &errStatus, &retries, &nextRetry, &workingPath, &rateLimit,
 		); err != nil {
 			utils.Debug("Failed to scan download entry: %v", err)
 			return nil, fmt.Errorf("failed to scan download: %w", err)
@@ -249,6 +317,20 @@ func LoadMasterList() (*types.MasterList, error) {
 		if mirrors.Valid && mirrors.String != "" {
 			e.Mirrors = strings.Split(mirrors.String, ",")
 		}
+		if checksum.Valid {
+			e.Checksum = checksum.String
+		}
+		e.StartAt = startAt.Int64
+		e.PauseAt = pauseAt.Int64
+		e.Priority = int(priority.Int64)
+		e.QueuePos = int(queuePos.Int64)
+		e.Error = errMsg.String
+		e.ErrorKind = types.ErrorKind(errKind.String)
+		e.ErrorStatus = int(errStatus.Int64)
+		e.RetryCount = int(retries.Int64)
+		e.NextRetryAt = nextRetry.Int64
+		e.RateLimit = rateLimit.Int64
+		e.WorkingPath = partialPath(workingPath, e.DestPath)
 
 		list.Downloads = append(list.Downloads, e)
 	}
@@ -264,6 +346,8 @@ func LoadMasterList() (*types.MasterList, error) {
 }
 
 // AddToMasterList upserts a download record for history and duplicates checks.
+// The rate limit is only stored for a new record; UpdateRateLimit changes it,
+// so entries built without one do not clear it.
 func AddToMasterList(entry types.DownloadEntry) error {
 	if entry.ID == "" {
 		if entry.URLHash != "" {
@@ -276,8 +360,8 @@ func AddToMasterList(entry types.DownloadEntry) error {
 	return withTx(func(tx *sql.Tx) error {
 		_, err := tx.Exec(`
 			INSERT INTO downloads (
-				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, checksum, start_at, pause_at, priority, error, error_kind, error_status, retry_count, next_retry_at, rate_limit
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				url=excluded.url,
 				dest_path=excluded.dest_path,
@@ -288,10 +372,22 @@ func AddToMasterList(entry types.DownloadEntry) error {
 				completed_at=excluded.completed_at,
 				time_taken=excluded.time_taken,
 				url_hash=excluded.url_hash,
-				mirrors=excluded.mirrors
+				mirrors=excluded.mirrors,
+				checksum=excluded.checksum,
+				start_at=excluded.start_at,
+				pause_at=excluded.pause_at,
+				priority=excluded.priority,
+				error=excluded.error,
+				error_kind=excluded.error_kind,
+				error_status=excluded.error_status,
+				retry_count=excluded.retry_count,
+				next_retry_at=excluded.next_retry_at
 		`,
+
 			entry.ID, entry.URL, entry.DestPath, entry.Filename, entry.Status, entry.TotalSize, entry.Downloaded,
-			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","))
+			entry.CompletedAt, entry.TimeTaken, entry.URLHash, strings.Join(entry.Mirrors, ","), entry.Checksum,
+			entry.StartAt, entry.PauseAt, entry.Priority, entry.Error, string(entry.ErrorKind), entry.ErrorStatus,
+			entry.RetryCount, entry.NextRetryAt, entry.RateLimit)
 
 		if err != nil {
 			utils.Debug("Failed to insert/update download: %v", err)
@@ -331,18 +427,19 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 	utils.Debug("Getting download by ID: %s", id)
 
 	var e types.DownloadEntry
-	var completedAt, timeTaken sql.NullInt64
-	var urlHash, filename, mirrors sql.NullString
+	var completedAt, timeTaken, startAt, pauseAt, priority, queuePos, errStatus, retries, nextRetry, rateLimit sql.NullInt64
+	var urlHash, filename, mirrors, checksum, errMsg, errKind, workingPath sql.NullString
 
 	row := db.QueryRow(`
-		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors 
+		SELECT id, url, dest_path, filename, status, total_size, downloaded, completed_at, time_taken, url_hash, mirrors, checksum, start_at, pause_at, priority, queue_position, error, error_kind, error_status, retry_count, next_retry_at, working_path, rate_limit
 		FROM downloads
 		WHERE id = ?
 	`, id)
 
 	if err := row.Scan(
 		&e.ID, &e.URL, &e.DestPath, &filename, &e.Status, &e.TotalSize, &e.Downloaded,
-		&completedAt, &timeTaken, &urlHash, &mirrors,
+		&completedAt, &timeTaken, &urlHash, &mirrors, &checksum, &startAt, &pauseAt, &priority, &queuePos,
+		&errMsg, &errKind, &errStatus, &retries, &nextRetry, &workingPath, &rateLimit,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			utils.Debug("Download not found: %s", id)
@@ -367,6 +464,20 @@ func GetDownload(id string) (*types.DownloadEntry, error) {
 	if mirrors.Valid && mirrors.String != "" {
 		e.Mirrors = strings.Split(mirrors.String, ",")
 	}
+	if checksum.Valid {
+		e.Checksum = checksum.String
+	}
+	e.StartAt = startAt.Int64
+	e.PauseAt = pauseAt.Int64
+	e.Priority = int(priority.Int64)
+	e.QueuePos = int(queuePos.Int64)
+	e.Error = errMsg.String
+	e.ErrorKind = types.ErrorKind(errKind.String)
+	e.ErrorStatus = int(errStatus.Int64)
+	e.RetryCount = int(retries.Int64)
+	e.NextRetryAt = nextRetry.Int64
+	e.RateLimit = rateLimit.Int64
+	e.WorkingPath = partialPath(workingPath, e.DestPath)
 
 	return &e, nil
 }
@@ -385,6 +496,11 @@ func LoadPausedDownloads() ([]types.DownloadEntry, error) {
 			paused = append(paused, e)
 		}
 	}
+	// Downloads that were running (no queue position) go first, then the
+	// queue in its saved order.
+	sort.SliceStable(paused, func(i, j int) bool {
+		return paused[i].QueuePos < paused[j].QueuePos
+	})
 	return paused, nil
 }
 
@@ -431,7 +547,10 @@ func UpdateStatus(id string, status string) error {
 
 	utils.Debug("Updating status for download %s to %s", id, status)
 
-	result, err := db.Exec("UPDATE downloads SET status = ? WHERE id = ?", status, id)
+	// Every external transition leaves the error state, so drop its details
+	// and any pending automatic retry.
+	result, err := db.Exec(`UPDATE downloads SET status = ?, error = NULL, error_kind = NULL, error_status = NULL, next_retry_at = NULL
+		WHERE id = ?`, status, id)
 	if err != nil {
 		utils.Debug("Failed to update status: %v", err)
 		return fmt.Errorf("failed to update status: %w", err)
@@ -446,14 +565,212 @@ func UpdateStatus(id string, status string) error {
 	return nil
 }
 
-// PauseAllDownloads marks all in-flight downloads as paused.
+// UpdateSchedule sets a download's start-at and pause-at times (Unix
+// seconds, 0 = none).
+func UpdateSchedule(id string, startAt int64, pauseAt int64) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	utils.Debug("Updating schedule for download %s: start_at=%d pause_at=%d", id, startAt, pauseAt)
+
+	result, err := db.Exec("UPDATE downloads SET start_at = ?, pause_at = ? WHERE id = ?", startAt, pauseAt, id)
+	if err != nil {
+		utils.Debug("Failed to update schedule: %v", err)
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+	return nil
+}
+
+// UpdateRetry marks a failed download as waiting for automatic retry
+// attempt, due at nextRetryAt (Unix seconds). The error details are kept.
+func UpdateRetry(id string, attempt int, nextRetryAt int64) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	utils.Debug("Download %s retries (attempt %d) at %d", id, attempt, nextRetryAt)
+
+	result, err := db.Exec("UPDATE downloads SET status = 'retrying', retry_count = ?, next_retry_at = ? WHERE id = ?", attempt, nextRetryAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update retry: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+	return nil
+}
+
+// UpdateURL points a download, and its saved partial, at a new URL with the
+// given mirror set.
+func UpdateURL(id string, url string, mirrors []string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	utils.Debug("Updating URL of download %s to %s", id, url)
+
+	result, err := db.Exec("UPDATE downloads SET url = ?, url_hash = ?, mirrors = ? WHERE id = ?",
+		url, URLHash(url), strings.Join(mirrors, ","), id)
+	if err != nil {
+		return fmt.Errorf("failed to update url: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download not found: %s", id)
+	}
+	return nil
+}
+
+// UpdatePaths records where a starting download will end up and where its
+// partial is written meanwhile.
+func UpdatePaths(id string, destPath string, workingPath string) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if _, err := db.Exec("UPDATE downloads SET dest_path = ?, working_path = ? WHERE id = ?",
+		destPath, nullString(workingPath), id); err != nil {
+		return fmt.Errorf("failed to update paths: %w", err)
+	}
+	return nil
+}
+
+// DestPathInUse reports whether a started download other than exceptID
+// will finish at destPath. Its partial may be in a temp dir, so the name
+// is not taken on disk yet.
+func DestPathInUse(destPath string, exceptID string) bool {
+	db := getDBHelper()
+	if db == nil {
+		return false
+	}
+
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM downloads
+		WHERE dest_path = ? AND id != ? AND status != 'completed' AND working_path IS NOT NULL`,
+		destPath, exceptID).Scan(&n)
+	if err != nil {
+		utils.Debug("Failed to check destination %s: %v", destPath, err)
+		return false
+	}
+	return n > 0
+}
+
+// SaveQueueOrder records the run queue, ids[0] first, as 1-based positions.
+// Positions of downloads no longer queued are cleared.
+func SaveQueueOrder(ids []string) error {
+	return withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("UPDATE downloads SET queue_position = NULL WHERE queue_position IS NOT NULL"); err != nil {
+			return fmt.Errorf("failed to clear queue order: %w", err)
+		}
+		stmt, err := tx.Prepare("UPDATE downloads SET queue_position = ? WHERE id = ?")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i, id := range ids {
+			if _, err := stmt.Exec(i+1, id); err != nil {
+				return fmt.Errorf("failed to save queue position: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdatePriority changes a download's stored priority.
+func UpdatePriority(id string, priority int) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if _, err := db.Exec("UPDATE downloads SET priority = ? WHERE id = ?", priority, id); err != nil {
+		return fmt.Errorf("failed to update priority: %w", err)
+	}
+	return nil
+}
+
+// UpdateRateLimit changes a download's stored speed cap.
+func UpdateRateLimit(id string, bytesPerSec int64) error {
+	db := getDBHelper()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	if _, err := db.Exec("UPDATE downloads SET rate_limit = ? WHERE id = ?", bytesPerSec, id); err != nil {
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+	return nil
+}
+
+// LoadScheduledDownloads returns entries waiting for their start time.
+func LoadScheduledDownloads() ([]types.DownloadEntry, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []types.DownloadEntry
+	for _, e := range list.Downloads {
+		if e.Status == "scheduled" {
+			scheduled = append(scheduled, e)
+		}
+	}
+	return scheduled, nil
+}
+
+// LoadRetryingDownloads returns failed entries waiting for an automatic retry.
+func LoadRetryingDownloads() ([]types.DownloadEntry, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+
+	var retrying []types.DownloadEntry
+	for _, e := range list.Downloads {
+		if e.Status == "retrying" {
+			retrying = append(retrying, e)
+		}
+	}
+	return retrying, nil
+}
+
+// LoadDiskFullDownloads returns downloads waiting for disk space.
+func LoadDiskFullDownloads() ([]types.DownloadEntry, error) {
+	list, err := LoadMasterList()
+	if err != nil {
+		return nil, err
+	}
+
+	var waiting []types.DownloadEntry
+	for _, e := range list.Downloads {
+		if e.Status == "disk_full" {
+			waiting = append(waiting, e)
+		}
+	}
+	return waiting, nil
+}
+
+// PauseAllDownloads marks all in-flight downloads as paused. Scheduled
+// downloads keep waiting for their start time.
 func PauseAllDownloads() error {
 	db := getDBHelper()
 	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	_, err := db.Exec("UPDATE downloads SET status = 'paused' WHERE status != 'completed'")
+	_, err := db.Exec("UPDATE downloads SET status = 'paused' WHERE status NOT IN ('completed', 'scheduled')")
 	return err
 }
 
@@ -519,7 +836,7 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 
 	// 1. Load Downloads
 	query := fmt.Sprintf(`
-		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size
+		SELECT id, url, dest_path, filename, total_size, downloaded, url_hash, created_at, paused_at, time_taken, mirrors, chunk_bitmap, actual_chunk_size, checksum, hash_state, hashed_bytes, pieces, etag, last_modified, working_path, rate_limit
 		FROM downloads
 		WHERE id IN (%s) AND status != 'completed'
 	`, inClause)
@@ -539,14 +856,14 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 
 	for rows.Next() {
 		var state types.DownloadState
-		var timeTaken, createdAt, pausedAt, actualChunkSize sql.NullInt64
-		var mirrors sql.NullString
-		var chunkBitmap []byte
+		var timeTaken, createdAt, pausedAt, actualChunkSize, hashedBytes, rateLimit sql.NullInt64
+		var mirrors, checksum, pieces, etag, lastModified, workingPath sql.NullString
+		var chunkBitmap, hashState []byte
 
 		if err := rows.Scan(
 			&state.ID, &state.URL, &state.DestPath, &state.Filename,
 			&state.TotalSize, &state.Downloaded, &state.URLHash,
-			&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize,
+			&createdAt, &pausedAt, &timeTaken, &mirrors, &chunkBitmap, &actualChunkSize, &checksum, &hashState, &hashedBytes, &pieces, &etag, &lastModified, &workingPath, &rateLimit,
 		); err != nil {
 			return nil, err
 		}
@@ -567,6 +884,18 @@ func LoadStates(ids []string) (map[string]*types.DownloadState, error) {
 			state.ActualChunkSize = actualChunkSize.Int64
 		}
 		state.ChunkBitmap = chunkBitmap
+		if checksum.Valid {
+			state.Checksum = checksum.String
+		}
+		if hashedBytes.Valid {
+			state.HashedBytes = hashedBytes.Int64
+		}
+		state.HashState = hashState
+		state.Pieces = decodePieces(pieces)
+		state.ETag = etag.String
+		state.LastModified = lastModified.String
+		state.WorkingPath = partialPath(workingPath, state.DestPath)
+		state.RateLimit = rateLimit.Int64
 
 		states[state.ID] = &state
 	}
@@ -624,7 +953,7 @@ func ValidateIntegrity() (int, error) {
 
 	// Load all paused/queued downloads
 	rows, err := db.Query(`
-		SELECT id, dest_path, file_hash
+		SELECT id, status, dest_path, file_hash, working_path, downloaded
 		FROM downloads
 		WHERE status IN ('paused', 'queued')
 	`)
@@ -634,27 +963,36 @@ func ValidateIntegrity() (int, error) {
 	defer func() { _ = rows.Close() }()
 
 	type entry struct {
-		id       string
-		destPath string
-		fileHash string
+		id          string
+		destPath    string
+		fileHash    string
+		workingPath string
 	}
 
 	var entries []entry
 	for rows.Next() {
 		var e entry
-		var fh sql.NullString
-		if err := rows.Scan(&e.id, &e.destPath, &fh); err != nil {
+		var fh, wp sql.NullString
+		var status string
+		var downloaded sql.NullInt64
+		if err := rows.Scan(&e.id, &status, &e.destPath, &fh, &wp, &downloaded); err != nil {
 			return 0, err
 		}
+		// A queued download that has not written anything yet has no
+		// partial to check; it only holds its place in the queue.
+		if downloaded.Int64 == 0 && (status == "queued" || wp.String == "") {
+			continue
+		}
 		if fh.Valid {
 			e.fileHash = fh.String
 		}
+		e.workingPath = partialPath(wp, e.destPath)
 		entries = append(entries, e)
 	}
 
 	removed := 0
 	for _, e := range entries {
-		GoFetchPath := e.destPath + types.IncompleteSuffix
+		GoFetchPath := e.workingPath
 
 		// Check if .GoFetch file exists
 		if _, err := os.Stat(GoFetchPath); os.IsNotExist(err) {
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// moveProgressInterval spaces out progress callbacks while copying.
+const moveProgressInterval = 500 * time.Millisecond
+
+// MoveFile moves src to dst. It renames when both are on the same
+// filesystem; otherwise it copies into a temporary file next to dst, syncs
+// it and renames that into place, so dst never holds a partial copy.
+// progress, if not nil, is called with the bytes copied so far while copying.
+func MoveFile(src, dst string, progress func(copied, total int64)) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	// A source that is gone cannot be copied either.
+	if _, statErr := os.Stat(src); statErr != nil {
+		return err
+	}
+	Debug("Rename %s failed (%v), copying instead", src, err)
+
+	if err := copyFileSynced(src, dst, progress); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		Debug("Failed to remove %s after copying it: %v", src, err)
+	}
+	return nil
+}
+
+// copyFileSynced copies src over dst through a synced temporary file.
+func copyFileSynced(src, dst string, progress func(copied, total int64)) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	total := info.Size()
+	var copied int64
+	last := time.Now()
+	buf := make([]byte, 1<<20)
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			if _, err := tmp.Write(buf[:n]); err != nil {
+				return err
+			}
+			copied += int64(n)
+			if progress != nil && time.Since(last) >= moveProgressInterval {
+				progress(copied, total)
+				last = time.Now()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	if progress != nil {
+		progress(copied, total)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		Debug("Failed to set mode of %s: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
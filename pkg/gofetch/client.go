@@ -9,6 +9,7 @@ import (
 	"concurrent_downloader/internal/utils"
 	"context"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -69,11 +70,7 @@ func NewClient(opts *ClientOptions) (*Client, error) {
 	}
 	state.Configure(statePath)
 
-	// Prefer the newer network setting; fall back to legacy connections config.
-	maxDownloads := settings.Network.MaxConcurrentDownloads
-	if maxDownloads <= 0 {
-		maxDownloads = settings.Connections.MaxConcurrentDownloads
-	}
+	maxDownloads := settings.MaxConcurrentDownloads()
 	if opts != nil && opts.MaxConcurrentDownloads > 0 {
 		maxDownloads = opts.MaxConcurrentDownloads
 	}
@@ -82,6 +79,16 @@ func NewClient(opts *ClientOptions) (*Client, error) {
 	progressCh := make(chan any, 100)
 	pool := download.NewWorkerPool(progressCh, maxDownloads)
 	service := core.NewLocalDownloadServiceWithInput(pool, progressCh)
+	service.ApplySettings(settings)
+	if opts != nil && opts.RefreshURL != nil {
+		service.SetURLRefresher(opts.RefreshURL)
+	}
+	if opts != nil && opts.MaxConcurrentDownloads > 0 {
+		// The override outranks the limit from settings.
+		if err := service.SetMaxConcurrentDownloads(opts.MaxConcurrentDownloads); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Client{
 		service:    service,
@@ -127,8 +134,21 @@ func (c *Client) Add(url string, outputDir string, filename string, opts *Downlo
 	if opts != nil {
 		mirrors = opts.Mirrors
 		headers = opts.Headers
-		if opts.ForceSingle {
-			addOpts = &types.AddOptions{ForceSingle: true}
+
+		checksum, err := types.ParseChecksum(opts.Checksum)
+		if err != nil {
+			return "", err
+		}
+		if opts.ForceSingle || checksum != nil || opts.RateLimit > 0 || opts.Priority != 0 || !opts.StartAt.IsZero() || !opts.PauseAt.IsZero() || opts.TempDir != "" {
+			addOpts = &types.AddOptions{
+				ForceSingle: opts.ForceSingle,
+				Checksum:    checksum,
+				RateLimit:   opts.RateLimit,
+				Priority:    opts.Priority,
+				StartAt:     opts.StartAt,
+				PauseAt:     opts.PauseAt,
+				TempDir:     opts.TempDir,
+			}
 		}
 	}
 
@@ -175,6 +195,60 @@ func (c *Client) ResumeBatch(ids []string) []error {
 	return c.service.ResumeBatch(ids)
 }
 
+// RefreshURL gives a download that is not running a new URL for the same
+// file, such as a newly presigned link, and keeps its progress. A download
+// waiting in "needs_url" resumes with it.
+func (c *Client) RefreshURL(id string, newURL string) error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.RefreshURL(id, newURL)
+}
+
+// SetRateLimit changes the speed cap of a queued or running download in bytes
+// per second (0 = unlimited). An empty id changes the cap shared by all downloads.
+func (c *Client) SetRateLimit(id string, bytesPerSec int64) error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.SetRateLimit(id, bytesPerSec)
+}
+
+// SetMaxConcurrentDownloads changes how many downloads run at once. Lowering
+// it lets running downloads finish; queued ones wait for a free slot.
+func (c *Client) SetMaxConcurrentDownloads(n int) error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.SetMaxConcurrentDownloads(n)
+}
+
+// ReloadSettings re-reads the settings file and applies its limits to the
+// running client.
+func (c *Client) ReloadSettings() error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.ReloadSettings()
+}
+
+// MoveInQueue moves a queued download to a 1-based queue position; 0 moves
+// it to the bottom.
+func (c *Client) MoveInQueue(id string, position int) error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.MoveInQueue(id, position)
+}
+
+// SwapInQueue exchanges the queue positions of two queued downloads.
+func (c *Client) SwapInQueue(a, b string) error {
+	if c == nil || c.service == nil {
+		return errors.New("client not initialized")
+	}
+	return c.service.SwapInQueue(a, b)
+}
+
 // Delete cancels and removes a download.
 func (c *Client) Delete(id string) error {
 	if c == nil || c.service == nil {
@@ -191,6 +265,17 @@ func (c *Client) GetStatus(id string) (*types.DownloadStatus, error) {
 	return c.service.GetStatus(id)
 }
 
+// Open returns a reader of a download's file that follows the download,
+// blocking until the bytes at its offset have been written. It works for
+// downloads that are still running as well as finished ones. Seeking is
+// allowed; a chunked download fetches the bytes a reader waits for first.
+func (c *Client) Open(id string) (io.ReadSeekCloser, error) {
+	if c == nil || c.service == nil {
+		return nil, errors.New("client not initialized")
+	}
+	return c.service.Open(id)
+}
+
 // StreamEvents subscribes to the live event stream.
 func (c *Client) StreamEvents(ctx context.Context) (<-chan interface{}, func(), error) {
 	if c == nil || c.service == nil {
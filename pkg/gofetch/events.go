@@ -9,7 +9,17 @@ type BatchProgressMsg = events.BatchProgressMsg
 type DownloadStartedMsg = events.DownloadStartedMsg
 type DownloadCompleteMsg = events.DownloadCompleteMsg
 type DownloadErrorMsg = events.DownloadErrorMsg
+type DownloadChecksumMismatchMsg = events.DownloadChecksumMismatchMsg
+type DownloadRetryMsg = events.DownloadRetryMsg
+type DownloadRetryScheduledMsg = events.DownloadRetryScheduledMsg
+type DownloadBackoffMsg = events.DownloadBackoffMsg
+type DownloadNeedsURLMsg = events.DownloadNeedsURLMsg
+type DownloadURLRefreshedMsg = events.DownloadURLRefreshedMsg
+type DownloadDiskFullMsg = events.DownloadDiskFullMsg
+type DownloadMovingMsg = events.DownloadMovingMsg
 type DownloadQueuedMsg = events.DownloadQueuedMsg
+type DownloadScheduledMsg = events.DownloadScheduledMsg
 type DownloadPausedMsg = events.DownloadPausedMsg
 type DownloadResumedMsg = events.DownloadResumedMsg
 type DownloadRemovedMsg = events.DownloadRemovedMsg
+type ScheduleProfileChangedMsg = events.ScheduleProfileChangedMsg
@@ -1,6 +1,11 @@
 package gofetch
 
-import "concurrent_downloader/internal/config"
+import (
+	"context"
+	"time"
+
+	"concurrent_downloader/internal/config"
+)
 
 // ClientOptions configures the embedded engine.
 type ClientOptions struct {
@@ -14,6 +19,11 @@ type ClientOptions struct {
 	StatePath string
 	// LogsDir redirects log output when the host wants a custom location.
 	LogsDir string
+	// RefreshURL is called when a download's URL stops working (401, 403 or
+	// 410), e.g. an expired presigned link, and returns a new URL for the same
+	// file. The download continues from its partial. Without it, or when it
+	// fails, the download waits in "needs_url" for Client.RefreshURL.
+	RefreshURL func(ctx context.Context, id string, expiredURL string) (string, error)
 }
 
 // DownloadOptions controls per-download behavior.
@@ -24,4 +34,17 @@ type DownloadOptions struct {
 	Headers map[string]string
 	// ForceSingle bypasses the concurrent downloader for servers that do not support ranges.
 	ForceSingle bool
+	// Checksum is an expected digest such as "sha256:<hex>"; a mismatch fails the download.
+	Checksum string
+	// RateLimit caps the download speed in bytes per second; 0 means unlimited.
+	RateLimit int64
+	// Priority orders the queue; higher starts first. Use types.PriorityHigh etc.
+	Priority int
+	// StartAt keeps the download "scheduled" until this time; zero starts it now.
+	StartAt time.Time
+	// PauseAt pauses the download at this time; zero means never.
+	PauseAt time.Time
+	// TempDir holds the partial file until it is complete, overriding the
+	// temp_dir setting; empty uses the setting.
+	TempDir string
 }
@@ -16,5 +16,34 @@ type ProgressState = types.ProgressState
 type RuntimeConfig = types.RuntimeConfig
 
 type AddOptions = types.AddOptions
+type WriteStats = types.WriteStats
 
 var ErrPaused = types.ErrPaused
+var ErrChecksumMismatch = types.ErrChecksumMismatch
+var ErrThrottled = types.ErrThrottled
+var ErrFileChanged = types.ErrFileChanged
+
+type Checksum = types.Checksum
+type ChecksumMismatchError = types.ChecksumMismatchError
+type ThrottledError = types.ThrottledError
+
+// DownloadError classifies a failure; see ErrorKind for the kinds.
+type DownloadError = types.DownloadError
+type ErrorKind = types.ErrorKind
+
+const (
+	KindUnknown      = types.KindUnknown
+	KindNetwork      = types.KindNetwork
+	KindHTTPStatus   = types.KindHTTPStatus
+	KindDiskFull     = types.KindDiskFull
+	KindPermission   = types.KindPermission
+	KindChecksum     = types.KindChecksum
+	KindAuthRequired = types.KindAuthRequired
+	KindFileChanged  = types.KindFileChanged
+	KindCancelled    = types.KindCancelled
+)
+
+// ClassifyError returns err as a *DownloadError with its kind filled in.
+func ClassifyError(err error) *DownloadError {
+	return types.Classify(err)
+}